@@ -1,20 +1,29 @@
 package main
 
 import (
-	"log"
+	"log/slog"
+	"os"
 
 	"github.com/cldmnky/pod-invaders/internal/api"
 	"github.com/cldmnky/pod-invaders/internal/config"
+	"github.com/cldmnky/pod-invaders/internal/logging"
 )
 
 // main is the entry point of the application.
 func main() {
 
-	// Initialize configuration from command-line flags and environment variables.
+	// Load configuration from the config file, environment variables and command-line flags.
 	cfg := config.New()
 
+	// Set up structured logging; the level follows config reloads.
+	if err := logging.Setup(os.Stderr, cfg.LogLevel, cfg.LogFormat); err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(2)
+	}
+
 	// Create and run the server.
 	if err := api.Run(cfg); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+		slog.Error("Failed to run server", "error", err)
+		os.Exit(1)
 	}
 }
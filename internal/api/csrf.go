@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/cldmnky/pod-invaders/internal/config"
+)
+
+// csrfHeader carries the CSRF token on state-changing requests.
+const csrfHeader = "X-CSRF-Token"
+
+// minCSRFKeyBytes is the shortest key accepted from --csrf-secret-file.
+const minCSRFKeyBytes = 32
+
+// csrfProtection guards state-changing requests against cross-site request forgery. Such
+// requests must come from an allowed origin and carry a token bound to the browser session.
+type csrfProtection struct {
+	key            []byte          // Signs tokens, so they can be checked without server-side state
+	allowedOrigins map[string]bool // Origins allowed besides the server's own
+	tls            bool            // Whether the server terminates TLS itself, so its own origin is https
+}
+
+// newCSRFProtection loads the signing key from cfg.CSRFSecretFile, or generates one.
+func newCSRFProtection(cfg *config.Config) (*csrfProtection, error) {
+	var key []byte
+	if cfg.CSRFSecretFile != "" {
+		data, err := os.ReadFile(cfg.CSRFSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		key = bytes.TrimSpace(data)
+		if len(key) < minCSRFKeyBytes {
+			return nil, fmt.Errorf("%s must contain at least %d bytes", cfg.CSRFSecretFile, minCSRFKeyBytes)
+		}
+	} else {
+		key = make([]byte, minCSRFKeyBytes)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &csrfProtection{key: key, allowedOrigins: make(map[string]bool), tls: cfg.TLSCertFile != ""}
+	for _, origin := range cfg.AllowedOrigins {
+		p.allowedOrigins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return p, nil
+}
+
+// token returns the CSRF token of a session.
+func (p *csrfProtection) token(session string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte("csrf:" + session))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// middleware rejects state-changing requests from other origins or without the session's
+// token with 403. It must run after sessionMiddleware.
+func (p *csrfProtection) middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions, fiber.MethodTrace:
+			return c.Next()
+		}
+
+		if !p.sameOrigin(c) {
+			logger(c).Warn("Rejected cross-origin request", "origin", c.Get(fiber.HeaderOrigin), "referer", c.Get(fiber.HeaderReferer))
+			return sendError(c, fiber.StatusForbidden, CodeCSRFFailed, "Cross-origin request denied")
+		}
+		session := sessionID(c)
+		if session == "" || !hmac.Equal([]byte(c.Get(csrfHeader)), []byte(p.token(session))) {
+			logger(c).Warn("Rejected request without a valid CSRF token")
+			return sendError(c, fiber.StatusForbidden, CodeCSRFFailed, "Missing or invalid CSRF token")
+		}
+		return c.Next()
+	}
+}
+
+// sameOrigin reports whether the request was sent by the server's own pages or an allowed
+// origin, going by Origin or, when browsers leave it out, Referer. Requests with neither are denied.
+func (p *csrfProtection) sameOrigin(c *fiber.Ctx) bool {
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" {
+		referer, err := url.Parse(c.Get(fiber.HeaderReferer))
+		if err != nil || referer.Host == "" {
+			return false
+		}
+		origin = referer.Scheme + "://" + referer.Host
+	}
+	origin = strings.ToLower(origin)
+	return origin == strings.ToLower(p.ownOrigin(c)) || p.allowedOrigins[origin]
+}
+
+// ownOrigin returns the origin the server's own pages are served from. With TLS the app
+// runs behind net/http, where Fiber cannot tell the request came in over TLS.
+func (p *csrfProtection) ownOrigin(c *fiber.Ctx) string {
+	if p.tls {
+		return "https://" + c.Hostname()
+	}
+	return c.BaseURL()
+}
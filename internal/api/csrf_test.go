@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/cldmnky/pod-invaders/internal/config"
+)
+
+func TestCSRFToken(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "csrf.key")
+	if err := os.WriteFile(keyFile, []byte(strings.Repeat("k", minCSRFKeyBytes)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := newCSRFProtection(&config.Config{CSRFSecretFile: keyFile})
+	if err != nil {
+		t.Fatalf("newCSRFProtection failed: %v", err)
+	}
+	b, err := newCSRFProtection(&config.Config{CSRFSecretFile: keyFile})
+	if err != nil {
+		t.Fatalf("newCSRFProtection failed: %v", err)
+	}
+	random, err := newCSRFProtection(&config.Config{})
+	if err != nil {
+		t.Fatalf("newCSRFProtection failed: %v", err)
+	}
+
+	if a.token("session-1") != b.token("session-1") {
+		t.Error("Expected replicas sharing a key to issue the same token")
+	}
+	if a.token("session-1") == a.token("session-2") {
+		t.Error("Expected tokens to differ between sessions")
+	}
+	if a.token("session-1") == random.token("session-1") {
+		t.Error("Expected tokens to differ between keys")
+	}
+
+	if err := os.WriteFile(keyFile, []byte("short"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newCSRFProtection(&config.Config{CSRFSecretFile: keyFile}); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	server := createTestServer(false)
+	csrf, err := newCSRFProtection(&config.Config{AllowedOrigins: []string{"https://games.example.com/"}})
+	if err != nil {
+		t.Fatalf("newCSRFProtection failed: %v", err)
+	}
+	server.csrf = csrf
+	app := createTestApp(server, setupTestTemplate(t))
+
+	// The game page embeds the token of the session it sets up
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	match := regexp.MustCompile(`name="csrf-token" content="([^"]+)"`).FindSubmatch(body)
+	if match == nil {
+		t.Fatalf("Expected the page to embed a CSRF token, got: %s", body)
+	}
+	token := string(match[1])
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == sessionCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatal("Expected a session cookie")
+	}
+	if token != csrf.token(cookie.Value) {
+		t.Error("Expected the embedded token to belong to the session")
+	}
+
+	// app.Test serves requests for http://example.com
+	const self = "http://example.com"
+	tests := []struct {
+		name, method, target string
+		origin, referer      string
+		token                string
+		wantCode             int
+	}{
+		{name: "same origin", method: "POST", target: "/api/v1/heartbeat", origin: self, token: token, wantCode: fiber.StatusOK},
+		{name: "referer without origin", method: "POST", target: "/api/v1/heartbeat", referer: self + "/", token: token, wantCode: fiber.StatusOK},
+		{name: "allowed origin", method: "POST", target: "/api/v1/heartbeat", origin: "https://games.example.com", token: token, wantCode: fiber.StatusOK},
+		{name: "safe method", method: "GET", target: "/api/v1/settings", origin: "https://evil.example", wantCode: fiber.StatusOK},
+		{name: "missing token", method: "POST", target: "/api/v1/heartbeat", origin: self, wantCode: fiber.StatusForbidden},
+		{name: "token of another session", method: "POST", target: "/api/v1/heartbeat", origin: self, token: csrf.token("other"), wantCode: fiber.StatusForbidden},
+		{name: "cross-site kill", method: "POST", target: "/kill", origin: "https://evil.example", token: token, wantCode: fiber.StatusForbidden},
+		{name: "cross-site namespaces", method: "POST", target: "/namespaces", referer: "https://evil.example/page", token: token, wantCode: fiber.StatusForbidden},
+		{name: "opaque origin", method: "DELETE", target: "/api/v1/monitors/abc", origin: "null", token: token, wantCode: fiber.StatusForbidden},
+		{name: "no origin or referer", method: "PUT", target: "/api/v1/namespaces", token: token, wantCode: fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.AddCookie(cookie)
+			if tt.origin != "" {
+				req.Header.Set(fiber.HeaderOrigin, tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set(fiber.HeaderReferer, tt.referer)
+			}
+			if tt.token != "" {
+				req.Header.Set(csrfHeader, tt.token)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d", tt.wantCode, resp.StatusCode)
+			}
+			if tt.wantCode == fiber.StatusForbidden {
+				var envelope ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error.Code != CodeCSRFFailed {
+					t.Errorf("Expected a %s error, got %+v (%v)", CodeCSRFFailed, envelope, err)
+				}
+			}
+		})
+	}
+}
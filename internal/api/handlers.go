@@ -2,227 +2,899 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"net/url"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 
+	"github.com/cldmnky/pod-invaders/internal/config"
 	"github.com/cldmnky/pod-invaders/internal/game"
 	"github.com/cldmnky/pod-invaders/internal/k8s"
+	"github.com/cldmnky/pod-invaders/internal/metrics"
 	"github.com/cldmnky/pod-invaders/internal/monitor"
 )
 
-// registerGameHandlers registers the game-related endpoints.
+// registerGameHandlers registers the game-related endpoints under the versioned API,
+// along with their deprecated unversioned aliases.
 func (s *Server) registerGameHandlers(app *fiber.App) {
 	app.Get("/", s.handleRoot)
-	app.Get("/names", s.handleGetNames)
-	app.Post("/kill", s.handleKill)
-	app.Post("/highscore", s.handlePostHighscore)
-	app.Get("/highscores", s.handleGetHighscores)
-	app.Post("/namespaces", s.handlePostNamespaces)
 	app.Get("/healthz", s.handleHealthz)
 	app.Get("/readyz", s.handleReadyz)
+
+	v1 := app.Group(apiV1Prefix)
+	v1.Get("/openapi.json", handleOpenAPI)
+	v1.Get("/pods", s.handleGetNames)
+	v1.Get("/pods/:namespace/:name", s.handleGetPod)
+	v1.Get("/workloads", s.handleGetWorkloads)
+	v1.Get("/boss", s.handleGetBoss)
+	v1.Post("/boss/defeats", s.handleBossDefeat)
+	v1.Post("/kills", s.handleKill)
+	v1.Get("/nodes", s.handleGetNodes)
+	v1.Post("/nodes/drains", s.handleNodeDrain)
+	v1.Get("/resources", s.handleGetResources)
+	v1.Post("/resources/kills", s.handleResourceKill)
+	v1.Get("/highscores", s.handleGetHighscores)
+	v1.Post("/highscores", s.handlePostHighscore)
+	v1.Put("/namespaces", s.handlePostNamespaces)
+	v1.Post("/heartbeat", s.handleHeartbeat)
+	v1.Get("/settings", s.handleGetSettings)
+
+	app.Get("/names", deprecated(apiV1Prefix+"/pods"), s.handleGetNames)
+	app.Post("/kill", deprecated(apiV1Prefix+"/kills"), s.handleKill)
+	app.Post("/highscore", deprecated(apiV1Prefix+"/highscores"), s.handlePostHighscore)
+	app.Get("/highscores", deprecated(apiV1Prefix+"/highscores"), s.handleGetHighscores)
+	app.Post("/namespaces", deprecated(apiV1Prefix+"/namespaces"), s.handlePostNamespaces)
+	app.Post("/heartbeat", deprecated(apiV1Prefix+"/heartbeat"), s.handleHeartbeat)
+	app.Get("/settings", deprecated(apiV1Prefix+"/settings"), s.handleGetSettings)
 }
 
-// registerMonitorHandlers registers the monitoring-related endpoints.
+// registerMonitorHandlers registers the monitoring-related endpoints under the versioned
+// API, along with their deprecated unversioned aliases.
 func (s *Server) registerMonitorHandlers(app *fiber.App) {
-	app.Post("/monitor", s.handleMonitor)
-	app.Post("/monitor/stop", s.handleMonitorStop)
-	app.Get("/monitor/status", s.handleMonitorStatus)
+	v1 := app.Group(apiV1Prefix)
+	v1.Get("/monitors", s.handleListMonitors)
+	v1.Post("/monitors", s.handleMonitor)
+	v1.Get("/monitors/:id", s.handleMonitorStatus)
+	v1.Delete("/monitors/:id", s.handleDeleteMonitor)
+
+	app.Post("/monitor", deprecated(apiV1Prefix+"/monitors"), s.handleMonitor)
+	app.Post("/monitor/stop", deprecated(apiV1Prefix+"/monitors/{id}"), s.handleMonitorStop)
+	app.Get("/monitor/status", deprecated(apiV1Prefix+"/monitors/{id}"), s.handleMonitorStatus)
+	app.Get("/monitors", deprecated(apiV1Prefix+"/monitors"), s.handleListMonitors)
 }
 
 // handleRoot serves the main game page.
 func (s *Server) handleRoot(c *fiber.Ctx) error {
-	return c.Render("index", fiber.Map{
+	data := fiber.Map{
 		"Title": "Pod Invaders",
-	})
+	}
+	if s.csrf != nil {
+		data["CSRFToken"] = s.csrf.token(sessionID(c))
+	}
+	return c.Render("index", data)
 }
 
 // handleGetNames provides a list of pods, either real or fake.
 func (s *Server) handleGetNames(c *fiber.Ctx) error {
-	count := c.QueryInt("count", 10)
-	if count > 100 {
-		count = 100
-	}
+	start := time.Now()
+	count := min(max(c.QueryInt("count", 10), 1), 100)
 
-	if !s.config.EnableKube {
+	if !s.kubeEnabled() {
+		fakes := s.fakePodsFor(c)
 		pods := make([]game.Pod, count)
 		for i := 0; i < count; i++ {
-			pods[i] = game.GenerateFakePod()
+			pods[i] = fakes.Pod()
 		}
+		s.scorePods(pods)
+		recordPodsServed("standalone", start, pods)
 		return c.JSON(pods)
 	}
 
-	if s.config.EnableOpenShiftAuth {
-		// Use the authenticated kube client from the context
-		client, ok := c.Locals("kubeClient").(kubernetes.Interface)
+	var pods []game.Pod
+	var err error
+	ctx := game.WithFakePodGenerator(c.UserContext(), s.lazyFakePodsFor(c))
+	if s.fleet != nil {
+		pods, err = k8s.GetFleetPods(ctx, s.fleet, count, s.settings().NamespaceNames...)
+	} else {
+		client, ok := s.kubeClientFor(c)
 		if !ok {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Kubernetes client not found"})
+			return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Kubernetes client not found")
 		}
-		s.kubeClient = client
+		pods, err = k8s.GetPods(ctx, client, count, s.settings().NamespaceNames...)
 	}
-
-	pods, err := k8s.GetPods(s.kubeClient, count, s.namespaces.Namespaces...)
 	if err != nil {
-		log.Printf("Error getting pods: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve pods"})
+		logger(c).Error("Failed to get pods", "error", err)
+		return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, "Failed to retrieve pods")
 	}
 
-	log.Printf("Returning %d pods", len(pods))
+	s.scorePods(pods)
+	logger(c).Debug("Returning pods", "count", len(pods))
+	mode := "kube"
+	if !s.config.EnableKube {
+		mode = "standalone"
+	}
+	recordPodsServed(mode, start, pods)
 	return c.JSON(pods)
 }
 
-// handleKill handles the request to kill a pod.
+// scorePods sets the hit points of pods under the hit point rules in effect.
+func (s *Server) scorePods(pods []game.Pod) {
+	if scorer := s.scorer.Load(); scorer != nil {
+		scorer.Apply(pods)
+	}
+}
+
+// handleGetPod returns the metadata of a pod in one of the target namespaces. In fleet
+// mode, the cluster query parameter says which cluster the pod runs in.
+func (s *Server) handleGetPod(c *fiber.Ctx) error {
+	pod := game.Pod{Namespace: c.Params("namespace"), Name: c.Params("name"), Cluster: c.Query("cluster")}
+	errs := validatePod(pod)
+	s.checkCluster(&errs, pod.Cluster)
+	if len(errs) > 0 {
+		return sendValidationError(c, errs)
+	}
+	notFound := fmt.Sprintf("Pod %s/%s not found", pod.Namespace, pod.Name)
+
+	// Only pods the game could hand out are described
+	if !s.kubeEnabled() || !slices.Contains(s.settings().NamespaceNames, pod.Namespace) {
+		return sendError(c, fiber.StatusNotFound, CodeNotFound, notFound)
+	}
+
+	client, ok := s.clusterClientFor(c, pod.Cluster)
+	if !ok {
+		return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Kubernetes client not found")
+	}
+	cluster := pod.Cluster
+	pod, err := k8s.GetPod(c.UserContext(), client, pod.Namespace, pod.Name)
+	pod.Cluster = cluster
+	switch {
+	case apierrors.IsNotFound(err):
+		return sendError(c, fiber.StatusNotFound, CodeNotFound, notFound)
+	case err != nil:
+		logger(c).Error("Failed to get pod", "namespace", c.Params("namespace"), "pod", c.Params("name"), "error", err)
+		return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, "Failed to retrieve pod")
+	}
+	pods := []game.Pod{pod}
+	s.scorePods(pods)
+	return c.JSON(pods[0])
+}
+
+// Limits on the grid requested from /workloads
+const (
+	maxWorkloadRows = 10
+	maxWorkloadCols = 20
+)
+
+// handleGetWorkloads provides rows of invaders, one workload's pods per row.
+func (s *Server) handleGetWorkloads(c *fiber.Ctx) error {
+	start := time.Now()
+	rows := min(max(c.QueryInt("rows", 1), 1), maxWorkloadRows)
+	cols := min(max(c.QueryInt("cols", 1), 1), maxWorkloadCols)
+
+	var workloads []game.Workload
+	mode := "standalone"
+	if !s.kubeEnabled() {
+		fakes := s.fakePodsFor(c)
+		for range rows {
+			workloads = append(workloads, fakes.Workload(cols))
+		}
+	} else {
+		mode = "kube"
+		client, ok := s.kubeClientFor(c)
+		if !ok {
+			return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Kubernetes client not found")
+		}
+		var err error
+		workloads, err = k8s.GetWorkloads(game.WithFakePodGenerator(c.UserContext(), s.lazyFakePodsFor(c)), client, rows, cols, s.settings().NamespaceNames...)
+		if err != nil {
+			logger(c).Error("Failed to get workloads", "error", err)
+			return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, "Failed to retrieve workloads")
+		}
+	}
+
+	var pods []game.Pod
+	for _, w := range workloads {
+		s.scorePods(w.Pods)
+		pods = append(pods, w.Pods...)
+	}
+	recordPodsServed(mode, start, pods)
+	return c.JSON(workloads)
+}
+
+// handleGetBoss provides the workload the next boss stands for.
+func (s *Server) handleGetBoss(c *fiber.Ctx) error {
+	if !s.kubeEnabled() {
+		return c.JSON(s.fakePodsFor(c).Boss())
+	}
+	client, ok := s.kubeClientFor(c)
+	if !ok {
+		return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Kubernetes client not found")
+	}
+	boss, err := k8s.GetBoss(game.WithFakePodGenerator(c.UserContext(), s.lazyFakePodsFor(c)), client, s.settings().NamespaceNames...)
+	if err != nil {
+		logger(c).Error("Failed to get boss workload", "error", err)
+		return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, "Failed to retrieve boss workload")
+	}
+	s.scorePods(boss.Pods)
+	return c.JSON(boss)
+}
+
+// handleBossDefeat applies the configured boss defeat action to a defeated boss workload.
+func (s *Server) handleBossDefeat(c *fiber.Ctx) error {
+	var req BossDefeatRequest
+	if err := c.BodyParser(&req); err != nil {
+		return sendError(c, fiber.StatusBadRequest, CodeInvalidRequest, "cannot parse JSON")
+	}
+	if errs := validateBossDefeat(req); len(errs) > 0 {
+		return sendValidationError(c, errs)
+	}
+
+	settings := s.settings()
+	action := settings.BossDefeatAction
+	if action != config.BossActionNone && settings.KillDryRun {
+		action = "dry-run"
+	}
+	bossLog := logger(c).With("kind", req.Kind, "namespace", req.Namespace, "workload", req.Name, "action", action)
+	trace.SpanFromContext(c.UserContext()).SetAttributes(
+		attribute.String("k8s.workload.kind", req.Kind),
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.workload.name", req.Name),
+		attribute.String("boss.action", action),
+	)
+	record := func(result string) {
+		metrics.BossDefeats.WithLabelValues(req.Kind, action, result).Inc()
+	}
+	defeated := fmt.Sprintf("%s %s/%s defeated", req.Kind, req.Namespace, req.Name)
+
+	restartable := req.Kind == game.KindDeployment || req.Kind == game.KindStatefulSet || req.Kind == game.KindDaemonSet
+	if !req.IsReal || !s.kubeEnabled() || settings.Targeting != config.TargetingWorkload ||
+		action == config.BossActionNone || !restartable {
+		bossLog.Info("Boss defeated, no action taken")
+		record("skipped")
+		return c.JSON(StatusResponse{Status: "skipped", Message: defeated})
+	}
+
+	if settings.IsProtectedNamespace(req.Namespace) {
+		bossLog.Warn("Refusing to act on workload in protected namespace")
+		record("denied")
+		return sendError(c, fiber.StatusForbidden, CodeNamespaceProtected, fmt.Sprintf("Namespace %s is protected", req.Namespace))
+	}
+	if !slices.Contains(settings.NamespaceNames, req.Namespace) {
+		bossLog.Warn("Refusing to act on workload outside the target namespaces")
+		record("denied")
+		return sendError(c, fiber.StatusForbidden, CodeForbidden, fmt.Sprintf("Namespace %s is not targeted", req.Namespace))
+	}
+
+	if settings.KillDryRun {
+		bossLog.Info("Dry run, workload not restarted")
+		record("success")
+		return c.JSON(StatusResponse{Status: "success", Message: defeated + " (dry run)"})
+	}
+
+	client, ok := s.kubeClientFor(c)
+	if !ok {
+		return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Kubernetes client not found")
+	}
+	if err := k8s.RestartWorkload(c.UserContext(), client, req.Kind, req.Namespace, req.Name); err != nil {
+		bossLog.Error("Failed to restart workload", "error", err)
+		record("failure")
+		return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, fmt.Sprintf("Failed to restart workload: %v", err))
+	}
+	bossLog.Info("Boss defeated, workload restarted")
+	record("success")
+	return c.JSON(StatusResponse{Status: "success", Message: defeated + ", rolling restart started"})
+}
+
+// handleGetResources provides objects of the targeted resources to stand in for invaders
+// in resource targeting.
+func (s *Server) handleGetResources(c *fiber.Ctx) error {
+	count := min(max(c.QueryInt("count", 10), 1), 100)
+	settings := s.settings()
+	targets := settings.ParsedResourceTargets()
+	if !s.config.EnableKube {
+		resources := make([]game.Resource, 0, count)
+		for i := 0; i < count && len(targets) > 0; i++ {
+			t := targets[rand.Intn(len(targets))]
+			resources = append(resources, game.GenerateFakeResource(t.GVR.Group, t.GVR.Version, t.GVR.Resource, t.GVR.Resource, t.Action))
+		}
+		return c.JSON(resources)
+	}
+
+	clients, ok := s.clientsFor(c)
+	if !ok {
+		return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Kubernetes client not found")
+	}
+	resources, err := k8s.GetResources(c.UserContext(), clients, targets, count, settings.NamespaceNames...)
+	if err != nil {
+		logger(c).Error("Failed to get resources", "error", err)
+		return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, "Failed to retrieve resources")
+	}
+	return c.JSON(resources)
+}
+
+// handleResourceKill performs the target's action on an object killed in resource
+// targeting, and schedules the rollback of temporary actions.
+func (s *Server) handleResourceKill(c *fiber.Ctx) error {
+	var req ResourceKillRequest
+	if err := c.BodyParser(&req); err != nil {
+		return sendError(c, fiber.StatusBadRequest, CodeInvalidRequest, "cannot parse JSON")
+	}
+	if errs := validateResourceKill(req); len(errs) > 0 {
+		return sendValidationError(c, errs)
+	}
+
+	settings := s.settings()
+	gvr := strings.TrimPrefix(req.Group+"/"+req.Version+"/"+req.Resource, "/")
+	target, targeted := settings.ResourceTarget(gvr)
+	strategy := "simulated"
+	if s.config.EnableKube {
+		strategy = target.Action
+		if settings.KillDryRun {
+			strategy = "dry-run"
+		}
+	}
+	killLog := logger(c).With("resource", gvr, "namespace", req.Namespace, "name", req.Name, "strategy", strategy)
+	trace.SpanFromContext(c.UserContext()).SetAttributes(
+		attribute.String("k8s.resource", gvr),
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.object.name", req.Name),
+		attribute.String("kill.strategy", strategy),
+	)
+	record := func(result string) {
+		resource := gvr
+		if !targeted {
+			resource = otherLabel
+		}
+		metrics.ResourceKills.WithLabelValues(resource, result, strategy).Inc()
+	}
+	killed := fmt.Sprintf("%s %s/%s killed", gvr, req.Namespace, req.Name)
+
+	if !req.IsReal || !s.config.EnableKube || settings.Targeting != config.TargetingResource {
+		killLog.Info("Resource killed, no action taken")
+		record("skipped")
+		return c.JSON(KillResponse{StatusResponse: StatusResponse{Status: "skipped", Message: killed}})
+	}
+	if !targeted {
+		killLog.Warn("Refusing to act on a resource that is not targeted")
+		record("denied")
+		return sendError(c, fiber.StatusForbidden, CodeForbidden, fmt.Sprintf("Resource %s is not targeted", gvr))
+	}
+	if settings.IsProtectedNamespace(req.Namespace) {
+		killLog.Warn("Refusing to act on resource in protected namespace")
+		record("denied")
+		return sendError(c, fiber.StatusForbidden, CodeNamespaceProtected, fmt.Sprintf("Namespace %s is protected", req.Namespace))
+	}
+	if !slices.Contains(settings.NamespaceNames, req.Namespace) {
+		killLog.Warn("Refusing to act on resource outside the target namespaces")
+		record("denied")
+		return sendError(c, fiber.StatusForbidden, CodeForbidden, fmt.Sprintf("Namespace %s is not targeted", req.Namespace))
+	}
+
+	// Resources share the kill cache with pods, keyed by resource and name
+	cached := game.Pod{Namespace: req.Namespace, Name: gvr + "/" + req.Name, IsRealPod: true}
+	if s.killCache.IsKilled(sessionID(c), cached) {
+		killLog.Info("Resource already killed, skipping")
+		record("skipped")
+		return c.JSON(KillResponse{StatusResponse: StatusResponse{Status: "skipped", Message: fmt.Sprintf("%s %s/%s already killed", gvr, req.Namespace, req.Name)}})
+	}
+	if settings.KillDryRun {
+		killLog.Info("Dry run, resource not touched")
+		record("success")
+		return c.JSON(KillResponse{StatusResponse: StatusResponse{Status: "success", Message: killed + " (dry run)"}, Action: target.Action})
+	}
+
+	clients, ok := s.clientsFor(c)
+	if !ok {
+		return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Kubernetes client not found")
+	}
+	change, err := k8s.ApplyResourceAction(c.UserContext(), clients, target, req.Namespace, req.Name)
+	if err != nil {
+		killLog.Error("Failed to kill resource", "error", err)
+		record("failure")
+		if apierrors.IsNotFound(err) {
+			return sendError(c, fiber.StatusNotFound, CodeNotFound, fmt.Sprintf("%s %s/%s not found", gvr, req.Namespace, req.Name))
+		}
+		return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, fmt.Sprintf("Failed to kill resource: %v", err))
+	}
+	var rollbackAt time.Time
+	if change != nil {
+		rollbackAt = s.rollbacks.Schedule(c.UserContext(), clients, change.Key, settings.ActionTTL, change.Undo)
+		killLog.Info("Scheduled rollback", "change", change.Key, "rollback_at", rollbackAt)
+	}
+	s.killCache.Add(sessionID(c), cached)
+	killLog.Info("Resource killed")
+	record("success")
+	return c.JSON(KillResponse{
+		StatusResponse: StatusResponse{Status: "success", Message: killed},
+		Action:         target.Action,
+		RollbackAt:     rollbackAt,
+	})
+}
+
+// handleGetNodes provides nodes to stand in for invaders in node targeting.
+func (s *Server) handleGetNodes(c *fiber.Ctx) error {
+	count := min(max(c.QueryInt("count", 10), 1), 100)
+	if !s.config.EnableKube {
+		nodes := make([]game.Node, count)
+		for i := range nodes {
+			nodes[i] = game.GenerateFakeNode()
+		}
+		return c.JSON(nodes)
+	}
+
+	client, ok := s.kubeClientFor(c)
+	if !ok {
+		return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Kubernetes client not found")
+	}
+	nodes, err := k8s.GetNodes(c.UserContext(), client, count)
+	if err != nil {
+		logger(c).Error("Failed to get nodes", "error", err)
+		return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, "Failed to retrieve nodes")
+	}
+	return c.JSON(nodes)
+}
+
+// handleNodeDrain cordons and drains a node killed in node targeting, and schedules
+// its uncordon.
+func (s *Server) handleNodeDrain(c *fiber.Ctx) error {
+	var req NodeDrainRequest
+	if err := c.BodyParser(&req); err != nil {
+		return sendError(c, fiber.StatusBadRequest, CodeInvalidRequest, "cannot parse JSON")
+	}
+	if errs := validateNodeDrain(req); len(errs) > 0 {
+		return sendValidationError(c, errs)
+	}
+
+	settings := s.settings()
+	strategy := "simulated"
+	if s.config.EnableKube {
+		strategy = "drain"
+		if settings.KillDryRun {
+			strategy = "dry-run"
+		}
+	}
+	nodeLog := logger(c).With("node", req.Name, "strategy", strategy)
+	trace.SpanFromContext(c.UserContext()).SetAttributes(
+		attribute.String("k8s.node.name", req.Name),
+		attribute.String("kill.strategy", strategy),
+	)
+	record := func(result string) {
+		metrics.NodeDrains.WithLabelValues(result, strategy).Inc()
+	}
+	killed := fmt.Sprintf("Node %s killed", req.Name)
+
+	if !req.IsReal || !s.config.EnableKube || settings.Targeting != config.TargetingNode {
+		nodeLog.Info("Node killed, not draining")
+		record("skipped")
+		return c.JSON(NodeDrainResponse{StatusResponse: StatusResponse{Status: "skipped", Message: killed}})
+	}
+	if settings.KillDryRun {
+		nodeLog.Info("Dry run, node not drained")
+		record("success")
+		return c.JSON(NodeDrainResponse{StatusResponse: StatusResponse{Status: "success", Message: killed + " (dry run)"}})
+	}
+
+	client, ok := s.kubeClientFor(c)
+	if !ok {
+		return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Kubernetes client not found")
+	}
+	if err := k8s.CordonNode(c.UserContext(), client, req.Name); err != nil {
+		// Uncordoning it later would end the maintenance it was cordoned for
+		if errors.Is(err, k8s.ErrNodeCordoned) {
+			nodeLog.Warn("Refusing to drain a node that is already cordoned")
+			record("denied")
+			return sendError(c, fiber.StatusConflict, CodeConflict, fmt.Sprintf("Node %s is already cordoned", req.Name))
+		}
+		record("failure")
+		if apierrors.IsNotFound(err) {
+			return sendError(c, fiber.StatusNotFound, CodeNotFound, fmt.Sprintf("Node %s not found", req.Name))
+		}
+		nodeLog.Error("Failed to cordon node", "error", err)
+		return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, fmt.Sprintf("Failed to cordon node: %v", err))
+	}
+	// Scheduled before draining, so that the node is uncordoned even if the drain fails
+	uncordonAt := s.rollbacks.Schedule(c.UserContext(), k8s.Clients{Kube: client}, "node/"+req.Name, settings.NodeUncordonAfter,
+		k8s.Undo{Op: k8s.UndoUncordon, Name: req.Name})
+
+	drain, err := k8s.DrainNode(c.UserContext(), client, req.Name, settings.NamespaceNames, settings.IsProtectedNamespace)
+	if err != nil {
+		nodeLog.Error("Failed to drain node", "error", err)
+		record("failure")
+		return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, fmt.Sprintf("Failed to drain node: %v", err))
+	}
+	metrics.NodeDrainPods.WithLabelValues("evicted").Add(float64(len(drain.Evicted)))
+	metrics.NodeDrainPods.WithLabelValues("blocked").Add(float64(len(drain.Blocked)))
+	metrics.NodeDrainPods.WithLabelValues("skipped").Add(float64(len(drain.Skipped)))
+	metrics.NodeDrainPods.WithLabelValues("failed").Add(float64(len(drain.Failed)))
+	nodeLog.Info("Node killed, drained", "uncordon_at", uncordonAt)
+	record("success")
+	return c.JSON(NodeDrainResponse{
+		StatusResponse: StatusResponse{Status: "success", Message: killed + ", node cordoned and drained"},
+		Drain:          &drain,
+		UncordonAt:     uncordonAt,
+	})
+}
+
+// fakePodsFor returns the generator of the fake pods of the player's game.
+func (s *Server) fakePodsFor(c *fiber.Ctx) *game.FakePodGenerator {
+	return s.fakePods.ForGame(sessionID(c))
+}
+
+// lazyFakePodsFor returns a function that gets the generator of the player's game the
+// first time it is called, for requests that only need fake pods to fill up real ones.
+func (s *Server) lazyFakePodsFor(c *fiber.Ctx) func() *game.FakePodGenerator {
+	id := sessionID(c)
+	return sync.OnceValue(func() *game.FakePodGenerator { return s.fakePods.ForGame(id) })
+}
+
+// kubeEnabled reports whether pods come from a cluster: a real one, or in standalone mode
+// the simulator's.
+func (s *Server) kubeEnabled() bool {
+	return s.config.EnableKube || s.simulator != nil
+}
+
+// kubeClientFor returns the Kubernetes client for a request: the user's own client
+// with OpenShift auth, otherwise the server's.
+func (s *Server) kubeClientFor(c *fiber.Ctx) (kubernetes.Interface, bool) {
+	if s.config.EnableOpenShiftAuth {
+		client, ok := c.Locals("kubeClient").(kubernetes.Interface)
+		return client, ok
+	}
+	return s.kubeClient, s.kubeClient != nil
+}
+
+// clusterClientFor returns the Kubernetes client for a cluster of the fleet, or outside
+// fleet mode, where the cluster has no name, the one kubeClientFor returns.
+func (s *Server) clusterClientFor(c *fiber.Ctx, cluster string) (kubernetes.Interface, bool) {
+	if s.fleet == nil {
+		if cluster != "" {
+			return nil, false
+		}
+		return s.kubeClientFor(c)
+	}
+	clients, ok := s.fleet.Clients(cluster)
+	return clients.Kube, ok
+}
+
+// checkCluster records a problem if cluster does not name a cluster of the fleet, or is
+// set outside fleet mode.
+func (s *Server) checkCluster(errs *fieldErrors, cluster string) {
+	switch {
+	case s.fleet == nil:
+		if cluster != "" {
+			errs.add("cluster", "must be empty outside fleet mode")
+		}
+	case cluster == "":
+		errs.add("cluster", "is required in fleet mode")
+	default:
+		if _, ok := s.fleet.Clients(cluster); !ok {
+			errs.add("cluster", "must be one of %s", strings.Join(s.fleet.Names(), ", "))
+		}
+	}
+}
+
+// clientsFor returns the Kubernetes and dynamic clients to use for a request.
+func (s *Server) clientsFor(c *fiber.Ctx) (k8s.Clients, bool) {
+	client, ok := s.kubeClientFor(c)
+	if !ok {
+		return k8s.Clients{}, false
+	}
+	if s.config.EnableOpenShiftAuth {
+		dyn, ok := c.Locals("dynamicClient").(dynamic.Interface)
+		return k8s.Clients{Kube: client, Dynamic: dyn}, ok
+	}
+	return k8s.Clients{Kube: client, Dynamic: s.dynamicClient}, s.dynamicClient != nil
+}
+
+// recordPodsServed updates the /names latency and real-vs-fake pod metrics.
+func recordPodsServed(mode string, start time.Time, pods []game.Pod) {
+	realPods := 0
+	for _, p := range pods {
+		if p.IsRealPod {
+			realPods++
+		}
+	}
+	metrics.PodsServed.WithLabelValues("real").Add(float64(realPods))
+	metrics.PodsServed.WithLabelValues("fake").Add(float64(len(pods) - realPods))
+	if len(pods) > 0 {
+		metrics.RealPodRatio.Set(float64(realPods) / float64(len(pods)))
+	}
+	metrics.NamesDuration.WithLabelValues(mode).Observe(time.Since(start).Seconds())
+}
+
+// handleKill handles the request to kill a pod, performing a kill action on real pods.
 func (s *Server) handleKill(c *fiber.Ctx) error {
-	var payload game.Pod
-	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot parse JSON"})
+	var req KillRequest
+	if err := c.BodyParser(&req); err != nil {
+		return sendError(c, fiber.StatusBadRequest, CodeInvalidRequest, "cannot parse JSON")
+	}
+	settings := s.settings()
+	errs := validatePod(req.Pod)
+	s.checkCluster(&errs, req.Cluster)
+	actionName := req.Action
+	if actionName == "" {
+		actionName = settings.KillActions[0]
+	} else if !slices.Contains(settings.KillActions, actionName) {
+		errs.add("action", "must be one of %s", strings.Join(settings.KillActions, ", "))
+	}
+	if len(errs) > 0 {
+		return sendValidationError(c, errs)
+	}
+	payload := req.Pod
+
+	killLog := logger(c).With("namespace", payload.Namespace, "pod", payload.Name)
+	span := trace.SpanFromContext(c.UserContext())
+	span.SetAttributes(
+		attribute.String("k8s.namespace.name", payload.Namespace),
+		attribute.String("k8s.pod.name", payload.Name),
+	)
+	if payload.Cluster != "" {
+		killLog = killLog.With("cluster", payload.Cluster)
+		span.SetAttributes(attribute.String("k8s.cluster.name", payload.Cluster))
+	}
+	strategy := "simulated"
+	if s.kubeEnabled() {
+		strategy = actionName
+		if settings.KillDryRun {
+			strategy = "dry-run"
+		}
+	}
+
+	if settings.IsProtectedNamespace(payload.Namespace) {
+		killLog.Warn("Refusing to kill pod in protected namespace")
+		recordKill(c, metricNamespace(settings, payload.Namespace), "denied", strategy)
+		return sendError(c, fiber.StatusForbidden, CodeNamespaceProtected, fmt.Sprintf("Namespace %s is protected", payload.Namespace))
+	}
+	// Fake pods are drawn from their own namespaces and never reach a cluster
+	if payload.IsRealPod && !slices.Contains(settings.NamespaceNames, payload.Namespace) {
+		killLog.Warn("Refusing to kill pod outside the target namespaces")
+		recordKill(c, metricNamespace(settings, payload.Namespace), "denied", strategy)
+		return sendError(c, fiber.StatusForbidden, CodeForbidden, fmt.Sprintf("Namespace %s is not targeted", payload.Namespace))
 	}
 
-	if s.killCache.IsKilled(payload) {
+	if s.killCache.IsKilled(sessionID(c), payload) {
 		msg := fmt.Sprintf("Pod %s/%s already killed", payload.Namespace, payload.Name)
-		log.Println(msg)
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "skipped", "message": msg})
+		killLog.Info("Pod already killed, skipping")
+		recordKill(c, metricNamespace(settings, payload.Namespace), "skipped", strategy)
+		return c.JSON(KillResponse{StatusResponse: StatusResponse{Status: "skipped", Message: msg}})
 	}
 
-	if s.config.EnableKube {
-		if s.config.EnableOpenShiftAuth {
-			// Use the authenticated kube client from the context
-			client, ok := c.Locals("kubeClient").(kubernetes.Interface)
-			if !ok {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Kubernetes client not found"})
-			}
-			s.kubeClient = client
+	var rollbackAt time.Time
+	if s.kubeEnabled() && settings.KillDryRun {
+		killLog.Info("Dry run kill, pod not touched", "action", actionName)
+	} else if s.kubeEnabled() {
+		client, ok := s.clusterClientFor(c, payload.Cluster)
+		if !ok {
+			return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Kubernetes client not found")
+		}
+		action, ok := k8s.LookupAction(actionName)
+		if !ok {
+			return sendError(c, fiber.StatusInternalServerError, CodeInternal, fmt.Sprintf("Kill action %s is not registered", actionName))
+		}
+		change, err := k8s.ApplyAction(c.UserContext(), client, action, payload)
+		if err != nil {
+			killLog.Error("Failed to kill pod", "action", actionName, "error", err)
+			recordKill(c, metricNamespace(settings, payload.Namespace), "failure", strategy)
+			return sendError(c, fiber.StatusInternalServerError, CodeKubernetesError, fmt.Sprintf("Failed to kill pod: %v", err))
 		}
-		if err := k8s.KillPod(s.kubeClient, payload); err != nil {
-			log.Printf("Error killing pod: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Failed to kill pod: %v", err)})
+		if change != nil {
+			key := change.Key
+			if payload.Cluster != "" {
+				key = payload.Cluster + "/" + key
+				change.Undo.Cluster = payload.Cluster
+			}
+			rollbackAt = s.rollbacks.Schedule(c.UserContext(), k8s.Clients{Kube: client}, key, settings.ActionTTL, change.Undo)
+			killLog.Info("Scheduled rollback", "change", key, "rollback_at", rollbackAt)
 		}
 	} else {
-		log.Printf("Simulated kill for pod: %s/%s (not a real Kubernetes pod)", payload.Namespace, payload.Name)
+		killLog.Info("Simulated kill, not a real Kubernetes pod")
 	}
 
-	s.killCache.Add(payload)
-	log.Printf("Logging kill for pod: %s/%s", payload.Namespace, payload.Name)
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "success",
-		"message": fmt.Sprintf("Logged kill for pod: %s/%s", payload.Namespace, payload.Name),
+	s.killCache.Add(sessionID(c), payload)
+	recordKill(c, metricNamespace(settings, payload.Namespace), "success", strategy)
+	killLog.Info("Pod killed", "strategy", strategy)
+	return c.JSON(KillResponse{
+		StatusResponse: StatusResponse{
+			Status:  "success",
+			Message: fmt.Sprintf("Logged kill for pod: %s/%s", payload.Namespace, payload.Name),
+		},
+		Action:     actionName,
+		RollbackAt: rollbackAt,
 	})
 }
 
+// otherLabel stands in metrics for values taken from requests that are not configured,
+// so that clients cannot create series at will.
+const otherLabel = "other"
+
+// metricNamespace returns the namespace label of a kill: the namespace if it is targeted
+// or protected, otherLabel if not.
+func metricNamespace(settings *config.Runtime, namespace string) string {
+	if slices.Contains(settings.NamespaceNames, namespace) || slices.Contains(settings.KillProtectedNamespaces, namespace) {
+		return namespace
+	}
+	return otherLabel
+}
+
+// recordKill counts a kill attempt and records its outcome on the request span.
+func recordKill(c *fiber.Ctx, namespace, result, strategy string) {
+	metrics.Kills.WithLabelValues(namespace, result, strategy).Inc()
+	trace.SpanFromContext(c.UserContext()).SetAttributes(
+		attribute.String("kill.result", result),
+		attribute.String("kill.strategy", strategy),
+	)
+}
+
 // handlePostHighscore saves a new highscore.
 func (s *Server) handlePostHighscore(c *fiber.Ctx) error {
 	var hs game.Highscore
 	if err := c.BodyParser(&hs); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot parse JSON"})
+		metrics.HighscoreSubmissions.WithLabelValues("rejected").Inc()
+		return sendError(c, fiber.StatusBadRequest, CodeInvalidRequest, "cannot parse JSON")
+	}
+	if errs := validateHighscore(hs); len(errs) > 0 {
+		metrics.HighscoreSubmissions.WithLabelValues("rejected").Inc()
+		return sendValidationError(c, errs)
 	}
 	s.highscoreCache.Add(hs)
-	log.Printf("Highscore submitted: %+v", hs)
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "Highscore logged"})
+	metrics.HighscoreSubmissions.WithLabelValues("accepted").Inc()
+	// A highscore is submitted when the game is over
+	s.games.End(sessionID(c))
+	s.fakePods.End(sessionID(c))
+	s.killCache.End(sessionID(c))
+	logger(c).Info("Highscore submitted", "name", hs.Name, "score", hs.Score, "levels_finished", hs.LevelsFinished)
+	return c.JSON(StatusResponse{Status: "success", Message: "Highscore logged"})
 }
 
 // handleGetHighscores returns all saved highscores.
 func (s *Server) handleGetHighscores(c *fiber.Ctx) error {
 	scores := s.highscoreCache.Get()
-	if len(scores) == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No highscores found"})
+	if scores == nil {
+		scores = []game.Highscore{}
 	}
 	return c.JSON(scores)
 }
 
 // handlePostNamespaces updates the list of namespaces to query for pods.
 func (s *Server) handlePostNamespaces(c *fiber.Ctx) error {
-	if err := c.BodyParser(&s.namespaces); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot parse JSON"})
+	var payload game.Namespaces
+	if err := c.BodyParser(&payload); err != nil {
+		return sendError(c, fiber.StatusBadRequest, CodeInvalidRequest, "cannot parse JSON")
 	}
-	if len(s.namespaces.Namespaces) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no namespaces provided"})
+	if errs := validateNamespaces(payload); len(errs) > 0 {
+		return sendValidationError(c, errs)
+	}
+	for _, ns := range payload.Namespaces {
+		if s.settings().IsProtectedNamespace(ns) {
+			logger(c).Warn("Refusing to target a protected namespace", "namespace", ns)
+			return sendError(c, fiber.StatusForbidden, CodeNamespaceProtected, fmt.Sprintf("Namespace %s is protected", ns))
+		}
+	}
+
+	if s.simulator != nil {
+		if err := s.simulator.AddNamespaces(payload.Namespaces...); err != nil {
+			logger(c).Error("Failed to simulate namespaces", "error", err)
+			return sendError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to simulate namespaces")
+		}
 	}
-	log.Printf("Updated namespaces: %v", s.namespaces.Namespaces)
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "success",
-		"message": fmt.Sprintf("Updated namespaces to: %v", s.namespaces.Namespaces),
+	settings := *s.settings()
+	settings.NamespaceNames = payload.Namespaces
+	s.runtime.Store(&settings)
+
+	logger(c).Info("Updated namespaces", "namespaces", payload.Namespaces)
+	return c.JSON(StatusResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Updated namespaces to: %v", payload.Namespaces),
 	})
 }
 
+// handleGetSettings returns the runtime settings the browser applies when a new game starts.
+func (s *Server) handleGetSettings(c *fiber.Ctx) error {
+	settings := s.settings()
+	return c.JSON(SettingsResponse{Difficulty: settings.Difficulty, Targeting: settings.Targeting, KillActions: settings.KillActions})
+}
+
 // handleMonitor starts a new URL monitor.
 func (s *Server) handleMonitor(c *fiber.Ctx) error {
-	var m monitor.Monitor
+	var m MonitorRequest
 	if err := c.BodyParser(&m); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot parse JSON"})
-	}
-	if m.URL == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "monitor URL cannot be empty"})
+		return sendError(c, fiber.StatusBadRequest, CodeInvalidRequest, "cannot parse JSON")
 	}
-
-	// Validate URL format more strictly
-	parsedURL, err := url.Parse(m.URL)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid monitor URL format"})
+	if errs := validateMonitorRequest(m); len(errs) > 0 {
+		return sendValidationError(c, errs)
 	}
 
-	// Ensure URL has a valid scheme and host
-	if parsedURL.Scheme == "" || parsedURL.Host == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid monitor URL format"})
+	id, err := s.monitorManager.StartForOwner(context.WithoutCancel(c.UserContext()), sessionID(c), m.URL)
+	if errors.Is(err, monitor.ErrEgressDenied) {
+		return sendError(c, fiber.StatusForbidden, CodeEgressDenied, err.Error())
 	}
-
-	// Only allow http and https schemes
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid monitor URL format"})
+	if errors.Is(err, monitor.ErrLimitReached) {
+		return sendError(c, fiber.StatusTooManyRequests, CodeLimitReached, err.Error())
 	}
-
-	id, err := s.monitorManager.Start(context.Background(), m.URL)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return sendError(c, fiber.StatusInternalServerError, CodeInternal, err.Error())
 	}
 
-	log.Printf("Monitoring service started for URL: %s with ID: %s", m.URL, id)
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "success",
-		"message": fmt.Sprintf("Monitor started for URL: %s", m.URL),
-		"id":      id,
+	return c.JSON(MonitorStartedResponse{
+		StatusResponse: StatusResponse{
+			Status:  "success",
+			Message: fmt.Sprintf("Monitor started for URL: %s", m.URL),
+		},
+		ID: id,
 	})
 }
 
-// handleMonitorStop stops a running URL monitor.
+// handleMonitorStop stops a running URL monitor identified in the request body.
 func (s *Server) handleMonitorStop(c *fiber.Ctx) error {
-	var req struct {
-		ID string `json:"id"`
-	}
+	var req MonitorStopRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot parse JSON"})
+		return sendError(c, fiber.StatusBadRequest, CodeInvalidRequest, "cannot parse JSON")
 	}
-	if req.ID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "monitor ID is required"})
+	return s.stopMonitor(c, req.ID)
+}
+
+// handleDeleteMonitor stops a running URL monitor identified in the path.
+func (s *Server) handleDeleteMonitor(c *fiber.Ctx) error {
+	return s.stopMonitor(c, c.Params("id"))
+}
+
+// stopMonitor stops the monitor with the given ID if it belongs to the caller's session.
+func (s *Server) stopMonitor(c *fiber.Ctx, id string) error {
+	if id == "" {
+		return sendError(c, fiber.StatusBadRequest, CodeInvalidRequest, "monitor ID is required")
 	}
 
-	if err := s.monitorManager.Stop(req.ID); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	if err := s.monitorManager.StopForOwner(id, sessionID(c)); err != nil {
+		if errors.Is(err, monitor.ErrNotOwner) {
+			return sendError(c, fiber.StatusForbidden, CodeForbidden, err.Error())
+		}
+		return sendError(c, fiber.StatusNotFound, CodeNotFound, err.Error())
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "success",
-		"message": fmt.Sprintf("Monitoring service for ID %s stopped", req.ID),
+	return c.JSON(StatusResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Monitoring service for ID %s stopped", id),
 	})
 }
 
-// handleMonitorStatus returns the status of a specific monitor.
+// handleMonitorStatus returns the status of one of the caller's monitors, identified in the path
+// or, for the deprecated route, the id query parameter.
 func (s *Server) handleMonitorStatus(c *fiber.Ctx) error {
-	monitorID := c.Query("id")
+	monitorID := c.Params("id", c.Query("id"))
 	if monitorID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "monitor ID is required"})
+		return sendError(c, fiber.StatusBadRequest, CodeInvalidRequest, "monitor ID is required")
 	}
 
-	status, err := s.monitorManager.GetStatus(monitorID)
+	status, err := s.monitorManager.GetStatusForOwner(monitorID, sessionID(c))
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return sendError(c, fiber.StatusNotFound, CodeNotFound, err.Error())
 	}
 
 	return c.JSON(status)
 }
 
+// handleListMonitors returns the monitors owned by the caller's session.
+func (s *Server) handleListMonitors(c *fiber.Ctx) error {
+	return c.JSON(s.monitorManager.ListByOwner(sessionID(c)))
+}
+
+// handleHeartbeat marks the caller's game as active and keeps its monitors alive.
+func (s *Server) handleHeartbeat(c *fiber.Ctx) error {
+	s.games.Touch(sessionID(c))
+	touched := s.monitorManager.Touch(sessionID(c))
+	return c.JSON(HeartbeatResponse{Status: "success", Monitors: touched})
+}
+
 // Healthz checks if the server is healthy.
 func (s *Server) handleHealthz(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusOK)
@@ -230,11 +902,15 @@ func (s *Server) handleHealthz(c *fiber.Ctx) error {
 
 // Readyz checks if the server is ready to serve requests.
 func (s *Server) handleReadyz(c *fiber.Ctx) error {
-	if s.kubeClient == nil && s.config.EnableKube {
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Kubernetes client is not available"})
+	if s.shuttingDown.Load() {
+		return sendError(c, fiber.StatusServiceUnavailable, CodeUnavailable, "Server is shutting down")
+	}
+	// With OpenShift auth, Kubernetes clients are created per request from the user's token
+	if s.kubeClient == nil && s.config.EnableKube && !s.config.EnableOpenShiftAuth {
+		return sendError(c, fiber.StatusServiceUnavailable, CodeUnavailable, "Kubernetes client is not available")
 	}
 	if s.highscoreCache == nil {
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Highscore cache is not initialized"})
+		return sendError(c, fiber.StatusServiceUnavailable, CodeUnavailable, "Highscore cache is not initialized")
 	}
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -2,19 +2,57 @@ package api
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/template/html/v2"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
 	"github.com/cldmnky/pod-invaders/internal/config"
 	"github.com/cldmnky/pod-invaders/internal/game"
+	"github.com/cldmnky/pod-invaders/internal/k8s"
+	"github.com/cldmnky/pod-invaders/internal/metrics"
 	"github.com/cldmnky/pod-invaders/internal/monitor"
+	"github.com/cldmnky/pod-invaders/internal/scoring"
+	"github.com/cldmnky/pod-invaders/internal/simulator"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
 )
 
 // setupTestTemplate creates a temporary template file for testing
@@ -26,6 +64,7 @@ func setupTestTemplate(t *testing.T) string {
 <html>
 <head>
     <title>{{.Title}}</title>
+    <meta name="csrf-token" content="{{.CSRFToken}}">
 </head>
 <body>
     <h1>{{.Title}}</h1>
@@ -43,18 +82,31 @@ func setupTestTemplate(t *testing.T) string {
 // createTestServer creates a test server with mocked dependencies
 func createTestServer(enableKube bool) *Server {
 	cfg := &config.Config{
-		EnableKube:     enableKube,
-		NamespaceNames: []string{"default", "test"},
+		EnableKube: enableKube,
+		Runtime: config.Runtime{
+			NamespaceNames: []string{"default", "test"},
+			Difficulty:     config.DifficultyNormal,
+			KillActions:    []string{k8s.ActionDelete},
+			ActionTTL:      time.Minute,
+			HitPointRules:  scoring.DefaultRules,
+			HitPointsBase:  1,
+			HitPointsMax:   20,
+			LogLevel:       "info",
+		},
 	}
 
-	return &Server{
+	server := &Server{
 		config:         cfg,
 		kubeClient:     nil, // Mock kubernetes client would go here
-		killCache:      game.NewKillPodCache(),
+		killCache:      game.NewKillPodCache(fakePodGameTTL),
 		highscoreCache: game.NewInMemoryHighscoreCache(),
-		namespaces:     game.Namespaces{Namespaces: cfg.NamespaceNames},
 		monitorManager: monitor.NewManager(),
+		games:          game.NewSessionTracker(gameSessionTTL),
+		fakePods:       game.NewFakePodGenerators(0, game.DefaultFakePools, fakePodGameTTL),
+		rollbacks:      k8s.NewRollbacks(k8s.NewMemoryRollbackStore()),
 	}
+	server.applyConfig(cfg)
+	return server
 }
 
 // createTestApp creates a Fiber app with routes registered
@@ -65,19 +117,51 @@ func createTestApp(server *Server, templateDir string) *fiber.App {
 		// Create template engine with the provided directory
 		engine := html.New(templateDir, ".html")
 		app = fiber.New(fiber.Config{
-			Views: engine,
+			Views:        engine,
+			ErrorHandler: errorHandler,
 		})
 	} else {
 		// Create app without templates for non-template tests
-		app = fiber.New()
+		app = fiber.New(fiber.Config{ErrorHandler: errorHandler})
 	}
 
+	app.Use(tracingMiddleware())
+	app.Use(requestIDMiddleware())
+	app.Use(sessionMiddleware())
+	if server.csrf != nil {
+		app.Use(server.csrf.middleware())
+	}
+	if server.rateLimiter != nil {
+		app.Use(server.rateLimiter.middleware())
+	}
 	server.registerGameHandlers(app)
 	server.registerMonitorHandlers(app)
+	server.registerMetricsHandlers(app)
 
 	return app
 }
 
+// newLoopbackMonitorManager creates a monitor manager whose egress policy allows
+// probing local httptest servers.
+func newLoopbackMonitorManager(opts monitor.Options) *monitor.Manager {
+	opts.Egress.AllowedCIDRs = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	return monitor.NewManagerWithOptions(opts)
+}
+
+// withSession attaches a session cookie to a test request.
+func withSession(req *http.Request, id string) *http.Request {
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: id})
+	return req
+}
+
+func TestNewServerInvalidClusters(t *testing.T) {
+	// Clusters are validated when loading the configuration, which a caller may skip
+	cfg := &config.Config{EnableKube: true, Clusters: []string{"Not_A_Label"}}
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error without a valid cluster")
+	}
+}
+
 func TestHandleRoot(t *testing.T) {
 	server := createTestServer(false)
 	templateDir := setupTestTemplate(t)
@@ -132,6 +216,12 @@ func TestHandleGetNames(t *testing.T) {
 			queryParam:   "?count=150", // Should be capped at 100
 			expectedCode: 200,
 		},
+		{
+			name:         "fake pods with negative count",
+			enableKube:   false,
+			queryParam:   "?count=-1", // Should be raised to 1
+			expectedCode: 200,
+		},
 	}
 
 	for _, tt := range tests {
@@ -169,11 +259,112 @@ func TestHandleGetNames(t *testing.T) {
 				if tt.queryParam == "?count=150" && len(pods) > 100 {
 					t.Errorf("Expected maximum 100 pods, got %d", len(pods))
 				}
+				if tt.queryParam == "?count=-1" && len(pods) != 1 {
+					t.Errorf("Expected 1 pod, got %d", len(pods))
+				}
 			}
 		})
 	}
 }
 
+func TestHandleGetNamesUniqueWithinGame(t *testing.T) {
+	server := createTestServer(false)
+	server.fakePods = game.NewFakePodGenerators(42, game.DefaultFakePools, fakePodGameTTL)
+	app := createTestApp(server, "")
+	wave := func(session string) []string {
+		t.Helper()
+		resp, err := app.Test(withSession(httptest.NewRequest("GET", "/api/v1/pods?count=100", nil), session))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+		var pods []game.Pod
+		if err := json.NewDecoder(resp.Body).Decode(&pods); err != nil {
+			t.Fatalf("Failed to decode pods: %v", err)
+		}
+		var keys []string
+		for _, p := range pods {
+			keys = append(keys, p.Namespace+"/"+p.Name)
+		}
+		return keys
+	}
+
+	alice, bob := uuid.NewString(), uuid.NewString()
+	first := wave(alice)
+	seen := make(map[string]bool)
+	for _, key := range append(first, wave(alice)...) {
+		if seen[key] {
+			t.Fatalf("Pod %s was served twice in one game", key)
+		}
+		seen[key] = true
+	}
+	// The seed gives every game the same pods
+	if other := wave(bob); !reflect.DeepEqual(other, first) {
+		t.Errorf("Expected the same first wave in every game, got %v and %v", first, other)
+	}
+}
+
+func TestHandleKillSeededGames(t *testing.T) {
+	server := createTestServer(false)
+	server.fakePods = game.NewFakePodGenerators(42, game.DefaultFakePools, fakePodGameTTL)
+	app := createTestApp(server, "")
+	do := func(session, method, target string, payload any) *http.Response {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		req := withSession(httptest.NewRequest(method, target, bytes.NewReader(body)), session)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	firstPod := func(session string) game.Pod {
+		t.Helper()
+		var pods []game.Pod
+		if err := json.NewDecoder(do(session, "GET", "/api/v1/pods?count=1", nil).Body).Decode(&pods); err != nil || len(pods) != 1 {
+			t.Fatalf("Expected a pod, got %v (%v)", pods, err)
+		}
+		return pods[0]
+	}
+	kill := func(session string, pod game.Pod) string {
+		t.Helper()
+		var kill KillResponse
+		if err := json.NewDecoder(do(session, "POST", "/api/v1/kills", KillRequest{Pod: pod}).Body).Decode(&kill); err != nil {
+			t.Fatalf("Failed to decode kill: %v", err)
+		}
+		return kill.Status
+	}
+
+	alice, bob := uuid.NewString(), uuid.NewString()
+	pod := firstPod(alice)
+	if got := kill(alice, pod); got != "success" {
+		t.Fatalf("Expected the first kill to succeed, got %s", got)
+	}
+	if got := kill(alice, pod); got != "skipped" {
+		t.Errorf("Expected a second kill in the same game to be skipped, got %s", got)
+	}
+	// The seed gives every game the same fake pods, which must not count as killed already
+	if other := firstPod(bob); other.Name != pod.Name || other.Namespace != pod.Namespace {
+		t.Fatalf("Expected the same first pod in every game, got %+v and %+v", pod, other)
+	}
+	if got := kill(bob, pod); got != "success" {
+		t.Errorf("Expected the pod to be killed in another game, got %s", got)
+	}
+	// Submitting a highscore ends the game, and the next one starts over
+	highscore := game.Highscore{GameStarted: 1640995200, TimeTaken: 60000, LevelsFinished: 1, Score: 100, Name: "alice"}
+	if resp := do(alice, "POST", "/api/v1/highscores", highscore); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected the highscore to be saved, got %d", resp.StatusCode)
+	}
+	if next := firstPod(alice); next.Name != pod.Name || next.Namespace != pod.Namespace {
+		t.Fatalf("Expected the next game to start with the same pod, got %+v and %+v", pod, next)
+	}
+	if got := kill(alice, pod); got != "success" {
+		t.Errorf("Expected the pod to be killed in the next game, got %s", got)
+	}
+}
+
 func TestHandleKill(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -223,7 +414,7 @@ func TestHandleKill(t *testing.T) {
 
 				// For duplicate kill test, add the pod to cache first
 				if tt.name == "duplicate kill" {
-					server.killCache.Add(pod)
+					server.killCache.Add("", pod)
 				}
 			} else {
 				reqBody = []byte(tt.payload.(string))
@@ -246,6 +437,71 @@ func TestHandleKill(t *testing.T) {
 	}
 }
 
+func TestHandleKillPolicy(t *testing.T) {
+	// Kubernetes is enabled without a client, so only dry-run kills can succeed
+	server := createTestServer(true)
+	server.config.KillDryRun = true
+	server.config.KillProtectedNamespaces = []string{"kube-system"}
+	server.applyConfig(server.config)
+	app := createTestApp(server, "")
+
+	tests := []struct {
+		namespace    string
+		real         bool
+		expectedCode int
+	}{
+		{namespace: "default", real: true, expectedCode: 200},
+		{namespace: "kube-system", real: true, expectedCode: 403},
+		{namespace: "not-targeted", real: true, expectedCode: 403},
+		// Fake pods come from namespaces of their own
+		{namespace: "not-targeted", expectedCode: 200},
+	}
+
+	for _, tt := range tests {
+		body, _ := json.Marshal(game.Pod{Name: "victim", Namespace: tt.namespace, IsRealPod: tt.real})
+		req := httptest.NewRequest("POST", "/kill", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.expectedCode {
+			t.Errorf("Namespace %s (real %v): expected status %d, got %d", tt.namespace, tt.real, tt.expectedCode, resp.StatusCode)
+		}
+	}
+}
+
+func TestHandleGetSettingsAfterReload(t *testing.T) {
+	server := createTestServer(false)
+	app := createTestApp(server, "")
+
+	getDifficulty := func() string {
+		resp, err := app.Test(httptest.NewRequest("GET", "/settings", nil))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+		var settings SettingsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&settings); err != nil {
+			t.Fatalf("Failed to decode settings: %v", err)
+		}
+		return settings.Difficulty
+	}
+
+	if got := getDifficulty(); got != config.DifficultyNormal {
+		t.Errorf("Expected difficulty %s, got %s", config.DifficultyNormal, got)
+	}
+
+	reloaded := *server.config
+	reloaded.Difficulty = config.DifficultyHard
+	server.applyConfig(&reloaded)
+
+	if got := getDifficulty(); got != config.DifficultyHard {
+		t.Errorf("Expected difficulty %s after reload, got %s", config.DifficultyHard, got)
+	}
+}
+
 func TestHandlePostHighscore(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -322,7 +578,7 @@ func TestHandleGetHighscores(t *testing.T) {
 		{
 			name:          "no highscores",
 			addHighscores: nil,
-			expectedCode:  404,
+			expectedCode:  200,
 			expectedCount: 0,
 		},
 		{
@@ -398,10 +654,17 @@ func TestHandlePostNamespaces(t *testing.T) {
 		{
 			name: "valid namespaces",
 			payload: game.Namespaces{
-				Namespaces: []string{"default", "kube-system", "test"},
+				Namespaces: []string{"default", "staging", "test"},
 			},
 			expectedCode: 200,
 		},
+		{
+			name: "protected namespace",
+			payload: game.Namespaces{
+				Namespaces: []string{"default", "kube-system"},
+			},
+			expectedCode: 403,
+		},
 		{
 			name: "empty namespaces",
 			payload: game.Namespaces{
@@ -419,6 +682,8 @@ func TestHandlePostNamespaces(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			server := createTestServer(false)
+			server.config.KillProtectedNamespaces = []string{"kube-system"}
+			server.applyConfig(server.config)
 			app := createTestApp(server, "") // No templates needed
 
 			var reqBody []byte
@@ -450,9 +715,11 @@ func TestHandlePostNamespaces(t *testing.T) {
 			// If successful, verify namespaces were updated
 			if tt.expectedCode == 200 {
 				expectedNs := tt.payload.(game.Namespaces)
-				if len(server.namespaces.Namespaces) != len(expectedNs.Namespaces) {
-					t.Errorf("Expected %d namespaces, got %d", len(expectedNs.Namespaces), len(server.namespaces.Namespaces))
+				if len(server.settings().NamespaceNames) != len(expectedNs.Namespaces) {
+					t.Errorf("Expected %d namespaces, got %d", len(expectedNs.Namespaces), len(server.settings().NamespaceNames))
 				}
+			} else if slices.Contains(server.settings().NamespaceNames, "kube-system") {
+				t.Errorf("Expected the namespaces to stay unchanged, got %v", server.settings().NamespaceNames)
 			}
 		})
 	}
@@ -692,6 +959,7 @@ func TestHandleReadyz(t *testing.T) {
 		name           string
 		enableKube     bool
 		nilCache       bool
+		shuttingDown   bool
 		expectedCode   int
 		expectedSubstr string
 	}{
@@ -715,6 +983,13 @@ func TestHandleReadyz(t *testing.T) {
 			expectedCode:   503,
 			expectedSubstr: "Highscore cache is not initialized",
 		},
+		{
+			name:           "not ready - shutting down",
+			enableKube:     false,
+			shuttingDown:   true,
+			expectedCode:   503,
+			expectedSubstr: "Server is shutting down",
+		},
 	}
 
 	for _, tt := range tests {
@@ -723,6 +998,7 @@ func TestHandleReadyz(t *testing.T) {
 			if tt.nilCache {
 				server.highscoreCache = nil
 			}
+			server.shuttingDown.Store(tt.shuttingDown)
 			app := createTestApp(server, "") // No templates needed
 
 			req := httptest.NewRequest("GET", "/readyz", nil)
@@ -745,13 +1021,15 @@ func TestHandleReadyz(t *testing.T) {
 
 				bodyStr := string(body)
 				if len(bodyStr) > 0 {
-					var response map[string]interface{}
-					if err := json.Unmarshal(body, &response); err == nil {
-						if errorMsg, ok := response["error"].(string); ok {
-							if errorMsg != tt.expectedSubstr {
-								t.Errorf("Expected error message '%s', got '%s'", tt.expectedSubstr, errorMsg)
-							}
-						}
+					var response ErrorResponse
+					if err := json.Unmarshal(body, &response); err != nil {
+						t.Fatalf("Failed to unmarshal error response: %v", err)
+					}
+					if response.Error.Message != tt.expectedSubstr {
+						t.Errorf("Expected error message '%s', got '%s'", tt.expectedSubstr, response.Error.Message)
+					}
+					if response.Error.Code != CodeUnavailable {
+						t.Errorf("Expected error code %s, got %s", CodeUnavailable, response.Error.Code)
 					}
 				}
 			}
@@ -799,3 +1077,2041 @@ func TestHandleGetNamesIntegration(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleMonitorOwnership(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	server := createTestServer(false)
+	server.monitorManager = newLoopbackMonitorManager(monitor.DefaultOptions())
+	defer server.monitorManager.Close()
+	app := createTestApp(server, "")
+
+	const owner = "11111111-1111-1111-1111-111111111111"
+	const other = "22222222-2222-2222-2222-222222222222"
+
+	body, _ := json.Marshal(map[string]string{"url": target.URL})
+	req := withSession(httptest.NewRequest("POST", "/monitor", bytes.NewReader(body)), owner)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	var started struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&started); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	resp.Body.Close()
+
+	// The owner sees the monitor, another session does not
+	for session, expected := range map[string]int{owner: 1, other: 0} {
+		resp, err := app.Test(withSession(httptest.NewRequest("GET", "/monitors", nil), session))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		var statuses []monitor.Status
+		if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		resp.Body.Close()
+		if len(statuses) != expected {
+			t.Errorf("Session %s: expected %d monitors, got %d", session, expected, len(statuses))
+		}
+	}
+
+	// Another session may not see its status
+	for session, expected := range map[string]int{owner: 200, other: 404} {
+		resp, err := app.Test(withSession(httptest.NewRequest("GET", "/api/v1/monitors/"+started.ID, nil), session))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("Session %s: expected status %d, got %d", session, expected, resp.StatusCode)
+		}
+	}
+
+	// Another session may not stop it
+	stopBody, _ := json.Marshal(map[string]string{"id": started.ID})
+	for _, attempt := range []struct {
+		session  string
+		expected int
+	}{{other, 403}, {owner, 200}} {
+		req := withSession(httptest.NewRequest("POST", "/monitor/stop", bytes.NewReader(stopBody)), attempt.session)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != attempt.expected {
+			t.Errorf("Session %s: expected status %d, got %d", attempt.session, attempt.expected, resp.StatusCode)
+		}
+	}
+}
+
+func TestHandleMonitorLimit(t *testing.T) {
+	server := createTestServer(false)
+	server.monitorManager = newLoopbackMonitorManager(monitor.Options{MaxPerOwner: 1})
+	defer server.monitorManager.Close()
+	app := createTestApp(server, "")
+
+	const owner = "11111111-1111-1111-1111-111111111111"
+	body, _ := json.Marshal(map[string]string{"url": "http://127.0.0.1:1"})
+
+	for i, expected := range []int{200, 429} {
+		req := withSession(httptest.NewRequest("POST", "/monitor", bytes.NewReader(body)), owner)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("Request %d: expected status %d, got %d", i, expected, resp.StatusCode)
+		}
+	}
+}
+
+func TestHandleMonitorEgressDenied(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "cloud metadata endpoint", url: "http://169.254.169.254/latest/meta-data/"},
+		{name: "loopback address", url: "http://127.0.0.1:8080/admin"},
+		{name: "IPv6 loopback", url: "http://[::1]/"},
+		{name: "localhost name", url: "http://localhost:3000/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer(false)
+			defer server.monitorManager.Close()
+			app := createTestApp(server, "")
+
+			body, _ := json.Marshal(map[string]string{"url": tt.url})
+			req := httptest.NewRequest("POST", "/monitor", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != fiber.StatusForbidden {
+				body, _ := io.ReadAll(resp.Body)
+				t.Errorf("Expected status 403, got %d. Response: %s", resp.StatusCode, string(body))
+			}
+		})
+	}
+}
+
+func TestHandleHeartbeat(t *testing.T) {
+	server := createTestServer(false)
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/heartbeat", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	// A session cookie is issued to new browsers
+	found := false
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == sessionCookieName && cookie.Value != "" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected a session cookie to be set")
+	}
+}
+
+func TestHandleMetrics(t *testing.T) {
+	server := createTestServer(false)
+	app := createTestApp(server, "")
+
+	const owner = "11111111-1111-1111-1111-111111111111"
+	killsBefore := testutil.ToFloat64(metrics.Kills.WithLabelValues("test", "success", "simulated"))
+	otherBefore := testutil.ToFloat64(metrics.Kills.WithLabelValues("other", "success", "simulated"))
+	podsBefore := testutil.ToFloat64(metrics.PodsServed.WithLabelValues("fake"))
+
+	requests := []*http.Request{
+		withSession(httptest.NewRequest("POST", "/heartbeat", nil), owner),
+		httptest.NewRequest("GET", "/names?count=4", nil),
+	}
+	// Namespaces that are not targeted are counted as other
+	for _, ns := range []string{"test", "made-up-by-client"} {
+		killBody, _ := json.Marshal(game.Pod{Name: "victim", Namespace: ns})
+		killReq := httptest.NewRequest("POST", "/kill", bytes.NewReader(killBody))
+		killReq.Header.Set("Content-Type", "application/json")
+		requests = append(requests, killReq)
+	}
+
+	for _, req := range requests {
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if got := testutil.ToFloat64(metrics.Kills.WithLabelValues("test", "success", "simulated")) - killsBefore; got != 1 {
+		t.Errorf("Expected 1 successful kill to be counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.Kills.WithLabelValues("other", "success", "simulated")) - otherBefore; got != 1 {
+		t.Errorf("Expected 1 kill in another namespace to be counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.PodsServed.WithLabelValues("fake")) - podsBefore; got != 4 {
+		t.Errorf("Expected 4 fake pods to be counted, got %v", got)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	for _, want := range []string{
+		"pod_invaders_active_games 1",
+		`pod_invaders_kills_total{namespace="test",result="success",strategy="simulated"}`,
+		`pod_invaders_names_request_duration_seconds_count{mode="standalone"}`,
+		"pod_invaders_names_real_pod_ratio 0",
+	} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+	if bytes.Contains(body, []byte("made-up-by-client")) {
+		t.Error("Expected namespaces from requests not to become labels")
+	}
+}
+
+func TestServeGracefulShutdown(t *testing.T) {
+	server := createTestServer(false)
+	server.config.ShutdownDrain = 500 * time.Millisecond
+	server.config.ShutdownTimeout = 2 * time.Second
+	app := createTestApp(server, "")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	readyz := "http://" + ln.Addr().String() + "/readyz"
+	// Fresh connections per probe, like a kubelet; fasthttp only counts unused
+	// keep-alive connections as idle after a few seconds
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- server.serve(ctx, app, ln, nil) }()
+
+	// waitForReadyz polls until /readyz returns the expected status
+	waitForReadyz := func(expected int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			resp, err := client.Get(readyz)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode == expected {
+					return
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("Timed out waiting for /readyz to return %d", expected)
+	}
+
+	waitForReadyz(http.StatusOK)
+	cancel()
+
+	// Still serving, but no longer ready, while draining
+	waitForReadyz(http.StatusServiceUnavailable)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serve returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for shutdown")
+	}
+
+	if _, err := client.Get(readyz); err == nil {
+		t.Error("Expected the listener to be closed after shutdown")
+	}
+	if err := server.Close(context.Background()); err != nil {
+		t.Errorf("Close after shutdown failed: %v", err)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	server := createTestServer(false)
+	app := createTestApp(server, "")
+
+	t.Run("echoes a valid request ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		req.Header.Set(requestIDHeader, "abc-123")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get(requestIDHeader); got != "abc-123" {
+			t.Errorf("Expected request ID abc-123, got %q", got)
+		}
+	})
+
+	t.Run("replaces an invalid request ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		req.Header.Set(requestIDHeader, "bad id\n")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		got := resp.Header.Get(requestIDHeader)
+		if _, err := uuid.Parse(got); err != nil {
+			t.Errorf("Expected a generated UUID request ID, got %q", got)
+		}
+	})
+}
+
+// recordSpans installs a tracer provider that records finished spans in memory for the test.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), 1)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	if _, err := tracing.Setup(context.Background(), tracing.Options{}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+// findSpan returns the recorded span with the given name.
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("No span named %q in %d recorded spans", name, len(spans))
+	return tracetest.SpanStub{}
+}
+
+// spanAttribute returns the value of the attribute key on span, or an empty value.
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingKill(t *testing.T) {
+	exporter := recordSpans(t)
+
+	server := createTestServer(true)
+	server.kubeClient = fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "victim", Namespace: "default"},
+	})
+	app := createTestApp(server, "")
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	body, _ := json.Marshal(game.Pod{Name: "victim", Namespace: "default", IsRealPod: true})
+	req := httptest.NewRequest("POST", "/kill", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	spans := exporter.GetSpans()
+	request := findSpan(t, spans, "POST /kill")
+	if request.SpanKind != trace.SpanKindServer {
+		t.Errorf("Expected a server span, got %v", request.SpanKind)
+	}
+	if got := request.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("Expected the propagated trace ID %s, got %s", traceID, got)
+	}
+	for key, want := range map[attribute.Key]string{
+		"http.route":         "/kill",
+		"k8s.namespace.name": "default",
+		"k8s.pod.name":       "victim",
+		"kill.result":        "success",
+		"kill.strategy":      "delete",
+	} {
+		if got := spanAttribute(request, key).AsString(); got != want {
+			t.Errorf("Attribute %s: expected %q, got %q", key, want, got)
+		}
+	}
+	if got := spanAttribute(request, "http.response.status_code").AsInt64(); got != fiber.StatusOK {
+		t.Errorf("Expected status code attribute 200, got %d", got)
+	}
+
+	action := findSpan(t, spans, "k8s.Action")
+	if action.Parent.SpanID() != request.SpanContext.SpanID() {
+		t.Error("Expected k8s.Action to be a child of the request span")
+	}
+	kill := findSpan(t, spans, "k8s.KillPod")
+	if kill.Parent.SpanID() != action.SpanContext.SpanID() {
+		t.Error("Expected k8s.KillPod to be a child of the k8s.Action span")
+	}
+}
+
+func TestTracingGetNames(t *testing.T) {
+	exporter := recordSpans(t)
+
+	server := createTestServer(true)
+	server.kubeClient = fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/names?count=3", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	spans := exporter.GetSpans()
+	request := findSpan(t, spans, "GET /names")
+	getPods := findSpan(t, spans, "k8s.GetPods")
+	if getPods.Parent.SpanID() != request.SpanContext.SpanID() {
+		t.Error("Expected k8s.GetPods to be a child of the request span")
+	}
+	list := findSpan(t, spans, "k8s.ListPods")
+	if list.Parent.SpanID() != getPods.SpanContext.SpanID() {
+		t.Error("Expected k8s.ListPods to be a child of k8s.GetPods")
+	}
+}
+
+func TestAPIV1(t *testing.T) {
+	server := createTestServer(false)
+	server.config.KillProtectedNamespaces = []string{"kube-system"}
+	server.applyConfig(server.config)
+	app := createTestApp(server, "")
+
+	do := func(method, target string, payload interface{}) *http.Response {
+		t.Helper()
+		var body io.Reader
+		if payload != nil {
+			data, _ := json.Marshal(payload)
+			body = bytes.NewReader(data)
+		}
+		req := httptest.NewRequest(method, target, body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, target, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	decode := func(resp *http.Response, v interface{}) {
+		t.Helper()
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+
+	t.Run("empty highscores", func(t *testing.T) {
+		resp := do("GET", "/api/v1/highscores", nil)
+		var scores []game.Highscore
+		decode(resp, &scores)
+		if resp.StatusCode != fiber.StatusOK || scores == nil || len(scores) != 0 {
+			t.Errorf("Expected 200 with an empty list, got %d %v", resp.StatusCode, scores)
+		}
+	})
+
+	t.Run("kill", func(t *testing.T) {
+		resp := do("POST", "/api/v1/kills", game.Pod{Name: "victim", Namespace: "default"})
+		var status StatusResponse
+		decode(resp, &status)
+		if resp.StatusCode != fiber.StatusOK || status.Status != "success" {
+			t.Errorf("Expected a successful kill, got %d %+v", resp.StatusCode, status)
+		}
+	})
+
+	t.Run("error envelope", func(t *testing.T) {
+		for _, tt := range []struct {
+			method, target string
+			payload        interface{}
+			status         int
+			code           string
+		}{
+			{"POST", "/api/v1/kills", game.Pod{Name: "victim", Namespace: "kube-system"}, fiber.StatusForbidden, CodeNamespaceProtected},
+			{"PUT", "/api/v1/namespaces", game.Namespaces{}, fiber.StatusBadRequest, CodeValidationFailed},
+			{"GET", "/api/v1/monitors/unknown", nil, fiber.StatusNotFound, CodeNotFound},
+			{"DELETE", "/api/v1/monitors/unknown", nil, fiber.StatusNotFound, CodeNotFound},
+			{"GET", "/api/v1/nonexistent", nil, fiber.StatusNotFound, CodeNotFound},
+		} {
+			resp := do(tt.method, tt.target, tt.payload)
+			var envelope ErrorResponse
+			decode(resp, &envelope)
+			if resp.StatusCode != tt.status || envelope.Error.Code != tt.code || envelope.Error.Message == "" {
+				t.Errorf("%s %s: expected %d %s, got %d %+v", tt.method, tt.target, tt.status, tt.code, resp.StatusCode, envelope)
+			}
+		}
+	})
+
+	t.Run("monitor lifecycle", func(t *testing.T) {
+		resp := do("POST", "/api/v1/monitors", MonitorRequest{URL: "http://127.0.0.1:1"})
+		if resp.StatusCode == fiber.StatusForbidden {
+			// The default egress policy denies loopback; exercise the routes with the error instead
+			var envelope ErrorResponse
+			decode(resp, &envelope)
+			if envelope.Error.Code != CodeEgressDenied {
+				t.Errorf("Expected %s, got %+v", CodeEgressDenied, envelope)
+			}
+			return
+		}
+		var started MonitorStartedResponse
+		decode(resp, &started)
+		if started.ID == "" {
+			t.Fatalf("Expected a monitor ID, got %+v", started)
+		}
+		if resp := do("GET", "/api/v1/monitors/"+started.ID, nil); resp.StatusCode != fiber.StatusOK {
+			t.Errorf("Expected monitor status, got %d", resp.StatusCode)
+		}
+		if resp := do("DELETE", "/api/v1/monitors/"+started.ID, nil); resp.StatusCode != fiber.StatusOK {
+			t.Errorf("Expected monitor to be stopped, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestDeprecatedRoutes(t *testing.T) {
+	server := createTestServer(false)
+	app := createTestApp(server, "")
+
+	for target, successor := range map[string]string{
+		"/names":      "/api/v1/pods",
+		"/highscores": "/api/v1/highscores",
+		"/settings":   "/api/v1/settings",
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", target, resp.StatusCode)
+		}
+		if resp.Header.Get("Deprecation") != "true" {
+			t.Errorf("%s: expected a Deprecation header", target)
+		}
+		if want := "<" + successor + `>; rel="successor-version"`; resp.Header.Get("Link") != want {
+			t.Errorf("%s: expected Link %q, got %q", target, want, resp.Header.Get("Link"))
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/settings", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Deprecation") != "" {
+		t.Error("Expected no Deprecation header on the versioned route")
+	}
+}
+
+func TestOpenAPIDocumentsAllRoutes(t *testing.T) {
+	server := createTestServer(false)
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatalf("Failed to decode OpenAPI document: %v", err)
+	}
+
+	documented := 0
+	for _, route := range app.GetRoutes(true) {
+		if !strings.HasPrefix(route.Path, apiV1Prefix+"/") || route.Method == fiber.MethodHead {
+			continue
+		}
+		path := strings.TrimPrefix(route.Path, apiV1Prefix)
+		path = regexp.MustCompile(`:(\w+)`).ReplaceAllString(path, "{$1}")
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("%s %s is not documented", route.Method, route.Path)
+		}
+		documented++
+	}
+	if documented == 0 {
+		t.Fatal("Expected versioned routes to be registered")
+	}
+}
+
+// richPod returns a running pod owned by a ReplicaSet, with the metadata the game shows.
+func richPod(namespace, name string) *corev1.Pod {
+	controller := true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			UID:               "0b7c8f1e-6a43-4a9e-9d6e-3f1d2c5b8a90",
+			Labels:            map[string]string{"app": "web"},
+			CreationTimestamp: metav1.NewTime(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)),
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d8f7c9b4", Controller: &controller},
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "worker-1",
+			Containers: []corev1.Container{
+				{Name: "web", Image: "nginx:1.27"},
+				{Name: "proxy", Image: "envoyproxy/envoy:v1.31"},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			QOSClass:   corev1.PodQOSBurstable,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "web", RestartCount: 2},
+				{Name: "proxy", RestartCount: 1},
+			},
+		},
+	}
+}
+
+func TestGetPodsMetadata(t *testing.T) {
+	server := createTestServer(true)
+	server.kubeClient = fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		richPod("default", "web-5d8f7c9b4-x2x7q"),
+	)
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/pods?count=1", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var pods []game.Pod
+	if err := json.NewDecoder(resp.Body).Decode(&pods); err != nil || len(pods) != 1 {
+		t.Fatalf("Expected one pod, got %v (%v)", pods, err)
+	}
+
+	want := game.Pod{
+		Name:          "web-5d8f7c9b4-x2x7q",
+		Namespace:     "default",
+		IsRealPod:     true,
+		UID:           "0b7c8f1e-6a43-4a9e-9d6e-3f1d2c5b8a90",
+		OwnerKind:     "ReplicaSet",
+		OwnerName:     "web-5d8f7c9b4",
+		Node:          "worker-1",
+		Phase:         "Running",
+		Ready:         true,
+		RestartCount:  3,
+		Images:        []string{"nginx:1.27", "envoyproxy/envoy:v1.31"},
+		CreatedAt:     time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Labels:        map[string]string{"app": "web"},
+		QOSClass:      "Burstable",
+		OwnerReplicas: 1,  // The ReplicaSet is gone, so the pod counts as a singleton
+		HitPoints:     10, // 1 + replicas 8*1 + qos 2*0.5
+	}
+	if !reflect.DeepEqual(pods[0], want) {
+		t.Errorf("Unexpected pod metadata:\n got %+v\nwant %+v", pods[0], want)
+	}
+}
+
+func TestGetPodsHitPoints(t *testing.T) {
+	controller := true
+	replicas := func(n int32) *int32 { return &n }
+	critical := int32(1_000_000_000)
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: replicas(50)},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f7c9b4", Namespace: "default", OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Controller: &controller},
+			}},
+			Spec: appsv1.ReplicaSetSpec{Replicas: replicas(50)},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f7c9b4-x2x7q", Namespace: "default", Labels: map[string]string{"app": "web"}, OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d8f7c9b4", Controller: &controller},
+			}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, QOSClass: corev1.PodQOSBestEffort},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: replicas(1)},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "default", Labels: map[string]string{"app": "db"}, OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "db", Controller: &controller},
+			}},
+			Spec:   corev1.PodSpec{PriorityClassName: "critical", Priority: &critical},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, QOSClass: corev1.PodQOSGuaranteed},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+		},
+	}
+	server := createTestServer(true)
+	server.kubeClient = fake.NewSimpleClientset(objects...)
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/pods?count=2", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var pods []game.Pod
+	if err := json.NewDecoder(resp.Body).Decode(&pods); err != nil || len(pods) != 2 {
+		t.Fatalf("Expected two pods, got %v (%v)", pods, err)
+	}
+	byName := make(map[string]game.Pod)
+	for _, p := range pods {
+		byName[p.Name] = p
+	}
+
+	db := byName["db-0"]
+	if !db.CoveredByPDB || db.OwnerReplicas != 1 || db.PriorityClass != "critical" || db.Priority != critical {
+		t.Errorf("Unexpected signals for db-0: %+v", db)
+	}
+	if db.HitPoints != 19 {
+		t.Errorf("Expected the critical singleton to take 19 hits, got %d", db.HitPoints)
+	}
+	web := byName["web-5d8f7c9b4-x2x7q"]
+	if web.CoveredByPDB || web.OwnerReplicas != 50 {
+		t.Errorf("Unexpected signals for web replica: %+v", web)
+	}
+	if web.HitPoints != 1 {
+		t.Errorf("Expected one of fifty replicas to take 1 hit, got %d", web.HitPoints)
+	}
+}
+
+func TestHandleGetPod(t *testing.T) {
+	server := createTestServer(true)
+	server.kubeClient = fake.NewSimpleClientset(
+		richPod("default", "web-5d8f7c9b4-x2x7q"),
+		richPod("kube-system", "etcd-0"),
+	)
+	app := createTestApp(server, "")
+
+	tests := []struct {
+		name     string
+		target   string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "pod in a target namespace", target: "/api/v1/pods/default/web-5d8f7c9b4-x2x7q", wantCode: fiber.StatusOK},
+		{name: "unknown pod", target: "/api/v1/pods/default/missing", wantCode: fiber.StatusNotFound, wantErr: CodeNotFound},
+		{name: "namespace not targeted", target: "/api/v1/pods/kube-system/etcd-0", wantCode: fiber.StatusNotFound, wantErr: CodeNotFound},
+		{name: "invalid name", target: "/api/v1/pods/default/Not_Valid", wantCode: fiber.StatusBadRequest, wantErr: CodeValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.target, nil))
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d", tt.wantCode, resp.StatusCode)
+			}
+			if tt.wantErr != "" {
+				var envelope ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error.Code != tt.wantErr {
+					t.Errorf("Expected a %s error, got %+v (%v)", tt.wantErr, envelope, err)
+				}
+				return
+			}
+			var pod game.Pod
+			if err := json.NewDecoder(resp.Body).Decode(&pod); err != nil {
+				t.Fatalf("Failed to decode pod: %v", err)
+			}
+			if pod.OwnerName != "web-5d8f7c9b4" || pod.Node != "worker-1" || pod.RestartCount != 3 {
+				t.Errorf("Expected pod metadata, got %+v", pod)
+			}
+		})
+	}
+
+	t.Run("standalone mode", func(t *testing.T) {
+		app := createTestApp(createTestServer(false), "")
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/pods/default/web-5d8f7c9b4-x2x7q", nil))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != fiber.StatusNotFound {
+			t.Errorf("Expected 404 for fake pods, got %d", resp.StatusCode)
+		}
+	})
+}
+
+// workloadObjects returns a Deployment with a ReplicaSet and two pods, a StatefulSet with
+// two pods and a pod without a controller, all in namespace default.
+func workloadObjects() []runtime.Object {
+	controller := true
+	owned := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, Controller: &controller}}
+	}
+	pod := func(name string, owners []metav1.OwnerReference) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", OwnerReferences: owners},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	replicas := int32(3)
+	return []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: appsv1.DeploymentSpec{Replicas: &replicas}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f7c9b4", Namespace: "default", OwnerReferences: owned("Deployment", "web")}},
+		pod("web-5d8f7c9b4-aaaaa", owned("ReplicaSet", "web-5d8f7c9b4")),
+		pod("web-5d8f7c9b4-bbbbb", owned("ReplicaSet", "web-5d8f7c9b4")),
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}, Spec: appsv1.StatefulSetSpec{Replicas: &replicas}},
+		pod("db-0", owned("StatefulSet", "db")),
+		pod("db-1", owned("StatefulSet", "db")),
+		pod("debug", nil),
+	}
+}
+
+func TestHandleGetWorkloads(t *testing.T) {
+	server := createTestServer(true)
+	server.kubeClient = fake.NewSimpleClientset(workloadObjects()...)
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/workloads?rows=4&cols=5", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var workloads []game.Workload
+	if err := json.NewDecoder(resp.Body).Decode(&workloads); err != nil {
+		t.Fatalf("Failed to decode workloads: %v", err)
+	}
+	if len(workloads) != 4 {
+		t.Fatalf("Expected 4 rows, got %d", len(workloads))
+	}
+
+	byName := make(map[string]game.Workload)
+	fake := 0
+	for _, w := range workloads {
+		if !w.IsReal {
+			fake++
+			if len(w.Pods) != 5 {
+				t.Errorf("Expected fake rows to be full, got %d pods", len(w.Pods))
+			}
+			continue
+		}
+		byName[w.Name] = w
+	}
+	if fake != 1 {
+		t.Errorf("Expected one fake row, got %d", fake)
+	}
+	for _, want := range []struct {
+		name, kind string
+		replicas   int32
+		pods       int
+	}{
+		{"web", game.KindDeployment, 3, 2},
+		{"db", game.KindStatefulSet, 3, 2},
+		{"debug", game.KindPod, 1, 1},
+	} {
+		w, ok := byName[want.name]
+		if !ok {
+			t.Errorf("Expected a row for %s", want.name)
+			continue
+		}
+		if w.Kind != want.kind || w.Replicas != want.replicas || len(w.Pods) != want.pods {
+			t.Errorf("Expected %s %s with %d replicas and %d pods, got %s with %d replicas and %d pods",
+				want.kind, want.name, want.replicas, want.pods, w.Kind, w.Replicas, len(w.Pods))
+		}
+	}
+
+	// Rows are capped at cols pods
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/workloads?rows=3&cols=1", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&workloads); err != nil {
+		t.Fatalf("Failed to decode workloads: %v", err)
+	}
+	for _, w := range workloads {
+		if len(w.Pods) > 1 {
+			t.Errorf("Expected at most 1 pod per row, got %d for %s", len(w.Pods), w.Name)
+		}
+	}
+}
+
+func TestHandleGetBoss(t *testing.T) {
+	server := createTestServer(true)
+	server.kubeClient = fake.NewSimpleClientset(workloadObjects()...)
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/boss", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var boss game.Boss
+	if err := json.NewDecoder(resp.Body).Decode(&boss); err != nil {
+		t.Fatalf("Failed to decode boss: %v", err)
+	}
+	if boss.Kind != game.KindStatefulSet || boss.Name != "db" || !boss.IsReal {
+		t.Errorf("Expected the StatefulSet to be the boss, got %s %s", boss.Kind, boss.Name)
+	}
+	if boss.HitPoints != game.BossHitPoints(3) {
+		t.Errorf("Expected hit points for 3 replicas, got %d", boss.HitPoints)
+	}
+}
+
+func TestHandleBossDefeat(t *testing.T) {
+	restartedAt := func(t *testing.T, server *Server) string {
+		t.Helper()
+		d, err := server.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get deployment: %v", err)
+		}
+		return d.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"]
+	}
+	web := BossDefeatRequest{Kind: game.KindDeployment, Namespace: "default", Name: "web", IsReal: true}
+
+	tests := []struct {
+		name        string
+		configure   func(*config.Config)
+		request     BossDefeatRequest
+		wantCode    int
+		wantStatus  string
+		wantErr     string
+		wantRestart bool
+	}{
+		{name: "restart", request: web, wantCode: fiber.StatusOK, wantStatus: "success", wantRestart: true},
+		{name: "no action configured", configure: func(c *config.Config) { c.BossDefeatAction = config.BossActionNone }, request: web, wantCode: fiber.StatusOK, wantStatus: "skipped"},
+		{name: "random targeting", configure: func(c *config.Config) { c.Targeting = config.TargetingRandom }, request: web, wantCode: fiber.StatusOK, wantStatus: "skipped"},
+		{name: "dry run", configure: func(c *config.Config) { c.KillDryRun = true }, request: web, wantCode: fiber.StatusOK, wantStatus: "success"},
+		{name: "fake workload", request: BossDefeatRequest{Kind: game.KindDeployment, Namespace: "default", Name: "web"}, wantCode: fiber.StatusOK, wantStatus: "skipped"},
+		{name: "bare pod", request: BossDefeatRequest{Kind: game.KindPod, Namespace: "default", Name: "debug", IsReal: true}, wantCode: fiber.StatusOK, wantStatus: "skipped"},
+		{name: "protected namespace", configure: func(c *config.Config) { c.KillProtectedNamespaces = []string{"default"} }, request: web, wantCode: fiber.StatusForbidden, wantErr: CodeNamespaceProtected},
+		{name: "namespace not targeted", request: BossDefeatRequest{Kind: game.KindDeployment, Namespace: "other", Name: "web", IsReal: true}, wantCode: fiber.StatusForbidden, wantErr: CodeForbidden},
+		{name: "invalid kind", request: BossDefeatRequest{Kind: "Secret", Namespace: "default", Name: "web", IsReal: true}, wantCode: fiber.StatusBadRequest, wantErr: CodeValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer(true)
+			server.kubeClient = fake.NewSimpleClientset(workloadObjects()...)
+			server.config.Targeting = config.TargetingWorkload
+			server.config.BossDefeatAction = config.BossActionRestart
+			if tt.configure != nil {
+				tt.configure(server.config)
+			}
+			server.applyConfig(server.config)
+			app := createTestApp(server, "")
+
+			body, _ := json.Marshal(tt.request)
+			req := httptest.NewRequest("POST", "/api/v1/boss/defeats", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d", tt.wantCode, resp.StatusCode)
+			}
+			if tt.wantErr != "" {
+				var envelope ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error.Code != tt.wantErr {
+					t.Errorf("Expected a %s error, got %+v (%v)", tt.wantErr, envelope, err)
+				}
+			} else {
+				var status StatusResponse
+				if err := json.NewDecoder(resp.Body).Decode(&status); err != nil || status.Status != tt.wantStatus {
+					t.Errorf("Expected status %q, got %+v (%v)", tt.wantStatus, status, err)
+				}
+			}
+			if restarted := restartedAt(t, server) != ""; restarted != tt.wantRestart {
+				t.Errorf("Expected restarted = %v, got %v", tt.wantRestart, restarted)
+			}
+		})
+	}
+}
+
+// nodeObjects returns a namespace and two nodes; worker-1 runs a Deployment pod, a pod a
+// PodDisruptionBudget protects, a DaemonSet pod and a pod in kube-system.
+func nodeObjects() []runtime.Object {
+	controller := true
+	pod := func(namespace, name, node, ownerKind string) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		if ownerKind != "" {
+			p.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: ownerKind, Name: name + "-owner", Controller: &controller}}
+		}
+		return p
+	}
+	node := func(name string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node-role.kubernetes.io/worker": ""}},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+				NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.33.1"},
+			},
+		}
+	}
+	return []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		node("worker-1"),
+		node("worker-2"),
+		pod("default", "web-1", "worker-1", game.KindReplicaSet),
+		pod("default", "db-0", "worker-1", game.KindStatefulSet),
+		pod("default", "node-exporter-1", "worker-1", game.KindDaemonSet),
+		pod("kube-system", "coredns-1", "worker-1", game.KindReplicaSet),
+		pod("default", "web-2", "worker-2", game.KindReplicaSet),
+	}
+}
+
+func TestHandleGetNodes(t *testing.T) {
+	server := createTestServer(true)
+	server.kubeClient = fake.NewSimpleClientset(nodeObjects()...)
+	if _, err := server.kubeClient.CoreV1().Nodes().Patch(context.Background(), "worker-2", types.StrategicMergePatchType,
+		[]byte(`{"spec":{"unschedulable":true}}`), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("Failed to cordon node: %v", err)
+	}
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/nodes?count=3", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var nodes []game.Node
+	if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil || len(nodes) != 3 {
+		t.Fatalf("Expected three nodes, got %v (%v)", nodes, err)
+	}
+	var real []game.Node
+	for _, n := range nodes {
+		if n.IsReal {
+			real = append(real, n)
+		}
+	}
+	want := []game.Node{{Name: "worker-1", IsReal: true, Ready: true, Roles: []string{"worker"}, KubeletVersion: "v1.33.1"}}
+	if !reflect.DeepEqual(real, want) {
+		t.Errorf("Expected only the schedulable node to be real, got %+v", real)
+	}
+}
+
+func TestHandleNodeDrain(t *testing.T) {
+	worker := NodeDrainRequest{Name: "worker-1", IsReal: true}
+	tests := []struct {
+		name         string
+		configure    func(*config.Config)
+		cordoned     bool // Whether the node is cordoned before the request
+		request      NodeDrainRequest
+		wantCode     int
+		wantStatus   string
+		wantErr      string
+		wantCordoned bool
+		wantDrain    *game.NodeDrain
+	}{
+		{
+			name: "drain", request: worker, wantCode: fiber.StatusOK, wantStatus: "success", wantCordoned: true,
+			wantDrain: &game.NodeDrain{
+				Node:    "worker-1",
+				Evicted: []string{"default/web-1"},
+				Blocked: []string{"default/db-0"},
+				Skipped: []string{"default/node-exporter-1", "kube-system/coredns-1"},
+			},
+		},
+		// An operator cordoned the node, so it must not be uncordoned by a rollback
+		{name: "already cordoned", cordoned: true, request: worker, wantCode: fiber.StatusConflict, wantErr: CodeConflict, wantCordoned: true},
+		{name: "dry run", configure: func(c *config.Config) { c.KillDryRun = true }, request: worker, wantCode: fiber.StatusOK, wantStatus: "success"},
+		{name: "random targeting", configure: func(c *config.Config) { c.Targeting = config.TargetingRandom }, request: worker, wantCode: fiber.StatusOK, wantStatus: "skipped"},
+		{name: "fake node", request: NodeDrainRequest{Name: "worker-1"}, wantCode: fiber.StatusOK, wantStatus: "skipped"},
+		{name: "unknown node", request: NodeDrainRequest{Name: "worker-9", IsReal: true}, wantCode: fiber.StatusNotFound, wantErr: CodeNotFound},
+		{name: "invalid name", request: NodeDrainRequest{Name: "Worker_1", IsReal: true}, wantCode: fiber.StatusBadRequest, wantErr: CodeValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(nodeObjects()...)
+			if tt.cordoned {
+				if err := k8s.CordonNode(context.Background(), client, "worker-1"); err != nil {
+					t.Fatalf("Failed to cordon node: %v", err)
+				}
+			}
+			var evicted []string
+			client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+				evicted = append(evicted, eviction.Namespace+"/"+eviction.Name)
+				if eviction.Name == "db-0" {
+					return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10)
+				}
+				return false, nil, nil
+			})
+
+			server := createTestServer(true)
+			server.kubeClient = client
+			server.config.Targeting = config.TargetingNode
+			server.config.NodeUncordonAfter = time.Hour
+			server.config.KillProtectedNamespaces = []string{"kube-system"}
+			if tt.configure != nil {
+				tt.configure(server.config)
+			}
+			server.applyConfig(server.config)
+			defer server.rollbacks.Close(context.Background())
+			app := createTestApp(server, "")
+
+			body, _ := json.Marshal(tt.request)
+			req := httptest.NewRequest("POST", "/api/v1/nodes/drains", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d", tt.wantCode, resp.StatusCode)
+			}
+			if tt.wantErr != "" {
+				var envelope ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error.Code != tt.wantErr {
+					t.Errorf("Expected a %s error, got %+v (%v)", tt.wantErr, envelope, err)
+				}
+			} else {
+				var status NodeDrainResponse
+				if err := json.NewDecoder(resp.Body).Decode(&status); err != nil || status.Status != tt.wantStatus {
+					t.Errorf("Expected status %q, got %+v (%v)", tt.wantStatus, status, err)
+				}
+				if tt.wantDrain != nil {
+					if status.Drain != nil {
+						slices.Sort(status.Drain.Skipped)
+					}
+					if !reflect.DeepEqual(status.Drain, tt.wantDrain) {
+						t.Errorf("Unexpected drain:\n got %+v\nwant %+v", status.Drain, tt.wantDrain)
+					}
+					if status.UncordonAt.IsZero() {
+						t.Error("Expected the uncordon time to be reported")
+					}
+				}
+			}
+
+			node, err := client.CoreV1().Nodes().Get(context.Background(), "worker-1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Failed to get node: %v", err)
+			}
+			if node.Spec.Unschedulable != tt.wantCordoned {
+				t.Errorf("Expected cordoned = %v, got %v", tt.wantCordoned, node.Spec.Unschedulable)
+			}
+			if (!tt.wantCordoned || tt.cordoned) && len(evicted) > 0 {
+				t.Errorf("Expected no evictions, got %v", evicted)
+			}
+			if tt.cordoned && server.rollbacks.Pending("node/worker-1") {
+				t.Error("Expected no uncordon to be scheduled for a node cordoned before")
+			}
+			for _, pod := range evicted {
+				if pod == "default/web-2" || pod == "default/node-exporter-1" || pod == "kube-system/coredns-1" {
+					t.Errorf("Expected %s not to be evicted", pod)
+				}
+			}
+		})
+	}
+}
+
+func TestNodeUncordon(t *testing.T) {
+	schedulable := func(client kubernetes.Interface) bool {
+		node, err := client.CoreV1().Nodes().Get(context.Background(), "worker-1", metav1.GetOptions{})
+		return err == nil && !node.Spec.Unschedulable
+	}
+	drain := func(t *testing.T, server *Server) {
+		t.Helper()
+		app := createTestApp(server, "")
+		req := httptest.NewRequest("POST", "/api/v1/nodes/drains", strings.NewReader(`{"name":"worker-1","isRealNode":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil || resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Failed to drain node: %v (%v)", resp, err)
+		}
+		resp.Body.Close()
+		if schedulable(server.kubeClient) {
+			t.Fatal("Expected the node to be cordoned")
+		}
+	}
+
+	t.Run("after the configured duration", func(t *testing.T) {
+		server := createTestServer(true)
+		server.kubeClient = fake.NewSimpleClientset(nodeObjects()...)
+		server.config.Targeting = config.TargetingNode
+		server.config.NodeUncordonAfter = 50 * time.Millisecond
+		server.applyConfig(server.config)
+		drain(t, server)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for !schedulable(server.kubeClient) {
+			if time.Now().After(deadline) {
+				t.Fatal("Expected the node to be uncordoned")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("on close", func(t *testing.T) {
+		server := createTestServer(true)
+		server.kubeClient = fake.NewSimpleClientset(nodeObjects()...)
+		server.config.Targeting = config.TargetingNode
+		server.config.NodeUncordonAfter = time.Hour
+		server.applyConfig(server.config)
+		drain(t, server)
+
+		server.rollbacks.Close(context.Background())
+		if !schedulable(server.kubeClient) {
+			t.Error("Expected closing to uncordon the node")
+		}
+	})
+}
+
+// actionObjects returns a Deployment "web" of three replicas with two running pods, a
+// bare pod and a pod sharing its process namespace.
+func actionObjects() []runtime.Object {
+	controller := true
+	replicas := int32(3)
+	shared := true
+	webPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name), Labels: map[string]string{"app": "web"},
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d8f7c9b4", Controller: &controller}}},
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1.27"}, {Name: "proxy", Image: "envoyproxy/envoy:v1.31"}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	return []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f7c9b4", Namespace: "default", OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Controller: &controller},
+			}},
+			Spec: appsv1.ReplicaSetSpec{Replicas: &replicas},
+		},
+		webPod("web-5d8f7c9b4-x2x7q"),
+		webPod("web-5d8f7c9b4-k9p2z"),
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "shell", Image: "busybox:1.36"}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "sidecars", Namespace: "default"},
+			Spec:       corev1.PodSpec{ShareProcessNamespace: &shared, Containers: []corev1.Container{{Name: "app", Image: "nginx:1.27"}}},
+		},
+	}
+}
+
+func TestKillActions(t *testing.T) {
+	ctx := context.Background()
+	web := game.Pod{Name: "web-5d8f7c9b4-x2x7q", Namespace: "default", IsRealPod: true}
+	replicas := func(t *testing.T, client kubernetes.Interface) int32 {
+		t.Helper()
+		d, err := client.AppsV1().Deployments("default").Get(ctx, "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get deployment: %v", err)
+		}
+		return *d.Spec.Replicas
+	}
+	partitioned := func(t *testing.T, client kubernetes.Interface) bool {
+		t.Helper()
+		policies, err := client.NetworkingV1().NetworkPolicies("default").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("Failed to list NetworkPolicies: %v", err)
+		}
+		pod, err := client.CoreV1().Pods("default").Get(ctx, web.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get pod: %v", err)
+		}
+		label, labelled := pod.Labels["pod-invaders/partition"]
+		if len(policies.Items) == 0 {
+			if labelled {
+				t.Error("Expected the partition label to be removed with the NetworkPolicy")
+			}
+			return false
+		}
+		policy := policies.Items[0]
+		if label != "uid-"+web.Name || policy.Spec.PodSelector.MatchLabels["pod-invaders/partition"] != label ||
+			len(policy.Spec.PolicyTypes) != 2 || len(policy.Spec.Ingress) != 0 || len(policy.Spec.Egress) != 0 {
+			t.Errorf("Expected a deny-all NetworkPolicy selecting only the pod, got %+v with pod label %q", policy.Spec, label)
+		}
+		return true
+	}
+
+	tests := []struct {
+		name         string
+		action       string
+		pods         []game.Pod
+		wantCode     int
+		wantErr      string
+		wantRollback bool
+		check        func(t *testing.T, client kubernetes.Interface, rolledBack bool)
+	}{
+		{
+			name: "default delete", pods: []game.Pod{web}, wantCode: fiber.StatusOK,
+			check: func(t *testing.T, client kubernetes.Interface, _ bool) {
+				if _, err := client.CoreV1().Pods("default").Get(ctx, web.Name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+					t.Errorf("Expected the pod to be deleted, got %v", err)
+				}
+			},
+		},
+		{
+			name: "container kill", action: k8s.ActionContainerKill, pods: []game.Pod{web}, wantCode: fiber.StatusOK,
+			check: func(t *testing.T, client kubernetes.Interface, _ bool) {
+				pod, err := client.CoreV1().Pods("default").Get(ctx, web.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("Failed to get pod: %v", err)
+				}
+				if len(pod.Spec.EphemeralContainers) != 1 {
+					t.Fatalf("Expected one ephemeral container, got %+v", pod.Spec.EphemeralContainers)
+				}
+				ec := pod.Spec.EphemeralContainers[0]
+				if ec.TargetContainerName != "app" || !reflect.DeepEqual(ec.Command, []string{"kill", "1"}) {
+					t.Errorf("Expected kill 1 targeting the first container, got %+v", ec)
+				}
+			},
+		},
+		{
+			name: "container kill in shared process namespace", action: k8s.ActionContainerKill,
+			pods:     []game.Pod{{Name: "sidecars", Namespace: "default", IsRealPod: true}},
+			wantCode: fiber.StatusInternalServerError, wantErr: CodeKubernetesError,
+		},
+		{
+			name: "network partition", action: k8s.ActionNetworkPartition, pods: []game.Pod{web}, wantCode: fiber.StatusOK, wantRollback: true,
+			check: func(t *testing.T, client kubernetes.Interface, rolledBack bool) {
+				if partitioned(t, client) == rolledBack {
+					t.Errorf("Expected partitioned = %v", !rolledBack)
+				}
+			},
+		},
+		{
+			name: "scale to zero", action: k8s.ActionScaleToZero,
+			pods:     []game.Pod{web, {Name: "web-5d8f7c9b4-k9p2z", Namespace: "default", IsRealPod: true}},
+			wantCode: fiber.StatusOK, wantRollback: true,
+			check: func(t *testing.T, client kubernetes.Interface, rolledBack bool) {
+				want := int32(0)
+				if rolledBack {
+					want = 3 // Not the zero replicas seen by the second kill
+				}
+				if got := replicas(t, client); got != want {
+					t.Errorf("Expected %d replicas, got %d", want, got)
+				}
+			},
+		},
+		{
+			name: "scale bare pod", action: k8s.ActionScaleToZero,
+			pods:     []game.Pod{{Name: "debug", Namespace: "default", IsRealPod: true}},
+			wantCode: fiber.StatusInternalServerError, wantErr: CodeKubernetesError,
+		},
+		{
+			name: "action not allowed", action: "explode", pods: []game.Pod{web},
+			wantCode: fiber.StatusBadRequest, wantErr: CodeValidationFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(actionObjects()...)
+			server := createTestServer(true)
+			server.kubeClient = client
+			server.config.KillActions = []string{k8s.ActionDelete, k8s.ActionContainerKill, k8s.ActionNetworkPartition, k8s.ActionScaleToZero}
+			server.config.ActionTTL = time.Hour
+			server.applyConfig(server.config)
+			defer server.rollbacks.Close(context.Background())
+			app := createTestApp(server, "")
+
+			for _, pod := range tt.pods {
+				body, _ := json.Marshal(KillRequest{Pod: pod, Action: tt.action})
+				req := httptest.NewRequest("POST", "/api/v1/kills", bytes.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatalf("Failed to make request: %v", err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != tt.wantCode {
+					t.Fatalf("Expected status %d, got %d", tt.wantCode, resp.StatusCode)
+				}
+				if tt.wantErr != "" {
+					var envelope ErrorResponse
+					if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error.Code != tt.wantErr {
+						t.Errorf("Expected a %s error, got %+v (%v)", tt.wantErr, envelope, err)
+					}
+					continue
+				}
+				var kill KillResponse
+				if err := json.NewDecoder(resp.Body).Decode(&kill); err != nil || kill.Status != "success" {
+					t.Fatalf("Expected success, got %+v (%v)", kill, err)
+				}
+				wantAction := cmp.Or(tt.action, k8s.ActionDelete)
+				if kill.Action != wantAction || kill.RollbackAt.IsZero() == tt.wantRollback {
+					t.Errorf("Expected action %s with rollback %v, got %+v", wantAction, tt.wantRollback, kill)
+				}
+			}
+
+			if tt.check != nil {
+				tt.check(t, client, false)
+				server.rollbacks.Close(context.Background())
+				tt.check(t, client, true)
+			}
+		})
+	}
+}
+
+func TestKillActionRollbackAfterTTL(t *testing.T) {
+	client := fake.NewSimpleClientset(actionObjects()...)
+	server := createTestServer(true)
+	server.kubeClient = client
+	server.config.KillActions = []string{k8s.ActionScaleToZero}
+	server.config.ActionTTL = 50 * time.Millisecond
+	server.applyConfig(server.config)
+	app := createTestApp(server, "")
+
+	req := httptest.NewRequest("POST", "/api/v1/kills", strings.NewReader(`{"name":"web-5d8f7c9b4-x2x7q","namespace":"default","isRealPod":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Failed to kill pod: %v (%v)", resp, err)
+	}
+	resp.Body.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d, err := client.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+		if err == nil && *d.Spec.Replicas == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the deployment to be scaled back up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRollbacksSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := game.OpenBadgerDB(dir)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	client := fake.NewSimpleClientset(actionObjects()...)
+	server := createTestServer(true)
+	server.kubeClient = client
+	server.rollbacks = k8s.NewRollbacks(k8s.NewBadgerRollbackStore(db))
+	server.config.KillActions = []string{k8s.ActionScaleToZero}
+	server.config.ActionTTL = time.Hour
+	server.applyConfig(server.config)
+	app := createTestApp(server, "")
+
+	req := httptest.NewRequest("POST", "/api/v1/kills", strings.NewReader(`{"name":"web-5d8f7c9b4-x2x7q","namespace":"default","isRealPod":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Failed to kill pod: %v (%v)", resp, err)
+	}
+	resp.Body.Close()
+
+	// Crash: the rollback is never run, but the database is intact
+	db.Close()
+	db, err = game.OpenBadgerDB(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	store := k8s.NewBadgerRollbackStore(db)
+	records, err := store.List()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Expected one persisted rollback, got %+v (%v)", records, err)
+	}
+	rec := records[0]
+	want := k8s.Undo{Op: k8s.UndoScale, Namespace: "default", Name: "web", Args: map[string]string{"kind": "Deployment", "replicas": "3"}}
+	if rec.Key != "Deployment/default/web" || !reflect.DeepEqual(rec.Undo, want) {
+		t.Fatalf("Expected a rollback scaling web back to 3 replicas, got %+v", rec)
+	}
+	rec.Due = time.Now().Add(-time.Minute) // Overdue by the time the server is back
+	if err := store.Save(rec); err != nil {
+		t.Fatalf("Failed to save rollback: %v", err)
+	}
+
+	rollbacks := k8s.NewRollbacks(store)
+	defer rollbacks.Close(context.Background())
+	restored, err := rollbacks.Restore(ctx, k8s.Clients{Kube: client}, nil)
+	if err != nil || restored != 1 {
+		t.Fatalf("Expected one restored rollback, got %d (%v)", restored, err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d, err := client.AppsV1().Deployments("default").Get(ctx, "web", metav1.GetOptions{})
+		records, _ := store.List()
+		if err == nil && *d.Spec.Replicas == 3 && len(records) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the deployment scaled back up and the rollback forgotten, got %d rollbacks", len(records))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRollbacksKeepFailures(t *testing.T) {
+	k8s.RegisterUndo("test-fail", func(context.Context, k8s.Clients, k8s.Undo) error {
+		return errors.New("API server unavailable")
+	})
+	store := k8s.NewMemoryRollbackStore()
+	rollbacks := k8s.NewRollbacks(store)
+	client := fake.NewSimpleClientset(nodeObjects()...)
+	ctx := context.Background()
+	rollbacks.Schedule(ctx, k8s.Clients{Kube: client}, "fail", time.Hour, k8s.Undo{Op: "test-fail", Name: "x"})
+	rollbacks.Schedule(ctx, k8s.Clients{Kube: client}, "node/worker-1", time.Hour, k8s.Undo{Op: k8s.UndoUncordon, Name: "worker-1"})
+	rollbacks.Close(context.Background())
+
+	records, err := store.List()
+	if err != nil || len(records) != 1 || records[0].Key != "fail" {
+		t.Errorf("Expected only the failed rollback to stay persisted, got %+v (%v)", records, err)
+	}
+}
+
+var (
+	rolloutsGVR = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	vmsGVR      = schema.GroupVersionResource{Group: "kubevirt.io", Version: "v1", Resource: "virtualmachines"}
+	ksvcGVR     = schema.GroupVersionResource{Group: "serving.knative.dev", Version: "v1", Resource: "services"}
+)
+
+// createResourceServer returns a server in resource targeting over an Argo Rollout, a
+// KubeVirt VM and a Knative service in the default namespace, a Rollout in a namespace
+// that is not targeted and an undiscoverable resource.
+func createResourceServer() (*Server, dynamic.Interface) {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "argoproj.io/v1alpha1", APIResources: []metav1.APIResource{
+			{Name: "rollouts", Kind: "Rollout", Namespaced: true, Verbs: metav1.Verbs{"get", "list", "patch", "delete"}},
+			{Name: "rollouts/scale", Kind: "Scale", Namespaced: true, Verbs: metav1.Verbs{"get", "patch"}},
+		}},
+		{GroupVersion: "kubevirt.io/v1", APIResources: []metav1.APIResource{
+			{Name: "virtualmachines", Kind: "VirtualMachine", Namespaced: true, Verbs: metav1.Verbs{"get", "list", "patch"}},
+		}},
+		{GroupVersion: "serving.knative.dev/v1", APIResources: []metav1.APIResource{
+			{Name: "services", Kind: "Service", Namespaced: true, Verbs: metav1.Verbs{"get", "list", "delete"}},
+		}},
+	}
+	object := func(apiVersion, kind, namespace, name string, spec map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   map[string]any{"name": name, "namespace": namespace},
+			"spec":       spec,
+		}}
+	}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		rolloutsGVR: "RolloutList",
+		vmsGVR:      "VirtualMachineList",
+		ksvcGVR:     "ServiceList",
+	},
+		object("argoproj.io/v1alpha1", "Rollout", "default", "checkout", map[string]any{"replicas": int64(4)}),
+		object("argoproj.io/v1alpha1", "Rollout", "payments", "ledger", map[string]any{"replicas": int64(2)}),
+		object("kubevirt.io/v1", "VirtualMachine", "default", "db-vm", map[string]any{"running": true}),
+		object("serving.knative.dev/v1", "Service", "default", "hello", map[string]any{}),
+	)
+
+	server := createTestServer(true)
+	server.kubeClient = client
+	server.dynamicClient = dyn
+	server.config.Targeting = config.TargetingResource
+	server.config.ResourceTargets = []string{
+		"argoproj.io/v1alpha1/rollouts=scale",
+		`kubevirt.io/v1/virtualmachines=patch:{"spec":{"running":false},"metadata":{"annotations":{"pod-invaders/killed":"true"}}}`,
+		"serving.knative.dev/v1/services=delete",
+		"example.com/v1/widgets=delete",
+	}
+	server.config.ActionTTL = time.Hour
+	server.applyConfig(server.config)
+	return server, dyn
+}
+
+func TestHandleGetResources(t *testing.T) {
+	server, _ := createResourceServer()
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/resources?count=10", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var resources []game.Resource
+	if err := json.NewDecoder(resp.Body).Decode(&resources); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resources) != 10 {
+		t.Fatalf("Expected 10 resources, got %d", len(resources))
+	}
+
+	var real []string
+	for _, r := range resources {
+		if !slices.Contains([]string{"Rollout", "VirtualMachine", "Service"}, r.Kind) {
+			t.Errorf("Expected only discovered kinds, got %+v", r)
+		}
+		if !r.IsReal {
+			continue
+		}
+		real = append(real, r.Kind+"/"+r.Namespace+"/"+r.Name)
+		if r.Kind == "Rollout" && (r.Action != k8s.ResourceActionScale || r.Replicas == nil || *r.Replicas != 4) {
+			t.Errorf("Expected the rollout to be scaled with 4 replicas, got %+v", r)
+		}
+	}
+	slices.Sort(real)
+	want := []string{"Rollout/default/checkout", "Service/default/hello", "VirtualMachine/default/db-vm"}
+	if !slices.Equal(real, want) {
+		t.Errorf("Expected real resources %v, got %v", want, real)
+	}
+}
+
+func TestHandleResourceKill(t *testing.T) {
+	ctx := context.Background()
+	get := func(t *testing.T, dyn dynamic.Interface, gvr schema.GroupVersionResource, name string) *unstructured.Unstructured {
+		t.Helper()
+		obj, err := dyn.Resource(gvr).Namespace("default").Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", name, err)
+		}
+		return obj
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		wantCode     int
+		wantStatus   string
+		wantErr      string
+		wantRollback bool
+		check        func(t *testing.T, dyn dynamic.Interface, rolledBack bool)
+	}{
+		{
+			name:     "scale",
+			body:     `{"group":"argoproj.io","version":"v1alpha1","resource":"rollouts","namespace":"default","name":"checkout","isRealResource":true}`,
+			wantCode: fiber.StatusOK, wantStatus: "success", wantRollback: true,
+			check: func(t *testing.T, dyn dynamic.Interface, rolledBack bool) {
+				want := int64(0)
+				if rolledBack {
+					want = 4
+				}
+				replicas, _, _ := unstructured.NestedInt64(get(t, dyn, rolloutsGVR, "checkout").Object, "spec", "replicas")
+				if replicas != want {
+					t.Errorf("Expected %d replicas, got %d", want, replicas)
+				}
+			},
+		},
+		{
+			name:     "patch",
+			body:     `{"group":"kubevirt.io","version":"v1","resource":"virtualmachines","namespace":"default","name":"db-vm","isRealResource":true}`,
+			wantCode: fiber.StatusOK, wantStatus: "success", wantRollback: true,
+			check: func(t *testing.T, dyn dynamic.Interface, rolledBack bool) {
+				vm := get(t, dyn, vmsGVR, "db-vm")
+				running, _, _ := unstructured.NestedBool(vm.Object, "spec", "running")
+				_, annotated := vm.GetAnnotations()["pod-invaders/killed"]
+				if running != rolledBack || annotated == rolledBack {
+					t.Errorf("Expected running %v and annotated %v, got %v and %v", rolledBack, !rolledBack, running, annotated)
+				}
+			},
+		},
+		{
+			name:     "delete",
+			body:     `{"group":"serving.knative.dev","version":"v1","resource":"services","namespace":"default","name":"hello","isRealResource":true}`,
+			wantCode: fiber.StatusOK, wantStatus: "success",
+			check: func(t *testing.T, dyn dynamic.Interface, _ bool) {
+				if _, err := dyn.Resource(ksvcGVR).Namespace("default").Get(ctx, "hello", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+					t.Errorf("Expected the service to be deleted, got %v", err)
+				}
+			},
+		},
+		{
+			name:     "fake resource",
+			body:     `{"group":"argoproj.io","version":"v1alpha1","resource":"rollouts","namespace":"default","name":"checkout"}`,
+			wantCode: fiber.StatusOK, wantStatus: "skipped",
+		},
+		{
+			name:     "resource not targeted",
+			body:     `{"group":"apps","version":"v1","resource":"deployments","namespace":"default","name":"web","isRealResource":true}`,
+			wantCode: fiber.StatusForbidden, wantErr: CodeForbidden,
+		},
+		{
+			name:     "namespace not targeted",
+			body:     `{"group":"argoproj.io","version":"v1alpha1","resource":"rollouts","namespace":"payments","name":"ledger","isRealResource":true}`,
+			wantCode: fiber.StatusForbidden, wantErr: CodeForbidden,
+		},
+		{
+			name:     "not found",
+			body:     `{"group":"argoproj.io","version":"v1alpha1","resource":"rollouts","namespace":"default","name":"ghost","isRealResource":true}`,
+			wantCode: fiber.StatusNotFound, wantErr: CodeNotFound,
+		},
+		{
+			name:     "invalid",
+			body:     `{"version":"v1","namespace":"default","name":"checkout","isRealResource":true}`,
+			wantCode: fiber.StatusBadRequest, wantErr: CodeValidationFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, dyn := createResourceServer()
+			defer server.rollbacks.Close(context.Background())
+			app := createTestApp(server, "")
+
+			req := httptest.NewRequest("POST", "/api/v1/resources/kills", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d", tt.wantCode, resp.StatusCode)
+			}
+			if tt.wantErr != "" {
+				var envelope ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error.Code != tt.wantErr {
+					t.Errorf("Expected a %s error, got %+v (%v)", tt.wantErr, envelope, err)
+				}
+				return
+			}
+			var kill KillResponse
+			if err := json.NewDecoder(resp.Body).Decode(&kill); err != nil || kill.Status != tt.wantStatus {
+				t.Fatalf("Expected status %s, got %+v (%v)", tt.wantStatus, kill, err)
+			}
+			if kill.RollbackAt.IsZero() == tt.wantRollback {
+				t.Errorf("Expected rollback %v, got %+v", tt.wantRollback, kill)
+			}
+			if tt.check != nil {
+				tt.check(t, dyn, false)
+				server.rollbacks.Close(context.Background())
+				tt.check(t, dyn, true)
+			}
+		})
+	}
+
+	t.Run("untargeted resources are counted as other", func(t *testing.T) {
+		server, _ := createResourceServer()
+		defer server.rollbacks.Close(context.Background())
+		app := createTestApp(server, "")
+		before := testutil.ToFloat64(metrics.ResourceKills.WithLabelValues("other", "denied", ""))
+		req := httptest.NewRequest("POST", "/api/v1/resources/kills", strings.NewReader(`{"group":"made.up","version":"v1","resource":"things","namespace":"default","name":"x","isRealResource":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		if got := testutil.ToFloat64(metrics.ResourceKills.WithLabelValues("other", "denied", "")) - before; got != 1 {
+			t.Errorf("Expected the kill to be counted as other, got %v", got)
+		}
+	})
+}
+
+// createFleetServer returns a server in fleet mode with clusters dev and edge, each
+// running one pod of the same name.
+func createFleetServer() (*Server, map[string]kubernetes.Interface) {
+	clients := map[string]kubernetes.Interface{
+		"dev":  fake.NewSimpleClientset(actionObjects()...),
+		"edge": fake.NewSimpleClientset(actionObjects()...),
+	}
+	server := createTestServer(true)
+	server.fleet = k8s.NewFleet()
+	for _, name := range []string{"dev", "edge"} {
+		server.fleet.Add(name, k8s.Clients{Kube: clients[name]})
+	}
+	server.kubeClient = clients["dev"]
+	server.config.NamespaceNames = []string{"default"}
+	server.applyConfig(server.config)
+	return server, clients
+}
+
+func TestFleetGetPods(t *testing.T) {
+	server, _ := createFleetServer()
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/pods?count=20", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var pods []game.Pod
+	if err := json.NewDecoder(resp.Body).Decode(&pods); err != nil || len(pods) != 20 {
+		t.Fatalf("Expected 20 pods, got %v (%v)", pods, err)
+	}
+	realPods := make(map[string]int)
+	for _, p := range pods {
+		if p.Cluster != "dev" && p.Cluster != "edge" {
+			t.Errorf("Expected every pod to come from a cluster of the fleet, got %+v", p)
+		}
+		if p.IsRealPod {
+			realPods[p.Cluster]++
+		}
+	}
+	// actionObjects has four pods per cluster
+	if realPods["dev"] != 4 || realPods["edge"] != 4 {
+		t.Errorf("Expected the real pods of both clusters, got %v", realPods)
+	}
+
+	for target, wantCode := range map[string]int{
+		"/api/v1/pods/default/web-5d8f7c9b4-x2x7q?cluster=edge": fiber.StatusOK,
+		"/api/v1/pods/default/web-5d8f7c9b4-x2x7q":              fiber.StatusBadRequest,
+		"/api/v1/pods/default/web-5d8f7c9b4-x2x7q?cluster=prod": fiber.StatusBadRequest,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		var pod game.Pod
+		if resp.StatusCode != wantCode {
+			t.Errorf("%s: expected status %d, got %d", target, wantCode, resp.StatusCode)
+		} else if wantCode == fiber.StatusOK && (json.NewDecoder(resp.Body).Decode(&pod) != nil || pod.Cluster != "edge") {
+			t.Errorf("%s: expected the pod of cluster edge, got %+v", target, pod)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestFleetKill(t *testing.T) {
+	ctx := context.Background()
+	pod := "web-5d8f7c9b4-x2x7q"
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		deleted  string // Cluster the pod is deleted from
+	}{
+		{name: "routed to edge", body: `{"name":"` + pod + `","namespace":"default","cluster":"edge","isRealPod":true}`, wantCode: fiber.StatusOK, deleted: "edge"},
+		{name: "routed to dev", body: `{"name":"` + pod + `","namespace":"default","cluster":"dev","isRealPod":true}`, wantCode: fiber.StatusOK, deleted: "dev"},
+		{name: "cluster missing", body: `{"name":"` + pod + `","namespace":"default","isRealPod":true}`, wantCode: fiber.StatusBadRequest},
+		{name: "unknown cluster", body: `{"name":"` + pod + `","namespace":"default","cluster":"prod","isRealPod":true}`, wantCode: fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, clients := createFleetServer()
+			app := createTestApp(server, "")
+
+			req := httptest.NewRequest("POST", "/api/v1/kills", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d", tt.wantCode, resp.StatusCode)
+			}
+			for name, client := range clients {
+				_, err := client.CoreV1().Pods("default").Get(ctx, pod, metav1.GetOptions{})
+				if gone := apierrors.IsNotFound(err); gone != (name == tt.deleted) {
+					t.Errorf("Cluster %s: expected pod deleted to be %t, got %v", name, name == tt.deleted, err)
+				}
+			}
+		})
+	}
+
+	t.Run("same pod in two clusters", func(t *testing.T) {
+		server, clients := createFleetServer()
+		app := createTestApp(server, "")
+		for _, cluster := range []string{"dev", "edge"} {
+			req := httptest.NewRequest("POST", "/api/v1/kills", strings.NewReader(`{"name":"`+pod+`","namespace":"default","cluster":"`+cluster+`","isRealPod":true}`))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			var kill KillResponse
+			if err := json.NewDecoder(resp.Body).Decode(&kill); err != nil || kill.Status != "success" {
+				t.Errorf("Expected the kill in %s to succeed, got %+v (%v)", cluster, kill, err)
+			}
+			resp.Body.Close()
+			if _, err := clients[cluster].CoreV1().Pods("default").Get(ctx, pod, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+				t.Errorf("Expected the pod to be deleted from %s, got %v", cluster, err)
+			}
+		}
+	})
+}
+
+func TestFleetRollbacks(t *testing.T) {
+	server, clients := createFleetServer()
+	server.config.KillActions = []string{k8s.ActionScaleToZero}
+	server.applyConfig(server.config)
+	app := createTestApp(server, "")
+
+	req := httptest.NewRequest("POST", "/api/v1/kills", strings.NewReader(`{"name":"web-5d8f7c9b4-x2x7q","namespace":"default","cluster":"edge","isRealPod":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Failed to kill pod: %v (%v)", resp, err)
+	}
+	resp.Body.Close()
+	if !server.rollbacks.Pending("edge/Deployment/default/web") {
+		t.Error("Expected the rollback to be keyed by cluster")
+	}
+
+	replicas := func(cluster string) int32 {
+		d, err := clients[cluster].AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get deployment: %v", err)
+		}
+		return *d.Spec.Replicas
+	}
+	if replicas("edge") != 0 || replicas("dev") != 3 {
+		t.Fatalf("Expected only edge to be scaled to zero, got edge %d, dev %d", replicas("edge"), replicas("dev"))
+	}
+
+	// Rollbacks restored after a restart go back to the cluster of their change
+	store := k8s.NewMemoryRollbackStore()
+	if err := store.Save(k8s.RollbackRecord{
+		Key:  "edge/Deployment/default/web",
+		Undo: k8s.Undo{Op: k8s.UndoScale, Cluster: "edge", Namespace: "default", Name: "web", Args: map[string]string{"kind": game.KindDeployment, "replicas": "3"}},
+		Due:  time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to save rollback: %v", err)
+	}
+	rollbacks := k8s.NewRollbacks(store)
+	if restored, err := rollbacks.Restore(context.Background(), k8s.Clients{Kube: clients["dev"]}, server.fleet); err != nil || restored != 1 {
+		t.Fatalf("Expected one rollback restored, got %d (%v)", restored, err)
+	}
+	rollbacks.Close(context.Background())
+	if replicas("edge") != 3 {
+		t.Errorf("Expected edge to be scaled back up, got %d", replicas("edge"))
+	}
+}
+
+func TestSimulatorStandalone(t *testing.T) {
+	ctx := context.Background()
+	server := createTestServer(false)
+	sim, err := simulator.New(simulator.Options{RecreateDelay: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to start simulated cluster: %v", err)
+	}
+	server.simulator = sim
+	defer server.simulator.Close()
+	server.kubeClient = server.simulator.Client()
+	server.applyConfig(server.config)
+	app := createTestApp(server, "")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/pods?count=30", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	var pods []game.Pod
+	err = json.NewDecoder(resp.Body).Decode(&pods)
+	resp.Body.Close()
+	if err != nil || len(pods) != 30 {
+		t.Fatalf("Expected 30 pods, got %v (%v)", pods, err)
+	}
+	var victim game.Pod
+	for _, p := range pods {
+		if p.IsRealPod && p.Namespace == "default" {
+			victim = p
+			break
+		}
+	}
+	if victim.Name == "" {
+		t.Fatalf("Expected simulated pods in default, got %+v", pods)
+	}
+
+	body := `{"name":"` + victim.Name + `","namespace":"default","isRealPod":true}`
+	req := httptest.NewRequest("POST", "/api/v1/kills", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	var kill KillResponse
+	err = json.NewDecoder(resp.Body).Decode(&kill)
+	resp.Body.Close()
+	if err != nil || kill.Status != "success" {
+		t.Fatalf("Expected the kill to succeed, got %+v (%v)", kill, err)
+	}
+	client := server.simulator.Client()
+	if _, err := client.CoreV1().Pods("default").Get(ctx, victim.Name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("Expected the pod to be deleted, got %v", err)
+	}
+	before, err := client.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list pods: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		after, err := client.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("Failed to list pods: %v", err)
+		}
+		if len(after.Items) > len(before.Items) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the killed pod to be replaced, still %d pods", len(after.Items))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Namespaces chosen in the game get a workload of their own
+	req = httptest.NewRequest("PUT", "/api/v1/namespaces", strings.NewReader(`{"namespaces":["staging"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	staging, err := client.CoreV1().Pods("staging").List(ctx, metav1.ListOptions{})
+	if err != nil || len(staging.Items) == 0 {
+		t.Errorf("Expected simulated pods in staging, got %v (%v)", staging, err)
+	}
+}
@@ -1,28 +1,37 @@
 package api
 
 import (
-	"log"
+	"log/slog"
+	"net/http"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"k8s.io/client-go/kubernetes"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/client-go/rest"
+
+	"github.com/cldmnky/pod-invaders/internal/k8s"
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
 )
 
 // OpenShiftAuthMiddleware extracts the user's access token from the oauth-proxy
 func (s *Server) OpenShiftAuthMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Skip authentication for health checks
-		if c.Path() == "/healthz" || c.Path() == "/readyz" {
+		// Skip authentication for health checks and metrics scraping
+		if c.Path() == "/healthz" || c.Path() == "/readyz" || c.Path() == "/metrics" {
 			return c.Next()
 		}
 
 		// Extract the access token from the header set by oauth-proxy
 		accessToken := c.Get("X-Forwarded-Access-Token")
 		if accessToken == "" {
-			log.Printf("No access token found in request")
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Authentication required",
-			})
+			logger(c).Warn("No access token found in request")
+			return sendError(c, fiber.StatusUnauthorized, CodeUnauthorized, "Authentication required")
 		}
 
 		// Create a Kubernetes client using the user's token
@@ -34,18 +43,135 @@ func (s *Server) OpenShiftAuthMiddleware() fiber.Handler {
 			},
 		}
 
-		clientset, err := kubernetes.NewForConfig(config)
+		clients, err := k8s.NewClientsForConfig(config)
 		if err != nil {
-			log.Printf("Failed to create Kubernetes client with user token: %v", err)
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid authentication token",
-			})
+			logger(c).Warn("Failed to create Kubernetes client with user token", "error", err)
+			return sendError(c, fiber.StatusUnauthorized, CodeUnauthorized, "Invalid authentication token")
 		}
 
-		// Store the authenticated client in the context for use by handlers
-		c.Locals("kubeClient", clientset)
+		// Store the authenticated clients in the context for use by handlers
+		c.Locals("kubeClient", clients.Kube)
+		c.Locals("dynamicClient", clients.Dynamic)
 		c.Locals("userToken", accessToken)
 
 		return c.Next()
 	}
 }
+
+// sessionCookieName is the cookie that identifies a browser's game session.
+const sessionCookieName = "pod_invaders_session"
+
+// sessionMiddleware assigns every browser a random session ID, used to scope
+// ownership of monitors and to tie them to game heartbeats.
+func sessionMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Fiber reuses the request buffer, so copy the cookie before keeping it around
+		id := strings.Clone(c.Cookies(sessionCookieName))
+		if _, err := uuid.Parse(id); err != nil {
+			id = uuid.New().String()
+			c.Cookie(&fiber.Cookie{
+				Name:     sessionCookieName,
+				Value:    id,
+				Path:     "/",
+				HTTPOnly: true,
+				SameSite: fiber.CookieSameSiteLaxMode,
+			})
+		}
+		c.Locals("sessionID", id)
+		return c.Next()
+	}
+}
+
+// sessionID returns the session ID assigned by sessionMiddleware, or an empty string.
+func sessionID(c *fiber.Ctx) string {
+	id, _ := c.Locals("sessionID").(string)
+	return id
+}
+
+// requestIDHeader carries the request ID to and from clients and proxies.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+// requestIDMiddleware tags every request with an ID, reusing a well-formed X-Request-ID
+// from the client or proxy, and attaches a logger carrying it to the request context.
+func requestIDMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := strings.Clone(c.Get(requestIDHeader))
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		c.Set(requestIDHeader, id)
+		c.Locals("requestID", id)
+		log := slog.Default().With("request_id", id)
+		if sc := trace.SpanContextFromContext(c.UserContext()); sc.IsValid() {
+			log = log.With("trace_id", sc.TraceID().String())
+		}
+		c.SetUserContext(logging.WithLogger(c.UserContext(), log))
+		return c.Next()
+	}
+}
+
+// validRequestID reports whether id is a non-empty, bounded string of safe characters.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// logger returns the request-scoped logger set up by requestIDMiddleware.
+func logger(c *fiber.Ctx) *slog.Logger {
+	return logging.FromContext(c.UserContext())
+}
+
+// tracingMiddleware starts a server span for every request, continuing a trace
+// propagated by the client, and makes it the parent of spans started by handlers.
+func tracingMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Fiber reuses request buffers, so copy strings that outlive the request
+		method := strings.Clone(c.Method())
+		header := http.Header{}
+		for key, values := range c.GetReqHeaders() {
+			for _, value := range values {
+				header.Add(key, strings.Clone(value))
+			}
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), propagation.HeaderCarrier(header))
+		ctx, span := tracing.Tracer().Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("url.path", strings.Clone(c.Path())),
+			attribute.String("client.address", strings.Clone(c.IP())),
+		))
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if fe, ok := err.(*fiber.Error); ok {
+			status = fe.Code
+		}
+		if route := c.Route().Path; route != "" {
+			span.SetName(method + " " + route)
+			span.SetAttributes(attribute.String("http.route", route))
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	_ "embed"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// openAPISpec describes the versioned API. Keep it in sync with the routes under apiV1Prefix.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// handleOpenAPI serves the OpenAPI document of the versioned API.
+func handleOpenAPI(c *fiber.Ctx) error {
+	c.Type("json")
+	return c.Send(openAPISpec)
+}
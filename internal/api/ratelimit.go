@@ -0,0 +1,148 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/cldmnky/pod-invaders/internal/config"
+	"github.com/cldmnky/pod-invaders/internal/metrics"
+	"github.com/cldmnky/pod-invaders/internal/ratelimit"
+)
+
+// Route groups with separate rate limit budgets
+const (
+	rateGroupPods     = "pods"     // Pod, workload and boss listing, which list pods in every namespace
+	rateGroupActions  = "actions"  // Kills, boss defeats, highscores and namespace changes
+	rateGroupMonitors = "monitors" // Monitor management, which starts outbound probes
+	rateGroupAPI      = "api"      // Everything else in the API
+)
+
+// rateLimiter enforces per-client budgets on the API, keyed by authenticated user or client IP.
+type rateLimiter struct {
+	groups         map[string]*ratelimit.Limiter
+	trustedProxies []netip.Prefix
+}
+
+// newRateLimiter creates the limiters configured in cfg. It returns nil if every group is off.
+func newRateLimiter(cfg *config.Config) (*rateLimiter, error) {
+	r := &rateLimiter{groups: make(map[string]*ratelimit.Limiter)}
+	for group, spec := range map[string]string{
+		rateGroupPods:     cfg.RateLimitPods,
+		rateGroupActions:  cfg.RateLimitActions,
+		rateGroupMonitors: cfg.RateLimitMonitors,
+		rateGroupAPI:      cfg.RateLimitAPI,
+	} {
+		limit, err := ratelimit.ParseLimit(spec)
+		if err != nil {
+			return nil, err
+		}
+		if limit.Enabled() {
+			r.groups[group] = ratelimit.New(limit)
+		}
+	}
+	if len(r.groups) == 0 {
+		return nil, nil
+	}
+
+	for _, cidr := range cfg.TrustedProxies {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		r.trustedProxies = append(r.trustedProxies, prefix.Masked())
+	}
+	return r, nil
+}
+
+// routeGroup returns the rate limit group of a request, or an empty string for routes
+// that are not limited, such as the game page, static files and probes.
+func routeGroup(method, path string) string {
+	versioned := strings.HasPrefix(path, apiV1Prefix+"/")
+	p := strings.TrimPrefix(path, apiV1Prefix)
+	switch {
+	case p == "/pods" || path == "/names", p == "/workloads", p == "/boss", p == "/nodes", p == "/resources":
+		return rateGroupPods
+	case p == "/kills" || path == "/kill", p == "/boss/defeats", p == "/nodes/drains", p == "/resources/kills",
+		p == "/highscores" && method == fiber.MethodPost, path == "/highscore",
+		p == "/namespaces":
+		return rateGroupActions
+	case strings.HasPrefix(p, "/monitors") || strings.HasPrefix(path, "/monitor"):
+		return rateGroupMonitors
+	case versioned, path == "/highscores", path == "/heartbeat", path == "/settings":
+		return rateGroupAPI
+	}
+	return ""
+}
+
+// middleware rejects requests that exceed their client's budget with 429 and Retry-After.
+func (r *rateLimiter) middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		group := routeGroup(c.Method(), c.Path())
+		limiter, ok := r.groups[group]
+		if !ok {
+			return c.Next()
+		}
+
+		allowed, retryAfter := limiter.Allow(r.clientKey(c))
+		if allowed {
+			return c.Next()
+		}
+
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		metrics.RateLimited.WithLabelValues(group).Inc()
+		logger(c).Debug("Rate limited request", "group", group, "retry_after", retryAfter)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+		return sendError(c, fiber.StatusTooManyRequests, CodeRateLimited, fmt.Sprintf("Rate limit exceeded, retry in %ds", seconds))
+	}
+}
+
+// clientKey identifies the client of a request: the user when authenticated through
+// OpenShift OAuth, otherwise the client IP.
+func (r *rateLimiter) clientKey(c *fiber.Ctx) string {
+	if token, ok := c.Locals("userToken").(string); ok && token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return "user:" + hex.EncodeToString(sum[:16])
+	}
+	return "ip:" + r.clientIP(c).String()
+}
+
+// clientIP returns the address of the client. Requests from trusted proxies are
+// attributed to the last untrusted address in X-Forwarded-For, so clients cannot
+// pick their own key by prepending addresses to the header.
+func (r *rateLimiter) clientIP(c *fiber.Ctx) netip.Addr {
+	addr, _ := netip.AddrFromSlice(c.Context().RemoteIP())
+	addr = addr.Unmap()
+	if !r.trusted(addr) {
+		return addr
+	}
+
+	hops := strings.Split(c.Get(fiber.HeaderXForwardedFor), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !r.trusted(addr) {
+			break
+		}
+	}
+	return addr
+}
+
+// trusted reports whether addr belongs to a trusted proxy.
+func (r *rateLimiter) trusted(addr netip.Addr) bool {
+	for _, prefix := range r.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
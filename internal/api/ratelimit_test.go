@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/cldmnky/pod-invaders/internal/config"
+	"github.com/cldmnky/pod-invaders/internal/metrics"
+	"github.com/cldmnky/pod-invaders/internal/ratelimit"
+)
+
+func TestRouteGroup(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"GET", "/api/v1/pods", rateGroupPods},
+		{"GET", "/names", rateGroupPods},
+		{"GET", "/api/v1/workloads", rateGroupPods},
+		{"GET", "/api/v1/boss", rateGroupPods},
+		{"POST", "/api/v1/boss/defeats", rateGroupActions},
+		{"GET", "/api/v1/nodes", rateGroupPods},
+		{"POST", "/api/v1/nodes/drains", rateGroupActions},
+		{"GET", "/api/v1/resources", rateGroupPods},
+		{"POST", "/api/v1/resources/kills", rateGroupActions},
+		{"GET", "/api/v1/pods/default/web", rateGroupAPI},
+		{"POST", "/api/v1/kills", rateGroupActions},
+		{"POST", "/kill", rateGroupActions},
+		{"POST", "/api/v1/highscores", rateGroupActions},
+		{"POST", "/highscore", rateGroupActions},
+		{"PUT", "/api/v1/namespaces", rateGroupActions},
+		{"POST", "/namespaces", rateGroupActions},
+		{"POST", "/api/v1/monitors", rateGroupMonitors},
+		{"DELETE", "/api/v1/monitors/abc", rateGroupMonitors},
+		{"GET", "/monitor/status", rateGroupMonitors},
+		{"GET", "/monitors", rateGroupMonitors},
+		{"GET", "/api/v1/highscores", rateGroupAPI},
+		{"GET", "/highscores", rateGroupAPI},
+		{"POST", "/api/v1/heartbeat", rateGroupAPI},
+		{"GET", "/settings", rateGroupAPI},
+		{"GET", "/api/v1/openapi.json", rateGroupAPI},
+		{"GET", "/", ""},
+		{"GET", "/js/game.js", ""},
+		{"GET", "/healthz", ""},
+		{"GET", "/metrics", ""},
+	}
+	for _, tt := range tests {
+		if got := routeGroup(tt.method, tt.path); got != tt.want {
+			t.Errorf("routeGroup(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestNewRateLimiterAllOff(t *testing.T) {
+	limiter, err := newRateLimiter(&config.Config{
+		RateLimitPods:     ratelimit.Off,
+		RateLimitActions:  ratelimit.Off,
+		RateLimitMonitors: ratelimit.Off,
+		RateLimitAPI:      ratelimit.Off,
+	})
+	if err != nil || limiter != nil {
+		t.Errorf("Expected no limiter when every group is off, got %v, %v", limiter, err)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	server := createTestServer(false)
+	limiter, err := newRateLimiter(&config.Config{
+		RateLimitPods:  "0.001:2",
+		RateLimitAPI:   "10:10",
+		TrustedProxies: []string{"0.0.0.0/32"}, // The address of requests made with app.Test
+	})
+	if err != nil {
+		t.Fatalf("newRateLimiter failed: %v", err)
+	}
+	server.rateLimiter = limiter
+	app := createTestApp(server, "")
+
+	get := func(target, forwardedFor string) int {
+		t.Helper()
+		req := httptest.NewRequest("GET", target, nil)
+		if forwardedFor != "" {
+			req.Header.Set(fiber.HeaderXForwardedFor, forwardedFor)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == fiber.StatusTooManyRequests {
+			if resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+				t.Error("Expected a Retry-After header")
+			}
+			var envelope ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error.Code != CodeRateLimited {
+				t.Errorf("Expected a %s error, got %+v (%v)", CodeRateLimited, envelope, err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	rejected := testutil.ToFloat64(metrics.RateLimited.WithLabelValues(rateGroupPods))
+
+	const client = "203.0.113.7"
+	for i := 0; i < 2; i++ {
+		if code := get("/api/v1/pods?count=1", client); code != fiber.StatusOK {
+			t.Fatalf("Request %d within the burst: expected 200, got %d", i+1, code)
+		}
+	}
+	if code := get("/api/v1/pods?count=1", client); code != fiber.StatusTooManyRequests {
+		t.Errorf("Expected 429 once the burst is used, got %d", code)
+	}
+	// The deprecated alias shares the budget
+	if code := get("/names?count=1", client); code != fiber.StatusTooManyRequests {
+		t.Errorf("Expected 429 on the deprecated alias, got %d", code)
+	}
+	// Prepending addresses to X-Forwarded-For does not pick a new bucket
+	if code := get("/api/v1/pods?count=1", "198.51.100.1, "+client); code != fiber.StatusTooManyRequests {
+		t.Errorf("Expected 429 with a spoofed X-Forwarded-For, got %d", code)
+	}
+	if got := testutil.ToFloat64(metrics.RateLimited.WithLabelValues(rateGroupPods)) - rejected; got != 3 {
+		t.Errorf("Expected 3 rate limited requests to be counted, got %v", got)
+	}
+
+	// Other groups and other clients have their own budgets
+	if code := get("/api/v1/settings", client); code != fiber.StatusOK {
+		t.Errorf("Expected another group to be allowed, got %d", code)
+	}
+	if code := get("/api/v1/pods?count=1", "198.51.100.2"); code != fiber.StatusOK {
+		t.Errorf("Expected another client to be allowed, got %d", code)
+	}
+	// Limiting off for a group
+	if code := get("/", client); code == fiber.StatusTooManyRequests {
+		t.Error("Expected the game page not to be limited")
+	}
+}
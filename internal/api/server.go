@@ -1,16 +1,29 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"mime"
+	"net"
 	"net/http"
+	"os/signal"
 	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/dgraph-io/badger/v4"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/template/html/v2"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
@@ -18,91 +31,465 @@ import (
 	"github.com/cldmnky/pod-invaders/internal/config"
 	"github.com/cldmnky/pod-invaders/internal/game"
 	"github.com/cldmnky/pod-invaders/internal/k8s"
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/metrics"
 	"github.com/cldmnky/pod-invaders/internal/monitor"
+	"github.com/cldmnky/pod-invaders/internal/scoring"
+	"github.com/cldmnky/pod-invaders/internal/simulator"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
 )
 
+// gameSessionTTL is how long a game counts as active after its last heartbeat.
+const gameSessionTTL = 30 * time.Second
+
+// fakePodGameTTL is how long the fake pods of a game are remembered after its last wave,
+// and kills after they happen.
+const fakePodGameTTL = 30 * time.Minute
+
 // Server holds the dependencies for the API server.
 type Server struct {
 	config         *config.Config
 	kubeClient     kubernetes.Interface
+	dynamicClient  dynamic.Interface  // Client for resource targeting, nil with OpenShift auth
+	fleet          *k8s.Fleet         // Clusters of fleet mode, nil when playing against one cluster
+	simulator      *simulator.Cluster // Cluster of standalone mode, nil without one
+	fakePods       *game.FakePodGenerators
 	killCache      *game.KillPodCache
 	highscoreCache game.HighscoreCache
-	namespaces     game.Namespaces
+	runtime        atomic.Pointer[config.Runtime] // Settings swapped in on config reload
+	scorer         atomic.Pointer[scoring.Scorer] // Hit points of real pods, rebuilt on config reload
 	monitorManager *monitor.Manager
-	kubeConfig     *rest.Config // Kubernetes configuration for client creation
+	rateLimiter    *rateLimiter         // Per-client request budgets, nil when rate limiting is off
+	csrf           *csrfProtection      // Guards state-changing requests against cross-site forgery
+	games          *game.SessionTracker // Games in progress, tracked by heartbeat
+	rollbacks      *k8s.Rollbacks       // Temporary chaos actions and drained nodes, waiting to be undone
+	kubeConfig     *rest.Config         // Kubernetes configuration for client creation
+	db             *badger.DB           // Database shared by the highscore cache and monitor store
+	shuttingDown   atomic.Bool          // Set once shutdown starts so readiness fails
+	closeOnce      sync.Once
 }
 
 // NewServer creates a new API server instance.
 func NewServer(cfg *config.Config) (*Server, error) {
-	var kc kubernetes.Interface
+	var clients k8s.Clients
+	var kubeConfig *rest.Config
+	var fleet *k8s.Fleet
+	var sim *simulator.Cluster
 	var err error
 
-	if cfg.EnableKube {
-		log.Println("Kubernetes client is enabled, attempting to connect.")
+	if cfg.EnableKube && len(cfg.Clusters) > 0 {
+		slog.Info("Fleet mode is enabled, attempting to connect", "clusters", len(cfg.Clusters))
+		fleet, err = k8s.LoadFleet(cfg.ParsedClusters(), cfg.Kubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load clusters: %w", err)
+		}
+		// Requests that are not about a particular cluster go to the first one
+		names := fleet.Names()
+		if len(names) == 0 {
+			return nil, errors.New("no valid clusters for fleet mode")
+		}
+		var ok bool
+		if clients, ok = fleet.Clients(names[0]); !ok {
+			return nil, fmt.Errorf("no clients for cluster %s", names[0])
+		}
+	} else if cfg.EnableKube {
+		slog.Info("Kubernetes client is enabled, attempting to connect")
+		kubeConfig, err = k8s.GetKubeConfig(cfg.Kubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get kube config: %w", err)
+		}
+		// With OpenShift auth, requests use clients authenticated as the player
 		if !cfg.EnableOpenShiftAuth {
-			kc, err = k8s.GetKubeClient(cfg.Kubeconfig)
+			clients, err = k8s.NewClientsForConfig(kubeConfig)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get kube client: %w", err)
 			}
 		}
+	} else if cfg.Simulate {
+		slog.Info("Kubernetes client is disabled, running in standalone mode against a simulated cluster")
 	} else {
-		log.Println("Kubernetes client is disabled, running in standalone mode.")
+		slog.Info("Kubernetes client is disabled, running in standalone mode")
+	}
+
+	fakePools := game.DefaultFakePools
+	if cfg.FakePodPools != "" {
+		fakePools, err = game.LoadFakePools(cfg.FakePodPools)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	limiter, err := newRateLimiter(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit: %w", err)
+	}
+	csrf, err := newCSRFProtection(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up CSRF protection: %w", err)
 	}
 
-	// Initialize the highscore cache
-	highscoreCache, err := game.NewBadgerCache(cfg.HighscoreDBPath)
+	// Open the database shared by the highscore cache and the monitor store
+	var db *badger.DB
+	if cfg.StorageBackend == config.StorageBadger {
+		db, err = game.OpenBadgerDB(cfg.HighscoreDBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+	}
+	closeDB := func() {
+		if db != nil {
+			db.Close()
+		}
+	}
+
+	egress, err := monitor.NewEgressPolicy(cfg.MonitorAllowedHosts, cfg.MonitorAllowedCIDRs, cfg.MonitorMaxResponseBytes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize highscore cache: %w", err)
+		closeDB()
+		return nil, fmt.Errorf("invalid monitor egress policy: %w", err)
+	}
+
+	var notifiers []monitor.Notifier
+	for _, spec := range cfg.MonitorWebhooks {
+		webhook, err := monitor.ParseWebhook(spec, cfg.MonitorWebhookRetries)
+		if err != nil {
+			closeDB()
+			return nil, fmt.Errorf("invalid monitor webhook: %w", err)
+		}
+		notifiers = append(notifiers, webhook)
 	}
 
-	return &Server{
+	if !cfg.EnableKube && cfg.Simulate {
+		sim, err = simulator.New(simulator.Options{
+			RecreateDelay: cfg.SimRecreateDelay,
+			CrashInterval: cfg.SimCrashInterval,
+			APIErrorRate:  cfg.SimAPIErrorRate,
+		}, cfg.NamespaceNames...)
+		if err != nil {
+			closeDB()
+			return nil, fmt.Errorf("failed to start simulated cluster: %w", err)
+		}
+		clients = k8s.Clients{Kube: sim.Client()}
+	}
+
+	var store monitor.Store = monitor.NewMemoryStore()
+	var rollbackStore k8s.RollbackStore = k8s.NewMemoryRollbackStore()
+	highscoreCache := game.NewInMemoryHighscoreCache()
+	if db != nil {
+		store = monitor.NewBadgerStore(db)
+		highscoreCache = game.NewBadgerCacheFromDB(db)
+		// Changes to a simulated cluster must not be rolled back on a real one after a restart
+		if cfg.EnableKube || !cfg.Simulate {
+			rollbackStore = k8s.NewBadgerRollbackStore(db)
+		}
+	}
+
+	monitorManager := monitor.NewManagerWithOptions(monitor.Options{
+		MaxMonitors:   cfg.MonitorMaxCount,
+		MaxPerOwner:   cfg.MonitorMaxPerSession,
+		IdleTimeout:   cfg.MonitorIdleTimeout,
+		Egress:        egress,
+		Store:         store,
+		Notifiers:     notifiers,
+		AlertDebounce: cfg.MonitorAlertDebounce,
+	})
+	restored, err := monitorManager.Restore(context.Background())
+	if err != nil {
+		slog.Error("Failed to restore monitors", "error", err)
+	} else if restored > 0 {
+		slog.Info("Restored monitors", "count", restored, "path", cfg.HighscoreDBPath)
+	}
+
+	server := &Server{
 		config:         cfg,
-		kubeClient:     kc,
-		killCache:      game.NewKillPodCache(),
+		kubeClient:     clients.Kube,
+		dynamicClient:  clients.Dynamic,
+		fleet:          fleet,
+		simulator:      sim,
+		fakePods:       game.NewFakePodGenerators(cfg.FakePodSeed, fakePools, fakePodGameTTL),
+		killCache:      game.NewKillPodCache(fakePodGameTTL),
 		highscoreCache: highscoreCache,
-		namespaces:     game.Namespaces{Namespaces: cfg.NamespaceNames},
-		monitorManager: monitor.NewManager(),
-	}, nil
+		monitorManager: monitorManager,
+		rateLimiter:    limiter,
+		csrf:           csrf,
+		games:          game.NewSessionTracker(gameSessionTTL),
+		rollbacks:      k8s.NewRollbacks(rollbackStore),
+		kubeConfig:     kubeConfig,
+		db:             db,
+	}
+	server.applyConfig(cfg)
+	if cfg.EnableKube {
+		server.restoreRollbacks(clients)
+	}
+	return server, nil
+}
+
+// restoreRollbacks picks up the rollbacks a previous run left pending, e.g. because it
+// crashed while a node was cordoned. With OpenShift auth, changes are made with the
+// player's token, so they are undone with the server's own credentials instead.
+func (s *Server) restoreRollbacks(clients k8s.Clients) {
+	if clients.Kube == nil {
+		var err error
+		if clients, err = k8s.NewClientsForConfig(s.kubeConfig); err != nil {
+			slog.Error("Failed to get kube client for restoring rollbacks, they stay pending until the next start", "error", err)
+			return
+		}
+	}
+	restored, err := s.rollbacks.Restore(context.Background(), clients, s.fleet)
+	if err != nil {
+		slog.Error("Failed to restore rollbacks", "error", err)
+	} else if restored > 0 {
+		slog.Info("Restored pending rollbacks", "count", restored)
+	}
 }
 
-// Run starts the Fiber web server.
+// settings returns the runtime settings currently in effect.
+func (s *Server) settings() *config.Runtime {
+	return s.runtime.Load()
+}
+
+// applyConfig swaps in the runtime settings of a (re)loaded configuration.
+// Requests already in progress keep the settings they started with.
+func (s *Server) applyConfig(cfg *config.Config) {
+	runtime := cfg.Runtime
+	s.runtime.Store(&runtime)
+	scorer, err := scoring.New(runtime.HitPointRules, runtime.HitPointsBase, runtime.HitPointsMax)
+	if err != nil {
+		slog.Error("Failed to apply hit point rules", "error", err)
+	} else {
+		s.scorer.Store(scorer)
+	}
+	if err := logging.SetLevel(runtime.LogLevel); err != nil {
+		slog.Error("Failed to apply log level", "error", err)
+	}
+	if s.simulator != nil {
+		if err := s.simulator.AddNamespaces(runtime.NamespaceNames...); err != nil {
+			slog.Error("Failed to simulate namespaces", "error", err)
+		}
+	}
+}
+
+// Close runs the pending rollbacks until ctx is done, stops all monitors and closes the
+// database. It is safe to call more than once.
+func (s *Server) Close(ctx context.Context) error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.rollbacks.Close(ctx)
+		s.fakePods.Close()
+		if s.simulator != nil {
+			s.simulator.Close()
+		}
+		if err = s.monitorManager.Close(); err != nil {
+			return
+		}
+		if s.db != nil {
+			err = s.db.Close()
+		}
+	})
+	return err
+}
+
+// Run starts the Fiber web server and shuts it down gracefully on SIGINT or SIGTERM.
 func Run(cfg *config.Config) error {
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint:    cfg.OTLPEndpoint,
+		Insecure:    cfg.OTLPInsecure,
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Flush spans still buffered for export
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+	if cfg.OTLPEndpoint != "" {
+		slog.Info("Exporting traces", "endpoint", cfg.OTLPEndpoint, "sample_ratio", cfg.TraceSampleRatio)
+	}
+
 	server, err := NewServer(cfg)
 	if err != nil {
 		return err
 	}
+	defer server.Close(context.Background())
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.ConfigFile != "" {
+		if err := config.Watch(ctx, cfg, server.applyConfig); err != nil {
+			return err
+		}
+		slog.Info("Watching config file for changes", "file", cfg.ConfigFile)
+	}
+
+	app := server.newApp()
+
+	ln, err := net.Listen("tcp", cfg.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddress, err)
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.TLSCertFile != "" {
+		certs, err := newCertReloader(cfg)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		go certs.watch(ctx, certReloadInterval)
+		tlsConfig = certs.tlsConfig()
+		slog.Info("Starting server", "address", cfg.ListenAddress, "tls", true)
+	} else {
+		slog.Info("Starting server", "address", cfg.ListenAddress, "tls", false)
+	}
+	return server.serve(ctx, app, ln, tlsConfig)
+}
+
+// newApp creates the Fiber app with all middleware and routes.
+func (s *Server) newApp() *fiber.App {
 	viewsFS, _ := fs.Sub(assets.EmbeddedFiles, "views")
 	engine := html.NewFileSystem(http.FS(viewsFS), ".html")
 
 	app := fiber.New(fiber.Config{
-		Views: engine,
+		Views:        engine,
+		ErrorHandler: errorHandler,
+		BodyLimit:    s.config.MaxRequestBodyBytes,
 	})
 
+	app.Use(tracingMiddleware())
+	app.Use(requestIDMiddleware())
 	app.Use(requestLogger())
-	if cfg.EnableOpenShiftAuth {
-		app.Use(server.OpenShiftAuthMiddleware())
+	app.Use(sessionMiddleware())
+	app.Use(s.csrf.middleware())
+	if s.config.EnableOpenShiftAuth {
+		app.Use(s.OpenShiftAuthMiddleware())
+	}
+	if s.rateLimiter != nil {
+		app.Use(s.rateLimiter.middleware())
 	}
 
-	app.Get("/healthz", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
-	app.Get("/readyz", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
-
-	server.registerGameHandlers(app)
-	server.registerMonitorHandlers(app)
+	s.registerGameHandlers(app)
+	s.registerMonitorHandlers(app)
+	s.registerMetricsHandlers(app)
 	registerStaticFileHandlers(app)
+	return app
+}
 
-	log.Println("Starting server on http://localhost:3000")
-	return app.Listen(":3000")
+// limitBody enforces the body limit of the Fiber app for requests served through net/http,
+// where the adaptor reads bodies without a limit.
+func limitBody(next http.Handler, limit int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			w.Header().Set("Content-Type", fiber.MIMEApplicationJSON)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: APIError{
+				Code:    CodeRequestTooLarge,
+				Message: http.StatusText(http.StatusRequestEntityTooLarge),
+			}})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
 }
 
-// requestLogger is a middleware for logging HTTP requests.
+// serve runs app on ln until ctx is done, then fails readiness for the drain period so
+// load balancers stop routing to this instance, waits for in-flight requests and closes
+// all monitors and stores. With a TLS configuration the app is served through net/http,
+// which adds HTTP/2 support that fasthttp lacks.
+func (s *Server) serve(ctx context.Context, app *fiber.App, ln net.Listener, tlsConfig *tls.Config) error {
+	shutdown := app.ShutdownWithContext
+	listen := func() error { return app.Listener(ln) }
+	if tlsConfig != nil {
+		srv := &http.Server{
+			Handler:           limitBody(adaptor.FiberApp(app), int64(s.config.MaxRequestBodyBytes)),
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		shutdown = srv.Shutdown
+		listen = func() error {
+			if err := srv.ServeTLS(ln, "", ""); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		}
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- listen()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, draining connections", "drain", s.config.ShutdownDrain)
+	s.shuttingDown.Store(true)
+	time.Sleep(s.config.ShutdownDrain)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx); err != nil {
+		slog.Warn("Failed to finish in-flight requests", "error", err)
+	}
+	if err := <-errCh; err != nil {
+		slog.Error("Server stopped with error", "error", err)
+	}
+
+	if err := s.Close(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to close stores: %w", err)
+	}
+	slog.Info("Shutdown complete")
+	return nil
+}
+
+// registerMetricsHandlers registers the Prometheus metrics endpoint.
+func (s *Server) registerMetricsHandlers(app *fiber.App) {
+	promHandler := adaptor.HTTPHandler(metrics.Handler())
+	app.Get("/metrics", func(c *fiber.Ctx) error {
+		metrics.ActiveGames.Set(float64(s.games.Active()))
+		return promHandler(c)
+	})
+}
+
+// requestLogger is a middleware for logging HTTP requests. Static assets are only
+// logged at debug level to keep them out of the default logs.
 func requestLogger() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		log.Printf("%s %s", c.Method(), c.OriginalURL())
-		return c.Next()
+		start := time.Now()
+		err := c.Next()
+
+		level := slog.LevelInfo
+		if isStaticAsset(c.Path()) {
+			level = slog.LevelDebug
+		}
+		status := c.Response().StatusCode()
+		if fe, ok := err.(*fiber.Error); ok {
+			status = fe.Code
+		}
+		logger(c).Log(c.UserContext(), level, "Request",
+			"method", c.Method(),
+			"path", c.Path(),
+			"status", status,
+			"duration", time.Since(start),
+			"ip", c.IP(),
+		)
+		return err
 	}
 }
 
+// isStaticAsset reports whether path is served by registerStaticFileHandlers.
+func isStaticAsset(path string) bool {
+	return strings.HasPrefix(path, "/assets/") || strings.HasPrefix(path, "/js/") || strings.HasSuffix(path, ".js")
+}
+
 // registerStaticFileHandlers registers handlers for serving static files.
 func registerStaticFileHandlers(app *fiber.App) {
 	app.Get("/assets/*", func(c *fiber.Ctx) error {
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cldmnky/pod-invaders/internal/config"
+)
+
+// certReloadInterval is how often mounted certificate files are checked for rotation.
+const certReloadInterval = 10 * time.Second
+
+// certReloader serves the certificate and client CA bundle from disk, reloading
+// them when the files change, e.g. when a mounted Secret is rotated.
+type certReloader struct {
+	certFile, keyFile, caFile string
+	clientAuth                tls.ClientAuthType
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  [3]time.Time // Modification times of the cert, key and CA files when last loaded
+}
+
+// newCertReloader loads the configured certificate, key and optional client CA bundle.
+func newCertReloader(cfg *config.Config) (*certReloader, error) {
+	r := &certReloader{
+		certFile:   cfg.TLSCertFile,
+		keyFile:    cfg.TLSKeyFile,
+		caFile:     cfg.TLSClientCA,
+		clientAuth: tls.RequireAndVerifyClientCert,
+	}
+	if cfg.TLSClientAuth == config.TLSClientAuthOptional {
+		r.clientAuth = tls.VerifyClientCertIfGiven
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload reads the files from disk and swaps them in if they are valid.
+func (r *certReloader) reload() error {
+	modTimes := r.fileModTimes()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("client CA bundle %s contains no certificates", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCAs = pool
+	r.modTimes = modTimes
+	return nil
+}
+
+// fileModTimes returns the modification times of the certificate files, following symlinks.
+func (r *certReloader) fileModTimes() [3]time.Time {
+	var modTimes [3]time.Time
+	for i, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			modTimes[i] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// changed reports whether any file was modified since it was last loaded.
+func (r *certReloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fileModTimes() != r.modTimes
+}
+
+// watch reloads the files whenever they change until ctx is done. Failed reloads
+// are logged and the previous certificate stays in use.
+func (r *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				slog.Error("Keeping previous TLS certificate", "error", err)
+				continue
+			}
+			slog.Info("Reloaded TLS certificate", "file", r.certFile)
+		}
+	}
+}
+
+// tlsConfig returns a server TLS configuration that picks up reloaded files on each handshake.
+func (r *certReloader) tlsConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	base.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.cert, nil
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cfg := base.Clone()
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		if r.clientCAs != nil {
+			cfg.ClientCAs = r.clientCAs
+			cfg.ClientAuth = r.clientAuth
+		}
+		return cfg, nil
+	}
+	return base
+}
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cldmnky/pod-invaders/internal/config"
+)
+
+// testCA issues certificates for TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "pod-invaders test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for commonName, usable by servers on 127.0.0.1 and by clients.
+func (ca *testCA) issue(t *testing.T, commonName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes content to path, failing the test on error.
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// startTLSServer serves a test server over TLS on a random port and returns its base URL.
+func startTLSServer(t *testing.T, cfg *config.Config) (string, *Server) {
+	t.Helper()
+	server := createTestServer(false)
+	server.config.ShutdownTimeout = time.Second
+	csrf, err := newCSRFProtection(cfg)
+	if err != nil {
+		t.Fatalf("newCSRFProtection failed: %v", err)
+	}
+	server.csrf = csrf
+
+	certs, err := newCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go certs.watch(ctx, 20*time.Millisecond)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.serve(ctx, createTestApp(server, ""), ln, certs.tlsConfig()) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return "https://" + ln.Addr().String(), server
+}
+
+// tlsClient returns an HTTP/2-capable client trusting ca, presenting clientCert if given.
+func tlsClient(ca *testCA, clientCert ...tls.Certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: clientCert},
+			ForceAttemptHTTP2: true,
+			DisableKeepAlives: true,
+		},
+	}
+}
+
+func TestTLSServingWithHTTP2AndReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	cfg := &config.Config{
+		TLSCertFile: filepath.Join(dir, "tls.crt"),
+		TLSKeyFile:  filepath.Join(dir, "tls.key"),
+	}
+	certPEM, keyPEM := ca.issue(t, "first")
+	writeFile(t, cfg.TLSCertFile, certPEM)
+	writeFile(t, cfg.TLSKeyFile, keyPEM)
+
+	url, _ := startTLSServer(t, cfg)
+	client := tlsClient(ca)
+
+	// servedCommonName returns the common name of the certificate presented by the server
+	servedCommonName := func() string {
+		resp, err := client.Get(url + "/healthz")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if resp.ProtoMajor != 2 {
+			t.Errorf("Expected HTTP/2, got %s", resp.Proto)
+		}
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	if got := servedCommonName(); got != "first" {
+		t.Fatalf("Expected certificate %q, got %q", "first", got)
+	}
+
+	// Rotate the certificate as a Secret update would; make sure the modification time moves
+	certPEM, keyPEM = ca.issue(t, "second")
+	writeFile(t, cfg.TLSKeyFile, keyPEM)
+	writeFile(t, cfg.TLSCertFile, certPEM)
+	later := time.Now().Add(time.Second)
+	os.Chtimes(cfg.TLSCertFile, later, later)
+	os.Chtimes(cfg.TLSKeyFile, later, later)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for servedCommonName() != "second" {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the rotated certificate")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestTLSClientCertificates(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	cfg := &config.Config{
+		TLSCertFile:   filepath.Join(dir, "tls.crt"),
+		TLSKeyFile:    filepath.Join(dir, "tls.key"),
+		TLSClientCA:   filepath.Join(dir, "ca.crt"),
+		TLSClientAuth: config.TLSClientAuthRequire,
+	}
+	certPEM, keyPEM := ca.issue(t, "server")
+	writeFile(t, cfg.TLSCertFile, certPEM)
+	writeFile(t, cfg.TLSKeyFile, keyPEM)
+	writeFile(t, cfg.TLSClientCA, ca.pem)
+
+	url, _ := startTLSServer(t, cfg)
+
+	if resp, err := tlsClient(ca).Get(url + "/healthz"); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected a client without a certificate to be rejected")
+	}
+
+	clientPEM, clientKeyPEM := ca.issue(t, "player")
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load client certificate: %v", err)
+	}
+	resp, err := tlsClient(ca, clientCert).Get(url + "/healthz")
+	if err != nil {
+		t.Fatalf("Request with client certificate failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestTLSSameOriginRequest(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	cfg := &config.Config{
+		TLSCertFile: filepath.Join(dir, "tls.crt"),
+		TLSKeyFile:  filepath.Join(dir, "tls.key"),
+	}
+	certPEM, keyPEM := ca.issue(t, "server")
+	writeFile(t, cfg.TLSCertFile, certPEM)
+	writeFile(t, cfg.TLSKeyFile, keyPEM)
+
+	url, server := startTLSServer(t, cfg)
+	client := tlsClient(ca)
+	session := uuid.NewString()
+	post := func(origin string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", url+"/api/v1/heartbeat", nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session})
+		req.Header.Set("Origin", origin)
+		req.Header.Set(csrfHeader, server.csrf.token(session))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// The browser sends the https origin of the page it got from the server
+	if code := post(url); code != http.StatusOK {
+		t.Errorf("Expected a same-origin request to be allowed, got %d", code)
+	}
+	if code := post(strings.Replace(url, "https://", "http://", 1)); code != http.StatusForbidden {
+		t.Errorf("Expected the plain HTTP origin to be denied, got %d", code)
+	}
+}
+
+func TestCertReloaderKeepsCertificateOnInvalidFiles(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	cfg := &config.Config{
+		TLSCertFile: filepath.Join(dir, "tls.crt"),
+		TLSKeyFile:  filepath.Join(dir, "tls.key"),
+	}
+	certPEM, keyPEM := ca.issue(t, "valid")
+	writeFile(t, cfg.TLSCertFile, certPEM)
+	writeFile(t, cfg.TLSKeyFile, keyPEM)
+
+	certs, err := newCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+
+	writeFile(t, cfg.TLSCertFile, []byte("not a certificate"))
+	if err := certs.reload(); err == nil {
+		t.Fatal("Expected reload of an invalid certificate to fail")
+	}
+
+	cert, err := certs.tlsConfig().GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate failed: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	if leaf.Subject.CommonName != "valid" {
+		t.Errorf("Expected the previous certificate to stay in use, got %q", leaf.Subject.CommonName)
+	}
+}
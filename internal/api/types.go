@@ -0,0 +1,168 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+)
+
+// apiV1Prefix is the path prefix of the versioned API.
+const apiV1Prefix = "/api/v1"
+
+// Error codes returned in ErrorResponse. Clients should branch on the code, not the message.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeRequestTooLarge    = "request_too_large"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeCSRFFailed         = "csrf_failed"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeNamespaceProtected = "namespace_protected"
+	CodeEgressDenied       = "egress_denied"
+	CodeLimitReached       = "limit_reached"
+	CodeRateLimited        = "rate_limited"
+	CodeKubernetesError    = "kubernetes_error"
+	CodeUnavailable        = "unavailable"
+	CodeInternal           = "internal"
+)
+
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// APIError describes what went wrong with a request.
+type APIError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"` // Invalid fields, with CodeValidationFailed
+}
+
+// StatusResponse reports the outcome of an action.
+type StatusResponse struct {
+	Status  string `json:"status"` // "success", or "skipped" for a pod that was already killed
+	Message string `json:"message"`
+}
+
+// SettingsResponse holds the runtime settings the browser applies when a new game starts.
+type SettingsResponse struct {
+	Difficulty  string   `json:"difficulty"`
+	Targeting   string   `json:"targeting"`
+	KillActions []string `json:"killActions"` // Actions real invaders may be assigned
+}
+
+// KillRequest reports a killed pod, optionally naming the action to perform on it.
+type KillRequest struct {
+	game.Pod
+	Action string `json:"action,omitempty"` // One of the configured kill actions; the first one if empty
+}
+
+// KillResponse reports the outcome of a kill.
+type KillResponse struct {
+	StatusResponse
+	Action     string    `json:"action,omitempty"`    // Action performed on the pod
+	RollbackAt time.Time `json:"rollbackAt,omitzero"` // When a temporary action is rolled back
+}
+
+// BossDefeatRequest reports that the boss standing for a workload was defeated.
+type BossDefeatRequest struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	IsReal    bool   `json:"isRealWorkload,omitempty"`
+}
+
+// NodeDrainRequest reports that the invader standing for a node was killed.
+type NodeDrainRequest struct {
+	Name   string `json:"name"`
+	IsReal bool   `json:"isRealNode,omitempty"`
+}
+
+// NodeDrainResponse reports the outcome of a node kill.
+type NodeDrainResponse struct {
+	StatusResponse
+	Drain      *game.NodeDrain `json:"drain,omitempty"`     // Set when the node was drained
+	UncordonAt time.Time       `json:"uncordonAt,omitzero"` // When the node becomes schedulable again
+}
+
+// ResourceKillRequest reports that the invader standing for an object of a targeted
+// resource was killed.
+type ResourceKillRequest struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	IsReal    bool   `json:"isRealResource,omitempty"`
+}
+
+// MonitorRequest starts a URL monitor.
+type MonitorRequest struct {
+	URL string `json:"url"`
+}
+
+// MonitorStopRequest stops a URL monitor through the deprecated POST /monitor/stop.
+type MonitorStopRequest struct {
+	ID string `json:"id"`
+}
+
+// MonitorStartedResponse is returned when a monitor was started.
+type MonitorStartedResponse struct {
+	StatusResponse
+	ID string `json:"id"`
+}
+
+// HeartbeatResponse reports how many of the session's monitors were kept alive.
+type HeartbeatResponse struct {
+	Status   string `json:"status"`
+	Monitors int    `json:"monitors"`
+}
+
+// sendError writes an error envelope with the given status and code.
+func sendError(c *fiber.Ctx, status int, code, message string) error {
+	return c.Status(status).JSON(ErrorResponse{Error: APIError{Code: code, Message: message}})
+}
+
+// errorHandler renders errors returned by handlers and by Fiber itself, such as
+// unknown routes, as error envelopes.
+func errorHandler(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		status = fe.Code
+	}
+
+	code := CodeInternal
+	switch {
+	case status == fiber.StatusNotFound:
+		code = CodeNotFound
+	case status == fiber.StatusMethodNotAllowed:
+		code = CodeMethodNotAllowed
+	case status == fiber.StatusRequestEntityTooLarge:
+		code = CodeRequestTooLarge
+	case status < fiber.StatusInternalServerError:
+		code = CodeInvalidRequest
+	}
+
+	message := http.StatusText(status)
+	if fe != nil {
+		message = fe.Message
+	}
+	return sendError(c, status, code, message)
+}
+
+// deprecated marks a route as a deprecated alias of successor in the versioned API.
+func deprecated(successor string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "true")
+		c.Set(fiber.HeaderLink, "<"+successor+`>; rel="successor-version"`)
+		return c.Next()
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+)
+
+// Limits on request fields.
+const (
+	maxNamespaces      = 50
+	maxPlayerNameRunes = 32 // Matches the maxlength of the name input in the game
+	maxScore           = 1_000_000_000
+	maxLevelsFinished  = 10_000
+	maxGameDuration    = 24 * time.Hour
+	maxMonitorURLBytes = 2048
+)
+
+// FieldError describes an invalid field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// fieldErrors collects the problems found while validating a request.
+type fieldErrors []FieldError
+
+// add records a problem with field.
+func (e *fieldErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// required records a problem if value is empty and reports whether it was set.
+func (e *fieldErrors) required(field, value string) bool {
+	if value == "" {
+		e.add(field, "is required")
+		return false
+	}
+	return true
+}
+
+// dnsLabel records a problem if value is not a valid Kubernetes namespace name.
+func (e *fieldErrors) dnsLabel(field, value string) {
+	if e.required(field, value) {
+		for _, msg := range validation.IsDNS1123Label(value) {
+			e.add(field, "%s", msg)
+		}
+	}
+}
+
+// dnsSubdomain records a problem if value is not a valid Kubernetes object name, such as a pod name.
+func (e *fieldErrors) dnsSubdomain(field, value string) {
+	if e.required(field, value) {
+		for _, msg := range validation.IsDNS1123Subdomain(value) {
+			e.add(field, "%s", msg)
+		}
+	}
+}
+
+// between records a problem if value is outside [min, max].
+func (e *fieldErrors) between(field string, value, min, max int64) {
+	if value < min || value > max {
+		e.add(field, "must be between %d and %d", min, max)
+	}
+}
+
+// validatePod checks a pod reported as killed.
+func validatePod(p game.Pod) fieldErrors {
+	var errs fieldErrors
+	errs.dnsSubdomain("name", p.Name)
+	errs.dnsLabel("namespace", p.Namespace)
+	return errs
+}
+
+// validateBossDefeat checks a workload reported as defeated.
+func validateBossDefeat(r BossDefeatRequest) fieldErrors {
+	var errs fieldErrors
+	switch r.Kind {
+	case game.KindDeployment, game.KindStatefulSet, game.KindDaemonSet, game.KindReplicaSet, game.KindPod:
+	case "":
+		errs.add("kind", "is required")
+	default:
+		errs.add("kind", "must be %s, %s, %s, %s or %s", game.KindDeployment, game.KindStatefulSet, game.KindDaemonSet, game.KindReplicaSet, game.KindPod)
+	}
+	errs.dnsSubdomain("name", r.Name)
+	errs.dnsLabel("namespace", r.Namespace)
+	return errs
+}
+
+// validateNodeDrain checks a node reported as killed.
+func validateNodeDrain(r NodeDrainRequest) fieldErrors {
+	var errs fieldErrors
+	errs.dnsSubdomain("name", r.Name)
+	return errs
+}
+
+// validateResourceKill checks an object reported as killed in resource targeting.
+func validateResourceKill(r ResourceKillRequest) fieldErrors {
+	var errs fieldErrors
+	if r.Group != "" {
+		errs.dnsSubdomain("group", r.Group)
+	}
+	errs.required("version", r.Version)
+	errs.required("resource", r.Resource)
+	errs.dnsLabel("namespace", r.Namespace)
+	errs.dnsSubdomain("name", r.Name)
+	return errs
+}
+
+// validateHighscore checks a submitted highscore.
+func validateHighscore(hs game.Highscore) fieldErrors {
+	var errs fieldErrors
+	switch {
+	case !utf8.ValidString(hs.Name):
+		errs.add("name", "must be valid UTF-8")
+	case utf8.RuneCountInString(hs.Name) > maxPlayerNameRunes:
+		errs.add("name", "must be at most %d characters", maxPlayerNameRunes)
+	case strings.IndexFunc(hs.Name, unicode.IsControl) >= 0:
+		errs.add("name", "must not contain control characters")
+	}
+	errs.between("score", int64(hs.Score), 0, maxScore)
+	errs.between("levelsFinished", int64(hs.LevelsFinished), 0, maxLevelsFinished)
+	errs.between("timeTaken", hs.TimeTaken, 0, maxGameDuration.Milliseconds())
+	if hs.GameStarted < 0 {
+		errs.add("gameStarted", "must not be negative")
+	}
+	return errs
+}
+
+// validateNamespaces checks a list of namespaces to take pods from.
+func validateNamespaces(ns game.Namespaces) fieldErrors {
+	var errs fieldErrors
+	switch {
+	case len(ns.Namespaces) == 0:
+		errs.add("namespaces", "must not be empty")
+	case len(ns.Namespaces) > maxNamespaces:
+		errs.add("namespaces", "must have at most %d entries", maxNamespaces)
+		return errs
+	}
+	seen := make(map[string]bool, len(ns.Namespaces))
+	for i, name := range ns.Namespaces {
+		field := fmt.Sprintf("namespaces[%d]", i)
+		errs.dnsLabel(field, name)
+		if seen[name] {
+			errs.add(field, "duplicate namespace %q", name)
+		}
+		seen[name] = true
+	}
+	return errs
+}
+
+// validateMonitorRequest checks the URL of a monitor to start.
+func validateMonitorRequest(m MonitorRequest) fieldErrors {
+	var errs fieldErrors
+	if !errs.required("url", m.URL) {
+		return errs
+	}
+	if len(m.URL) > maxMonitorURLBytes {
+		errs.add("url", "must be at most %d bytes", maxMonitorURLBytes)
+		return errs
+	}
+	parsed, err := url.Parse(m.URL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		errs.add("url", "must be an absolute http or https URL")
+	}
+	return errs
+}
+
+// sendValidationError responds with the field errors found in a request body.
+func sendValidationError(c *fiber.Ctx, errs fieldErrors) error {
+	return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: APIError{
+		Code:    CodeValidationFailed,
+		Message: "request validation failed",
+		Fields:  errs,
+	}})
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+)
+
+// fields returns the names of the invalid fields.
+func fields(errs fieldErrors) []string {
+	names := make([]string, 0, len(errs))
+	for _, e := range errs {
+		names = append(names, e.Field)
+	}
+	return names
+}
+
+func TestValidatePod(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  game.Pod
+		want []string
+	}{
+		{name: "valid", pod: game.Pod{Name: "web-7d9f8-abcde", Namespace: "default"}, want: []string{}},
+		{name: "dotted name", pod: game.Pod{Name: "etcd-node.example", Namespace: "kube-system"}, want: []string{}},
+		{name: "missing fields", pod: game.Pod{}, want: []string{"name", "namespace"}},
+		{name: "upper case namespace", pod: game.Pod{Name: "web", Namespace: "Default"}, want: []string{"namespace"}},
+		{name: "long name", pod: game.Pod{Name: strings.Repeat("a", 254), Namespace: "default"}, want: []string{"name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fields(validatePod(tt.pod)); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected invalid fields %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateHighscore(t *testing.T) {
+	valid := game.Highscore{Name: "Ada", Score: 1500, LevelsFinished: 3, GameStarted: 1640995200000, TimeTaken: 60000}
+	tests := []struct {
+		name   string
+		modify func(*game.Highscore)
+		want   []string
+	}{
+		{name: "valid", modify: func(*game.Highscore) {}, want: []string{}},
+		{name: "anonymous", modify: func(hs *game.Highscore) { hs.Name = "" }, want: []string{}},
+		{name: "long name", modify: func(hs *game.Highscore) { hs.Name = strings.Repeat("x", 33) }, want: []string{"name"}},
+		{name: "control characters", modify: func(hs *game.Highscore) { hs.Name = "Ada\n" }, want: []string{"name"}},
+		{name: "negative score", modify: func(hs *game.Highscore) { hs.Score = -1 }, want: []string{"score"}},
+		{name: "huge score", modify: func(hs *game.Highscore) { hs.Score = maxScore + 1 }, want: []string{"score"}},
+		{name: "negative levels", modify: func(hs *game.Highscore) { hs.LevelsFinished = -1 }, want: []string{"levelsFinished"}},
+		{name: "negative durations", modify: func(hs *game.Highscore) { hs.TimeTaken, hs.GameStarted = -1, -1 }, want: []string{"timeTaken", "gameStarted"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := valid
+			tt.modify(&hs)
+			if got := fields(validateHighscore(hs)); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected invalid fields %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateNamespaces(t *testing.T) {
+	tooMany := make([]string, maxNamespaces+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("ns-%d", i)
+	}
+	tests := []struct {
+		name       string
+		namespaces []string
+		want       []string
+	}{
+		{name: "valid", namespaces: []string{"default", "team-a"}, want: []string{}},
+		{name: "empty", namespaces: nil, want: []string{"namespaces"}},
+		{name: "too many", namespaces: tooMany, want: []string{"namespaces"}},
+		{name: "invalid names", namespaces: []string{"default", "Not_Valid", ""}, want: []string{"namespaces[1]", "namespaces[2]"}},
+		{name: "duplicate", namespaces: []string{"default", "default"}, want: []string{"namespaces[1]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fields(validateNamespaces(game.Namespaces{Namespaces: tt.namespaces})); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected invalid fields %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidationErrorResponse(t *testing.T) {
+	server := createTestServer(false)
+	app := createTestApp(server, "")
+
+	body, _ := json.Marshal(game.Highscore{Name: "Ada", Score: -5, LevelsFinished: -1})
+	req := httptest.NewRequest("POST", "/api/v1/highscores", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest || envelope.Error.Code != CodeValidationFailed {
+		t.Fatalf("Expected 400 %s, got %d %+v", CodeValidationFailed, resp.StatusCode, envelope)
+	}
+	if got := fmt.Sprint(fields(envelope.Error.Fields)); got != "[score levelsFinished]" {
+		t.Errorf("Expected field errors for score and levelsFinished, got %v", envelope.Error.Fields)
+	}
+	if len(server.highscoreCache.Get()) != 0 {
+		t.Error("Expected the invalid highscore to be rejected")
+	}
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	server := createTestServer(false)
+	server.config.MaxRequestBodyBytes = 1024
+	big := `{"namespaces": ["` + strings.Repeat("a", 2048) + `"]}`
+
+	t.Run("fiber", func(t *testing.T) {
+		app := server.newApp()
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		go app.Listener(ln)
+		defer app.Shutdown()
+
+		resp, err := http.Post("http://"+ln.Addr().String()+"/api/v1/highscores", "application/json", strings.NewReader(big))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+		assertTooLarge(t, resp)
+	})
+
+	t.Run("net/http", func(t *testing.T) {
+		handler := limitBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Expected the oversized request to be rejected before the handler")
+		}), int64(server.config.MaxRequestBodyBytes))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/highscores", strings.NewReader(big)))
+		assertTooLarge(t, rec.Result())
+	})
+}
+
+// assertTooLarge checks that resp rejects the request body as too large.
+func assertTooLarge(t *testing.T, resp *http.Response) {
+	t.Helper()
+	var envelope ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.StatusCode != http.StatusRequestEntityTooLarge || envelope.Error.Code != CodeRequestTooLarge {
+		t.Errorf("Expected 413 %s, got %d %+v", CodeRequestTooLarge, resp.StatusCode, envelope)
+	}
+}
@@ -1,23 +1,39 @@
 // API Communication Functions
 import { updateDebugPanelMonitorStatus } from './ui.js';
 
-export async function reportKill(podName, namespace, isRealPod) {
+// State-changing requests must carry the CSRF token the server embeds in the page
+const csrfToken = document.querySelector('meta[name="csrf-token"]')?.content || '';
+const jsonHeaders = { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken };
+
+export async function reportKill(podName, namespace, isRealPod, action = '', cluster = '') {
     try {
-        await fetch('/kill', {
+        await fetch('/api/v1/kills', {
             method: 'POST', 
-            headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ name: podName, namespace: namespace, isRealPod: true })
+            headers: jsonHeaders,
+            body: JSON.stringify({ name: podName, namespace: namespace, isRealPod: true, action: action || undefined, cluster: cluster || undefined })
         });
     } catch (error) { 
         console.error("Failed to report kill:", error); 
     }
 }
 
+export async function reportNodeDrain(nodeName, isRealNode) {
+    try {
+        await fetch('/api/v1/nodes/drains', {
+            method: 'POST',
+            headers: jsonHeaders,
+            body: JSON.stringify({ name: nodeName, isRealNode })
+        });
+    } catch (error) {
+        console.error("Failed to report node kill:", error);
+    }
+}
+
 export async function sendHighscore(playerName, gameStartedTimestamp, timeTaken, levelsFinished, score) {
     try {
-        await fetch('/highscore', {
+        await fetch('/api/v1/highscores', {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: jsonHeaders,
             body: JSON.stringify({
                 name: playerName,
                 gameStarted: gameStartedTimestamp,
@@ -31,11 +47,98 @@ export async function sendHighscore(playerName, gameStartedTimestamp, timeTaken,
     }
 }
 
-export async function sendNamespaces(namespaces) {
+export async function fetchSettings() {
+    try {
+        const res = await fetch('/api/v1/settings');
+        if (res.ok) {
+            return await res.json();
+        }
+    } catch (e) {
+        console.error('Failed to fetch settings:', e);
+    }
+    return {};
+}
+
+export async function fetchPodDetails(namespace, name, cluster = '') {
+    try {
+        const query = cluster ? `?cluster=${encodeURIComponent(cluster)}` : '';
+        const res = await fetch(`/api/v1/pods/${encodeURIComponent(namespace)}/${encodeURIComponent(name)}${query}`);
+        if (res.ok) return await res.json();
+    } catch (e) {
+        console.error('Failed to fetch pod details:', e);
+    }
+    return null;
+}
+
+export async function fetchWorkloads(rows, cols) {
+    const res = await fetch(`/api/v1/workloads?rows=${rows}&cols=${cols}`);
+    if (!res.ok) throw new Error(`API Error: ${res.statusText}`);
+    return res.json();
+}
+
+export async function fetchNodes(count) {
+    const res = await fetch(`/api/v1/nodes?count=${count}`);
+    if (!res.ok) throw new Error(`API Error: ${res.statusText}`);
+    return res.json();
+}
+
+export async function fetchResources(count) {
+    const res = await fetch(`/api/v1/resources?count=${count}`);
+    if (!res.ok) throw new Error(`API Error: ${res.statusText}`);
+    return res.json();
+}
+
+export async function reportResourceKill(resource) {
+    try {
+        await fetch('/api/v1/resources/kills', {
+            method: 'POST',
+            headers: jsonHeaders,
+            body: JSON.stringify({
+                group: resource.group,
+                version: resource.version,
+                resource: resource.resource,
+                namespace: resource.namespace,
+                name: resource.name,
+                isRealResource: resource.isRealResource
+            })
+        });
+    } catch (error) {
+        console.error("Failed to report resource kill:", error);
+    }
+}
+
+export async function fetchBoss() {
     try {
-        await fetch('/namespaces', {
+        const res = await fetch('/api/v1/boss');
+        if (res.ok) return await res.json();
+    } catch (e) {
+        console.error('Failed to fetch boss workload:', e);
+    }
+    return null;
+}
+
+export async function reportBossDefeat(workload) {
+    try {
+        await fetch('/api/v1/boss/defeats', {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: jsonHeaders,
+            body: JSON.stringify({
+                kind: workload.kind,
+                namespace: workload.namespace,
+                name: workload.name,
+                isRealWorkload: workload.isRealWorkload || false
+            })
+        });
+    } catch (e) {
+        console.error('Failed to report boss defeat:', e);
+    }
+}
+
+export async function sendNamespaces(namespaces) {
+    try {
+        await fetch('/api/v1/namespaces', {
+            method: 'PUT',
+            headers: jsonHeaders,
             body: JSON.stringify({ namespaces })
         });
     } catch (e) {
@@ -45,9 +148,9 @@ export async function sendNamespaces(namespaces) {
 
 export async function startMonitor(monitorUrl) {
     try {
-        const res = await fetch('/monitor', {
+        const res = await fetch('/api/v1/monitors', {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: jsonHeaders,
             body: JSON.stringify({ url: monitorUrl })
         });
         if (res.ok) {
@@ -64,31 +167,51 @@ export async function startMonitor(monitorUrl) {
 export async function stopMonitor(monitorId) {
     if (monitorId) {
         try {
-            await fetch('/monitor/stop', {
-                method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ id: monitorId })
-            });
+            await fetch(`/api/v1/monitors/${encodeURIComponent(monitorId)}`, { method: 'DELETE', headers: { 'X-CSRF-Token': csrfToken } });
         } catch (e) {
             console.error('Failed to stop monitor:', e);
         }
     }
 }
 
+// Heartbeat marks the game as active and keeps this session's monitors alive on the server
+let heartbeatInterval = null;
+
+export async function sendHeartbeat() {
+    try {
+        await fetch('/api/v1/heartbeat', { method: 'POST', headers: { 'X-CSRF-Token': csrfToken } });
+    } catch (e) {
+        console.error('Failed to send heartbeat:', e);
+    }
+}
+
+export function startHeartbeat() {
+    stopHeartbeat();
+    sendHeartbeat();
+    heartbeatInterval = setInterval(sendHeartbeat, 5000);
+}
+
+export function stopHeartbeat() {
+    if (heartbeatInterval) {
+        clearInterval(heartbeatInterval);
+        heartbeatInterval = null;
+    }
+}
+
 // Monitor Status Polling
 let monitorStatusInterval = null;
 
 export function startMonitorStatusPolling(monitorId) {
     if (monitorStatusInterval) clearInterval(monitorStatusInterval);
     monitorStatusInterval = setInterval(() => {
-        fetch(`/monitor/status?id=${monitorId}`)
+        fetch(`/api/v1/monitors/${encodeURIComponent(monitorId)}`)
             .then(res => res.json())
             .then(data => {
                 let statusText = '';
                 if (data.error) {
-                    statusText = `Monitor Error: ${data.error}`;
+                    statusText = `Monitor Error: ${data.error.message}`;
                 } else {
-                    statusText = `Monitor Status: ${data.status || data.Status} | URL: ${data.url || data.URL}`;
+                    statusText = `Monitor Status: ${data.status} | URL: ${data.url}`;
                 }
                 updateDebugPanelMonitorStatus(statusText);
             })
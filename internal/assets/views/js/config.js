@@ -42,7 +42,7 @@ export let bossProjectileFrequency = 120;
  */
 export const INVADER_SPEED_INCREMENT = 0.4;
 /**
- * INVADER_REAL_POD_HITS: Number of hits required to kill a real pod invader. Example: 3 (gentle), 5 (normal), 7 (aggressive).
+ * INVADER_REAL_POD_HITS: Number of hits required to kill a real pod invader the server sent no hit points for. Example: 3 (gentle), 5 (normal), 7 (aggressive).
  */
 export const INVADER_REAL_POD_HITS = 5;
 /**
@@ -80,6 +80,24 @@ export const INIT_INVADER_SPEED = invaderSpeed;
 export const INIT_INVADER_PROJECTILE_FREQUENCY = invaderProjectileFrequency;
 export const INIT_BOSS_PROJECTILE_FREQUENCY = bossProjectileFrequency;
 
+// --- Difficulty presets, selected by the server's "difficulty" setting at the start of each game ---
+export const difficultyPresets = {
+    easy: {
+        invaderProjectileSpeed: 2, bossProjectileSpeed: 3, bossMaxHits: 5, bossVerticalAmplitude: 10,
+        bossVerticalFrequency: 0.005, invaderSpeed: 0.5, invaderProjectileFrequency: 400, bossProjectileFrequency: 200
+    },
+    normal: {
+        invaderProjectileSpeed: INIT_INVADER_PROJECTILE_SPEED, bossProjectileSpeed: INIT_BOSS_PROJECTILE_SPEED,
+        bossMaxHits: INIT_BOSS_MAX_HITS, bossVerticalAmplitude: INIT_BOSS_VERTICAL_AMPLITUDE,
+        bossVerticalFrequency: INIT_BOSS_VERTICAL_FREQUENCY, invaderSpeed: INIT_INVADER_SPEED,
+        invaderProjectileFrequency: INIT_INVADER_PROJECTILE_FREQUENCY, bossProjectileFrequency: INIT_BOSS_PROJECTILE_FREQUENCY
+    },
+    hard: {
+        invaderProjectileSpeed: 5, bossProjectileSpeed: 6, bossMaxHits: 12, bossVerticalAmplitude: 40,
+        bossVerticalFrequency: 0.03, invaderSpeed: 2, invaderProjectileFrequency: 100, bossProjectileFrequency: 60
+    }
+};
+
 // Canvas constants
 export const CANVAS_WIDTH = 600;
 export const CANVAS_HEIGHT = 600;
@@ -17,13 +17,15 @@ export function setBossImage(image) {
 }
 
 export class Boss {
-    constructor() {
+    // workload is the workload the boss stands for with workload targeting, or null
+    constructor(workload = null) {
         this.width = 150;
         this.height = 150;
         this.position = { x: CANVAS_CENTER_X - 75, y: 50 }; // Optimized calculation
         this.baseY = this.position.y; // Store initial Y position
         this.velocity = { x: 2, y: 0 };
-        this.maxHealth = bossMaxHits;
+        this.workload = workload;
+        this.maxHealth = workload?.hitPoints || bossMaxHits;
         this.health = this.maxHealth;
         this.image = bossImage;
         this.nextFireFrame = 0;
@@ -39,6 +41,13 @@ export class Boss {
         ctx.fillRect(this.position.x, this.position.y - 20, this.width, 10);
         ctx.fillStyle = '#23d160';
         ctx.fillRect(this.position.x, this.position.y - 20, healthBarWidth, 10);
+        if (this.workload) {
+            ctx.fillStyle = this.workload.isRealWorkload ? '#326ce5' : '#ff9800';
+            ctx.font = '12px "Courier New", Courier, monospace';
+            ctx.textAlign = 'center';
+            ctx.fillText(`${this.workload.kind} ${this.workload.namespace}/${this.workload.name} (${this.workload.replicas})`,
+                this.position.x + this.width / 2, this.position.y - 26);
+        }
     }
 
     update() {
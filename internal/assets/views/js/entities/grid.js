@@ -1,6 +1,7 @@
 // Grid Class
 import { levelConfigs, podNames, invaderSpeed, CANVAS_WIDTH } from '../config.js';
 import { Invader } from './invader.js';
+import { fetchWorkloads, fetchNodes, fetchResources } from '../api.js';
 
 export class Grid {
     constructor() {
@@ -13,7 +14,10 @@ export class Grid {
         this.rightBoundary = CANVAS_WIDTH;
     }
     
-    async init(level) {
+    // targeting is the server's targeting mode: 'random', 'workload' for one workload per row,
+    // 'node' for invaders standing for nodes, or 'resource' for objects of targeted resources. killActions are the kill actions the server
+    // allows; each real pod invader is given one at random.
+    async init(level, targeting = 'random', killActions = []) {
         const config = levelConfigs[level - 1];
         if (!config) return;
 
@@ -21,7 +25,20 @@ export class Grid {
         this.width = cols * 45;
         
         try {
-            const response = await fetch(`/names?count=${rows * cols}`);
+            if (targeting === 'workload') {
+                this.initWorkloadRows(await fetchWorkloads(rows, cols), rows, cols);
+                this.assignActions(killActions);
+                return;
+            }
+            if (targeting === 'node') {
+                this.initNodes(await fetchNodes(rows * cols), rows, cols);
+                return;
+            }
+            if (targeting === 'resource') {
+                this.initResources(await fetchResources(rows * cols), rows, cols);
+                return;
+            }
+            const response = await fetch(`/api/v1/pods?count=${rows * cols}`);
             if (!response.ok) throw new Error(`API Error: ${response.statusText}`);
             const names = await response.json();
             let nameIndex = 0;
@@ -36,7 +53,8 @@ export class Grid {
                         position: { x: x * 45, y: y * 45 + 50 },
                         name: names[nameIndex]?.name || podNames[Math.floor(Math.random() * podNames.length)],
                         namespace: names[nameIndex]?.namespace || podNames[Math.floor(Math.random() * podNames.length)],
-                        isRealPod: names[nameIndex]?.isRealPod || false
+                        isRealPod: names[nameIndex]?.isRealPod || false,
+                        pod: names[nameIndex]
                     });
                     nameIndex++;
                 }
@@ -66,6 +84,67 @@ export class Grid {
             this.invaders[i] = this.invaders[j];
             this.invaders[j] = temp;
         }
+        this.assignActions(killActions);
+    }
+    
+    // Real pod invaders carry the action their kill triggers, e.g. 'network-partition'
+    assignActions(killActions) {
+        if (!killActions.length) return;
+        for (const invader of this.invaders) {
+            if (invader?.isRealPod && invader.kind === 'pod') {
+                invader.action = killActions[Math.floor(Math.random() * killActions.length)];
+            }
+        }
+    }
+    
+    // Each row holds the pods of one workload, e.g. a Deployment's replicas
+    initWorkloadRows(workloads, rows, cols) {
+        this.invaders = [];
+        for (let y = 0; y < rows; y++) {
+            const pods = workloads[y]?.pods || [];
+            for (let x = 0; x < Math.min(cols, pods.length); x++) {
+                const pod = pods[x];
+                this.invaders.push(new Invader({
+                    position: { x: x * 45, y: y * 45 + 50 },
+                    name: pod.name,
+                    namespace: pod.namespace,
+                    isRealPod: pod.isRealPod || false,
+                    pod
+                }));
+            }
+        }
+    }
+    
+    // Each invader is a node; killing a real one drains it
+    initNodes(nodes, rows, cols) {
+        this.invaders = [];
+        for (let i = 0; i < Math.min(nodes.length, rows * cols); i++) {
+            const node = nodes[i];
+            this.invaders.push(new Invader({
+                position: { x: Math.floor(i / rows) * 45, y: (i % rows) * 45 + 50 },
+                name: node.name,
+                namespace: 'node',
+                isRealPod: node.isRealNode || false,
+                pod: node,
+                kind: 'node'
+            }));
+        }
+    }
+    
+    // Each invader is an object of a targeted resource, e.g. an Argo Rollout
+    initResources(resources, rows, cols) {
+        this.invaders = [];
+        for (let i = 0; i < Math.min(resources.length, rows * cols); i++) {
+            const resource = resources[i];
+            this.invaders.push(new Invader({
+                position: { x: Math.floor(i / rows) * 45, y: (i % rows) * 45 + 50 },
+                name: resource.name,
+                namespace: resource.namespace,
+                isRealPod: resource.isRealResource || false,
+                pod: resource,
+                kind: 'resource'
+            }));
+        }
     }
     
     update() {
@@ -4,21 +4,33 @@ import { invaderProjectileSpeed, INVADER_REAL_POD_HITS } from '../config.js';
 import { InvaderProjectile } from './projectiles.js';
 
 export class Invader {
-    constructor({ position, name, namespace, isRealPod }) {
+    constructor({ position, name, namespace, isRealPod, pod, kind = 'pod' }) {
         this.width = 35; 
         this.height = 35;
         this.position = { x: position.x, y: position.y };
         this.name = name; 
         this.namespace = namespace;
         this.isRealPod = isRealPod; 
+        this.pod = pod || null; // Metadata from the server, shown when hovering
+        this.kind = kind; // 'pod', 'node' in node targeting or 'resource' in resource targeting
+        this.action = ''; // Kill action for real pods, assigned by the grid; empty means the server default
         this.isKilled = false; // Track if this pod has been killed
         this.hits = 0; // Track number of hits for real pods
+        this.maxHits = pod?.hitPoints || INVADER_REAL_POD_HITS; // Hits a real pod takes, scored by the server from its importance
         // Cache commonly used values
         this.halfWidth = this.width * 0.5;
         this.halfHeight = this.height * 0.5;
     }
     
     draw() {
+        if (this.kind === 'node') {
+            this.drawNode();
+            return;
+        }
+        if (this.kind === 'resource') {
+            this.drawResource();
+            return;
+        }
         const x = this.position.x, y = this.position.y, w = this.width, h = this.height;
         ctx.save();
         ctx.fillStyle = this.isRealPod ? '#326ce5' : '#ff9800';
@@ -51,6 +63,52 @@ export class Invader {
         ctx.restore();
     }
     
+    // Resources are drawn as a cube, as their objects can be anything
+    drawResource() {
+        const x = this.position.x, y = this.position.y, w = this.width, h = this.height;
+        ctx.save();
+        ctx.fillStyle = this.isRealPod ? '#326ce5' : '#ff9800';
+        ctx.beginPath();
+        ctx.moveTo(x + this.halfWidth, y + h * 0.05);
+        ctx.lineTo(x + w * 0.9, y + h * 0.28);
+        ctx.lineTo(x + w * 0.9, y + h * 0.72);
+        ctx.lineTo(x + this.halfWidth, y + h * 0.95);
+        ctx.lineTo(x + w * 0.1, y + h * 0.72);
+        ctx.lineTo(x + w * 0.1, y + h * 0.28);
+        ctx.closePath();
+        ctx.fill();
+        ctx.strokeStyle = 'white';
+        ctx.lineWidth = 1.5;
+        ctx.beginPath();
+        ctx.moveTo(x + w * 0.1, y + h * 0.28);
+        ctx.lineTo(x + this.halfWidth, y + h * 0.5);
+        ctx.lineTo(x + w * 0.9, y + h * 0.28);
+        ctx.moveTo(x + this.halfWidth, y + h * 0.5);
+        ctx.lineTo(x + this.halfWidth, y + h * 0.95);
+        ctx.stroke();
+        ctx.restore();
+    }
+    
+    // Nodes are drawn as a server with three drive bays
+    drawNode() {
+        const x = this.position.x, y = this.position.y, w = this.width, h = this.height;
+        ctx.save();
+        ctx.fillStyle = this.isRealPod ? '#326ce5' : '#ff9800';
+        ctx.fillRect(x + w * 0.1, y + h * 0.1, w * 0.8, h * 0.8);
+        ctx.strokeStyle = 'white';
+        ctx.lineWidth = 1.5;
+        ctx.beginPath();
+        for (let i = 1; i <= 3; i++) {
+            const bayY = y + h * (0.1 + 0.2 * i);
+            ctx.moveTo(x + w * 0.2, bayY);
+            ctx.lineTo(x + w * 0.65, bayY);
+            ctx.moveTo(x + w * 0.75, bayY);
+            ctx.lineTo(x + w * 0.8, bayY);
+        }
+        ctx.stroke();
+        ctx.restore();
+    }
+    
     update({ velocity }) { 
         this.draw(); 
         this.position.x += velocity.x; 
@@ -3,14 +3,7 @@ import {
     CANVAS_WIDTH, 
     CANVAS_HEIGHT, 
     levelConfigs,
-    INIT_INVADER_PROJECTILE_SPEED,
-    INIT_BOSS_PROJECTILE_SPEED,
-    INIT_BOSS_MAX_HITS,
-    INIT_BOSS_VERTICAL_AMPLITUDE,
-    INIT_BOSS_VERTICAL_FREQUENCY,
-    INIT_INVADER_SPEED,
-    INIT_INVADER_PROJECTILE_FREQUENCY,
-    INIT_BOSS_PROJECTILE_FREQUENCY,
+    difficultyPresets,
     setInvaderProjectileSpeed,
     setBossProjectileSpeed,
     setBossMaxHits,
@@ -33,8 +26,7 @@ import {
     BOSS_VERTICAL_FREQUENCY_INCREMENT,
     BOSS_PROJECTILE_FREQUENCY_INCREMENT,
     INVADER_PROJECTILE_FREQUENCY_INCREMENT,
-    INVADER_SPEED_INCREMENT,
-    INVADER_REAL_POD_HITS
+    INVADER_SPEED_INCREMENT
 } from './config.js';
 import { ctx, gameOverScreen, countdownOverlay, endGameTitle, finalScoreEl } from './dom.js';
 import { switchMusic, backgroundMusic, playExplosionSound, playhitBossSound, playExplosionBossSound, playCountDownSound } from './audio.js';
@@ -58,7 +50,7 @@ import {
     updateDebugPanel,
     getMonitorIsUp
 } from './ui.js';
-import { sendHighscore, reportKill, stopMonitor, stopMonitorStatusPolling } from './api.js';
+import { sendHighscore, reportKill, reportNodeDrain, reportResourceKill, stopMonitor, stopMonitorStatusPolling, startHeartbeat, stopHeartbeat, fetchSettings, fetchBoss, reportBossDefeat } from './api.js';
 
 // --- Game State ---
 export let player = null, projectiles = [], grids = [], invaderProjectiles = [], particles = [], flashingTexts = [], boss = null;
@@ -70,6 +62,8 @@ export let keys = {
 export let animationId;
 export let gameStartedTimestamp;
 let currentMonitorId = null;
+let targeting = 'random'; // Server targeting mode, read when a game starts
+let killActions = []; // Kill actions the server allows, read when a game starts
 
 // Global frames counter for game entities
 window.frames = 0;
@@ -78,15 +72,19 @@ export async function init() {
     // Set entity classes for object pools
     setEntityClasses(Particle, Projectile);
     
-    // Reset difficulty variables to initial values
-    setInvaderProjectileSpeed(INIT_INVADER_PROJECTILE_SPEED);
-    setBossProjectileSpeed(INIT_BOSS_PROJECTILE_SPEED);
-    setBossMaxHits(INIT_BOSS_MAX_HITS);
-    setBossVerticalAmplitude(INIT_BOSS_VERTICAL_AMPLITUDE);
-    setBossVerticalFrequency(INIT_BOSS_VERTICAL_FREQUENCY);
-    setInvaderSpeed(INIT_INVADER_SPEED);
-    setInvaderProjectileFrequency(INIT_INVADER_PROJECTILE_FREQUENCY);
-    setBossProjectileFrequency(INIT_BOSS_PROJECTILE_FREQUENCY);
+    // Reset difficulty variables to the preset currently configured on the server
+    const { difficulty, targeting: serverTargeting, killActions: serverKillActions } = await fetchSettings();
+    targeting = serverTargeting || 'random';
+    killActions = serverKillActions || [];
+    const preset = difficultyPresets[difficulty] || difficultyPresets.normal;
+    setInvaderProjectileSpeed(preset.invaderProjectileSpeed);
+    setBossProjectileSpeed(preset.bossProjectileSpeed);
+    setBossMaxHits(preset.bossMaxHits);
+    setBossVerticalAmplitude(preset.bossVerticalAmplitude);
+    setBossVerticalFrequency(preset.bossVerticalFrequency);
+    setInvaderSpeed(preset.invaderSpeed);
+    setInvaderProjectileFrequency(preset.invaderProjectileFrequency);
+    setBossProjectileFrequency(preset.bossProjectileFrequency);
     
     player = new Player();
     projectiles = []; 
@@ -124,6 +122,7 @@ function endGame() {
     backgroundMusic.pause();
     stopMonitor(currentMonitorId);
     stopMonitorStatusPolling();
+    stopHeartbeat();
     currentMonitorId = null;
     
     // --- Highscore submission ---
@@ -154,6 +153,7 @@ function winGame() {
     backgroundMusic.pause();
     stopMonitor(currentMonitorId);
     stopMonitorStatusPolling();
+    stopHeartbeat();
     currentMonitorId = null;
     
     // --- Highscore submission ---
@@ -205,20 +205,26 @@ export async function advanceLevel() {
         setBossVerticalAmplitude(bossVerticalAmplitude + BOSS_VERTICAL_AMPLITUDE_INCREMENT);
         setBossVerticalFrequency(bossVerticalFrequency + BOSS_VERTICAL_FREQUENCY_INCREMENT);
         setBossProjectileFrequency(Math.max(40, bossProjectileFrequency - BOSS_PROJECTILE_FREQUENCY_INCREMENT)); // Increase boss firing rate
-        boss = new Boss();
+        boss = await createBoss();
     } else {
         // Increase invader speed
         setInvaderSpeed(invaderSpeed + INVADER_SPEED_INCREMENT);
         // increase invader projectile frequency
         setInvaderProjectileFrequency(Math.max(60, invaderProjectileFrequency - INVADER_PROJECTILE_FREQUENCY_INCREMENT));
         const newGrid = new Grid();
-        await newGrid.init(level);
+        await newGrid.init(level, targeting, killActions);
         grids.push(newGrid);
     }
     game.active = true;
     switchMusic(isBossLevel, game);
 }
 
+// With workload targeting the boss stands for a workload and takes its hit points from the server
+async function createBoss() {
+    const workload = targeting === 'workload' ? await fetchBoss() : null;
+    return new Boss(workload);
+}
+
 export function animate() {
     if (!game.active) return;
     animationId = requestAnimationFrame(animate);
@@ -319,6 +325,7 @@ export function animate() {
                     createParticles({ object: boss, color: '#D92A2A', amount: 100, particles });
                     playExplosionBossSound();
                     playExplosionSound();
+                    if (boss.workload) reportBossDefeat(boss.workload);
                     boss = null;
                     advanceLevel();
                     break;
@@ -360,8 +367,8 @@ export function animate() {
                     if (!invader.isKilled) {
                         if (invader.isRealPod) {
                             invader.hits++;
-                            if (invader.hits >= INVADER_REAL_POD_HITS) {
-                                score = updateScore(score + 200);
+                            if (invader.hits >= invader.maxHits) {
+                                score = updateScore(score + 40 * invader.maxHits);
                                 // Add random offset to prevent text overlap
                                 const randomOffsetX = (Math.random() - 0.5) * 30; // -15 to +15 pixels
                                 const randomOffsetY = (Math.random() - 0.5) * 20; // -10 to +10 pixels
@@ -374,7 +381,13 @@ export function animate() {
                                 }));
                                 createParticles({ object: invader, color: '#326ce5', amount: 15, particles });
                                 playExplosionSound();
-                                reportKill(invader.name, invader.namespace, invader.isRealPod);
+                                if (invader.kind === 'node') {
+                                    reportNodeDrain(invader.name, invader.isRealPod);
+                                } else if (invader.kind === 'resource') {
+                                    reportResourceKill(invader.pod);
+                                } else {
+                                    reportKill(invader.name, invader.namespace, invader.isRealPod, invader.action, invader.pod?.cluster);
+                                }
                                 addKilledPodToSidebar(invader.namespace, invader.name);
                                 invader.isKilled = true;
                                 hit = true;
@@ -383,7 +396,7 @@ export function animate() {
                                 const randomOffsetX = (Math.random() - 0.5) * 30; // -15 to +15 pixels
                                 const randomOffsetY = (Math.random() - 0.5) * 20; // -10 to +10 pixels
                                 flashingTexts.push(new FlashingText({ 
-                                    text: `Hit ${invader.hits}/${INVADER_REAL_POD_HITS}`, 
+                                    text: `Hit ${invader.hits}/${invader.maxHits}`, 
                                     position: { 
                                         x: invader.position.x + 17 + randomOffsetX, 
                                         y: invader.position.y + randomOffsetY 
@@ -460,6 +473,7 @@ export async function startGame(countdownText, monitorUrl = '') {
     
     await init();
     gameStartedTimestamp = Date.now();
+    startHeartbeat();
 
     // Always start monitoring with every new game
     if (monitorUrl) {
@@ -477,10 +491,10 @@ export async function startGame(countdownText, monitorUrl = '') {
 
     const isBossLevel = levelConfigs[level - 1] === null;
     if (isBossLevel) {
-        boss = new Boss();
+        boss = await createBoss();
     } else {
         const firstGrid = new Grid();
-        await firstGrid.init(level);
+        await firstGrid.init(level, targeting, killActions);
         grids.push(firstGrid);
     }
 
@@ -11,6 +11,7 @@ import {
     showDebugPanel 
 } from './ui.js';
 import { sendNamespaces } from './api.js';
+import { initPodTooltip } from './tooltip.js';
 
 // Initialize the game
 async function initialize() {
@@ -28,6 +29,9 @@ async function initialize() {
     // Setup UI event handlers
     setupUIEventHandlers();
     
+    // Show pod details when hovering invaders
+    initPodTooltip();
+    
     // Show highscore table on load
     showHighscoreTable();
     
@@ -0,0 +1,136 @@
+// Pod Tooltip
+// Hovering an invader shows the pod it stands for, so players know what they are about to destroy
+import { canvas } from './dom.js';
+import { getGameState } from './game.js';
+import { fetchPodDetails } from './api.js';
+
+let tooltip = null;
+let hovered = null;
+
+function escapeHTML(value) {
+    return String(value).replace(/[&<>"']/g, c => ({ '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' }[c]));
+}
+
+function formatAge(createdAt) {
+    if (!createdAt) return '';
+    const seconds = Math.max(0, (Date.now() - new Date(createdAt).getTime()) / 1000);
+    if (seconds < 120) return `${Math.floor(seconds)}s`;
+    if (seconds < 7200) return `${Math.floor(seconds / 60)}m`;
+    if (seconds < 172800) return `${Math.floor(seconds / 3600)}h`;
+    return `${Math.floor(seconds / 86400)}d`;
+}
+
+function podRows(invader) {
+    const pod = invader.pod || {};
+    return [
+        ['Cluster', pod.cluster],
+        ['Hit points', invader.isRealPod ? invader.maxHits : ''],
+        ['Kill action', invader.action],
+        ['Owner', pod.ownerKind ? `${pod.ownerKind}/${pod.ownerName}` : ''],
+        ['Replicas', pod.ownerReplicas ?? ''],
+        ['Priority', pod.priorityClass ? `${pod.priorityClass} (${pod.priority ?? 0})` : ''],
+        ['PDB', pod.coveredByPDB ? 'covered' : ''],
+        ['Node', pod.node],
+        ['Phase', pod.phase ? `${pod.phase}${pod.ready ? ', ready' : ', not ready'}` : ''],
+        ['Restarts', pod.restartCount ?? 0],
+        ['Images', (pod.images || []).join(', ')],
+        ['Age', formatAge(pod.createdAt)],
+        ['QoS', pod.qosClass],
+        ['Labels', Object.entries(pod.labels || {}).map(([k, v]) => `${k}=${v}`).join(', ')],
+    ];
+}
+
+function nodeRows(invader) {
+    const node = invader.pod || {};
+    return [
+        ['Hit points', invader.isRealPod ? invader.maxHits : ''],
+        ['Status', node.ready ? 'Ready' : 'NotReady'],
+        ['Roles', (node.roles || []).join(', ')],
+        ['Kubelet', node.kubeletVersion],
+    ];
+}
+
+function resourceRows(invader) {
+    const resource = invader.pod || {};
+    return [
+        ['Kind', resource.kind],
+        ['API version', resource.group ? `${resource.group}/${resource.version}` : resource.version],
+        ['Kill action', resource.action],
+        ['Replicas', resource.replicas ?? ''],
+    ];
+}
+
+const rowsByKind = { pod: podRows, node: nodeRows, resource: resourceRows };
+
+function renderPod(invader) {
+    const rows = rowsByKind[invader.kind](invader)
+        .filter(([, value]) => value !== '' && value !== undefined);
+    const noun = invader.kind === 'resource' ? (invader.pod?.kind || 'resource') : invader.kind;
+
+    let html = `<div style="font-weight:bold;color:${invader.isRealPod ? '#326ce5' : '#ff9800'};">${escapeHTML(invader.namespace)}/${escapeHTML(invader.name)}</div>`;
+    html += `<div style="color:#aaa;margin-bottom:4px;">${invader.isRealPod ? 'Real' : 'Fake'} ${escapeHTML(noun)}</div>`;
+    for (const [label, value] of rows) {
+        html += `<div><span style="color:#aaa;">${label}:</span> ${escapeHTML(value)}</div>`;
+    }
+    tooltip.innerHTML = html;
+}
+
+function invaderAt(x, y) {
+    for (const grid of getGameState().grids) {
+        for (const invader of grid.invaders) {
+            if (!invader || invader.isKilled) continue;
+            if (x >= invader.position.x && x <= invader.position.x + invader.width &&
+                y >= invader.position.y && y <= invader.position.y + invader.height) {
+                return invader;
+            }
+        }
+    }
+    return null;
+}
+
+function hide() {
+    hovered = null;
+    tooltip.style.display = 'none';
+}
+
+async function show(invader, clientX, clientY) {
+    tooltip.style.left = `${clientX + 14}px`;
+    tooltip.style.top = `${clientY + 14}px`;
+    if (invader === hovered) return;
+
+    hovered = invader;
+    renderPod(invader);
+    tooltip.style.display = 'block';
+
+    // Real pods are refreshed once, since they may have restarted or moved since the level started
+    if (invader.isRealPod && invader.kind === 'pod' && !invader.detailsFetched) {
+        invader.detailsFetched = true;
+        const details = await fetchPodDetails(invader.namespace, invader.name, invader.pod?.cluster);
+        if (details) {
+            invader.pod = details;
+            if (hovered === invader) renderPod(invader);
+        }
+    }
+}
+
+export function initPodTooltip() {
+    tooltip = document.createElement('div');
+    tooltip.id = 'podTooltip';
+    tooltip.style.cssText = 'position:fixed;display:none;z-index:1000;pointer-events:none;max-width:360px;' +
+        'background:#101010ee;color:#f5f5f5;border:1.5px solid #326ce5;border-radius:8px;padding:8px 10px;' +
+        "font-family:'Courier New',Courier,monospace;font-size:13px;word-break:break-word;";
+    document.body.appendChild(tooltip);
+
+    canvas.addEventListener('mousemove', (event) => {
+        const rect = canvas.getBoundingClientRect();
+        const x = (event.clientX - rect.left) * (canvas.width / rect.width);
+        const y = (event.clientY - rect.top) * (canvas.height / rect.height);
+        const invader = invaderAt(x, y);
+        if (invader) {
+            show(invader, event.clientX, event.clientY);
+        } else {
+            hide();
+        }
+    });
+    canvas.addEventListener('mouseleave', hide);
+}
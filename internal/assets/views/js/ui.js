@@ -61,7 +61,7 @@ export function renderHighscoreTable(highscores) {
 }
 
 export function showHighscoreTable() {
-    fetch('/highscores')
+    fetch('/api/v1/highscores')
         .then(res => res.json())
         .then(data => {
             const container = elements.highscoreTableContainer;
@@ -1,35 +1,658 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+	"github.com/cldmnky/pod-invaders/internal/k8s"
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/monitor"
+	"github.com/cldmnky/pod-invaders/internal/ratelimit"
+	"github.com/cldmnky/pod-invaders/internal/scoring"
+)
+
+// EnvPrefix is prepended to upper-cased flag names to form environment variables,
+// e.g. --monitor-max can be set with POD_INVADERS_MONITOR_MAX.
+const EnvPrefix = "POD_INVADERS_"
+
+// Difficulty presets
+const (
+	DifficultyEasy   = "easy"
+	DifficultyNormal = "normal"
+	DifficultyHard   = "hard"
+)
+
+// Targeting modes, which decide how pods are arranged into invaders
+const (
+	TargetingRandom   = "random"   // Invaders are a random shuffle of pods
+	TargetingWorkload = "workload" // Each grid row is one workload's pods and the boss is a whole workload
+	TargetingNode     = "node"     // Invaders are nodes, cordoned and drained when killed
+	TargetingResource = "resource" // Invaders are objects of the --resource-target resources
+)
+
+// Actions taken on the boss workload when it is defeated
+const (
+	BossActionNone    = "none"    // Defeating the boss changes nothing in the cluster
+	BossActionRestart = "restart" // Rolling restart of the boss workload
+)
+
+// Client certificate policies for mTLS
+const (
+	TLSClientAuthRequire  = "require"  // Reject connections without a valid client certificate
+	TLSClientAuthOptional = "optional" // Verify client certificates when presented
+)
+
+// Storage backends
+const (
+	StorageBadger = "badger" // Highscores and monitors persisted in BadgerDB
+	StorageMemory = "memory" // Everything is lost on restart
 )
 
 // Config holds the application configuration.
 type Config struct {
-	Kubeconfig          string
-	EnableKube          bool
-	NamespaceNames      []string
-	HighscoreDBPath     string // Path to the highscore database
-	EnableOpenShiftAuth bool   // Enable OpenShift OAuth authentication
+	ConfigFile    string // YAML file the configuration was loaded from, if any
+	ListenAddress string // Address the HTTP server listens on
+	TLSCertFile   string // Serve HTTPS with this certificate when set
+	TLSKeyFile    string // Private key for TLSCertFile
+	TLSClientCA   string // Require client certificates signed by this CA bundle (mTLS) when set
+	TLSClientAuth string // With TLSClientCA: TLSClientAuthRequire or TLSClientAuthOptional
+
+	LogFormat string // Log output format: text or json
+
+	OTLPEndpoint     string  // OTLP/HTTP collector traces are exported to (empty = tracing disabled)
+	OTLPInsecure     bool    // Export traces over plain HTTP
+	TraceSampleRatio float64 // Fraction of new traces to sample
+
+	MaxRequestBodyBytes int // Largest request body accepted
+
+	CSRFSecretFile string   // Key used to sign CSRF tokens; a random key is generated when empty
+	AllowedOrigins []string // Origins besides the server's own that may send state-changing requests
+
+	TrustedProxies    []string // Proxies whose X-Forwarded-For is trusted to identify the client
+	RateLimitPods     string   // Budget per client for pod listing, as "rate:burst" or "off"
+	RateLimitActions  string   // Budget per client for kills, highscores and namespace changes
+	RateLimitMonitors string   // Budget per client for starting, stopping and polling monitors
+	RateLimitAPI      string   // Budget per client for the other API endpoints
+
+	ShutdownDrain   time.Duration // How long to keep serving with failing readiness after SIGTERM
+	ShutdownTimeout time.Duration // How long in-flight requests may take to finish during shutdown
+
+	Kubeconfig string
+	EnableKube bool
+	Clusters   []string // Clusters of fleet mode, as name, name=context or name=file:kubeconfig
+
+	Simulate            bool          // Without EnableKube, play against a simulated cluster instead of unconnected fake pods
+	SimRecreateDelay    time.Duration // How long simulated controllers take to replace a killed pod
+	SimCrashInterval    time.Duration // Mean time between simulated container crashes (0 = none)
+	SimAPIErrorRate     float64       // Fraction of simulated pod deletions that fail
+	FakePodSeed         uint64        // Seed every game's fake pods start from (0 = random per game)
+	FakePodPools        string        // YAML file with the names, namespaces, nodes and images of fake pods
+	StorageBackend      string        // Either StorageBadger or StorageMemory
+	HighscoreDBPath     string        // Path to the highscore database
+	EnableOpenShiftAuth bool          // Enable OpenShift OAuth authentication
+
+	MonitorMaxCount      int           // Maximum number of concurrent monitors across all sessions
+	MonitorMaxPerSession int           // Maximum number of concurrent monitors per browser session
+	MonitorIdleTimeout   time.Duration // Stop monitors whose session has not sent a heartbeat for this long
+
+	MonitorAllowedHosts     []string // Hostname patterns monitors may probe (empty = any public host)
+	MonitorAllowedCIDRs     []string // Address ranges monitors may probe, also used to re-allow loopback, link-local and private ranges
+	MonitorMaxResponseBytes int64    // Maximum response body size read by a monitor probe
+
+	MonitorWebhooks       []string // Webhooks notified on monitor up/down transitions, as "format=url"
+	MonitorWebhookRetries int      // Retries for failed webhook deliveries
+	MonitorAlertDebounce  int      // Consecutive checks a new monitor state must hold before alerting
+
+	Runtime // Settings that can change while the server is running
+
+	args []string // Command line the configuration was loaded from, reused on reload
+}
+
+// Runtime holds the settings that are reloaded from the config file without a restart.
+type Runtime struct {
+	NamespaceNames          []string
+	KillDryRun              bool          // Log kills of real pods without deleting them
+	KillProtectedNamespaces []string      // Namespaces whose pods are never killed
+	KillActions             []string      // Actions performed on killed real pods; invaders get one of them at random
+	ActionTTL               time.Duration // How long the temporary changes of kill actions last before they are rolled back
+	Difficulty              string        // Difficulty preset for new games: easy, normal or hard
+	Targeting               string        // How pods become invaders: random, workload, node or resource
+	ResourceTargets         []string      // Resources targeted in resource targeting, as group/version/resource=action
+	BossDefeatAction        string        // What happens to the boss workload when it is defeated: none or restart
+	NodeUncordonAfter       time.Duration // How long a node killed in node targeting stays cordoned
+	HitPointRules           []string      // Scoring rules for the hit points of real pods, as name=weight
+	HitPointsBase           int           // Hit points of a real pod that no rule rates as important
+	HitPointsMax            int           // Upper bound on the hit points of a real pod
+	LogLevel                string        // Minimum log level: debug, info, warn or error
 }
 
-// New initializes a new Config object from command-line flags.
+// New initializes a new Config from the command line, exiting if the configuration is invalid.
 func New() *Config {
-	cfg := &Config{}
+	cfg, err := Load(os.Args[1:])
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	return cfg
+}
+
+// newFlagSet defines all configuration flags, bound to the fields of cfg.
+// Flag names double as config file keys and, with EnvPrefix, as environment variables.
+func newFlagSet(cfg *Config) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("pod-invaders", pflag.ContinueOnError)
+
+	fs.StringVar(&cfg.ConfigFile, "config", "", "Path to a YAML config file whose keys are flag names")
+
+	// Server
+	fs.StringVar(&cfg.ListenAddress, "listen-address", ":3000", "Address the HTTP server listens on")
+	fs.StringVar(&cfg.TLSCertFile, "tls-cert-file", "", "TLS certificate file; serves HTTPS when set together with --tls-key-file")
+	fs.StringVar(&cfg.TLSKeyFile, "tls-key-file", "", "TLS private key file")
+	fs.StringVar(&cfg.TLSClientCA, "tls-client-ca-file", "", "CA bundle used to verify client certificates (enables mTLS)")
+	fs.StringVar(&cfg.TLSClientAuth, "tls-client-auth", TLSClientAuthRequire, "Client certificate policy with --tls-client-ca-file: require or optional")
+	fs.StringVar(&cfg.LogLevel, "log-level", "info", "Minimum log level: debug, info, warn or error (debug also logs static asset requests)")
+	fs.StringVar(&cfg.LogFormat, "log-format", logging.FormatText, "Log output format: text or json")
+	fs.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", "", "OTLP/HTTP collector to export traces to, as host:port or URL (default: tracing disabled)")
+	fs.BoolVar(&cfg.OTLPInsecure, "otlp-insecure", false, "Export traces to a host:port --otlp-endpoint over plain HTTP")
+	fs.Float64Var(&cfg.TraceSampleRatio, "trace-sample-ratio", 1, "Fraction of new traces to sample, between 0 and 1")
+	fs.IntVar(&cfg.MaxRequestBodyBytes, "max-request-body-bytes", 64<<10, "Largest request body accepted, in bytes")
+	fs.StringVar(&cfg.CSRFSecretFile, "csrf-secret-file", "", "File with the key used to sign CSRF tokens; share it between replicas (default: random key per process)")
+	fs.StringSliceVar(&cfg.AllowedOrigins, "allowed-origins", nil, "Origins besides the server's own allowed to send state-changing requests, e.g. https://games.example.com")
+	fs.DurationVar(&cfg.ShutdownDrain, "shutdown-drain", 5*time.Second, "How long to keep serving with failing readiness after SIGTERM so load balancers stop sending traffic")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", 20*time.Second, "How long in-flight requests, and then pending rollbacks, may take to finish during shutdown")
+
+	// Kubernetes
+	fs.StringVar(&cfg.Kubeconfig, "kubeconfig", "", "(optional) absolute path to the kubeconfig file")
+	fs.BoolVar(&cfg.EnableKube, "enable-kube", true, "Enable Kubernetes client (default: true)")
+	fs.StringArrayVar(&cfg.Clusters, "cluster", nil, "Cluster invaders are taken from in fleet mode, as name for the context of that name in --kubeconfig, name=context, or name=file:path for the current context of another kubeconfig (repeatable)")
+	fs.StringArrayVar(&cfg.NamespaceNames, "namespaces", []string{"default"}, "List of namespaces to query pods from (default: default)")
+
+	// Standalone simulator
+	fs.BoolVar(&cfg.Simulate, "simulate", true, "With --enable-kube=false, play against an in-process simulated cluster whose controllers replace killed pods; false serves unconnected fake pods")
+	fs.DurationVar(&cfg.SimRecreateDelay, "sim-recreate-delay", 5*time.Second, "How long simulated controllers take to replace a killed pod, varied by up to 50%")
+	fs.DurationVar(&cfg.SimCrashInterval, "sim-crash-interval", 0, "Mean time between simulated container crashes, which leave a pod unready for a while (0 = none)")
+	fs.Float64Var(&cfg.SimAPIErrorRate, "sim-api-error-rate", 0, "Fraction of simulated pod deletions that fail with a server error, between 0 and 1")
+
+	// Fake pods
+	fs.Uint64Var(&cfg.FakePodSeed, "fake-pod-seed", 0, "Seed of the fake pods of every game, so that each game gets the same ones in the same order (0 = random per game)")
+	fs.StringVar(&cfg.FakePodPools, "fake-pod-pools", "", "YAML file with lists of apps, namespaces, nodes and images fake pods are made of; missing lists keep the built-in ones")
+
+	// Game
+	fs.StringVar(&cfg.Difficulty, "difficulty", DifficultyNormal, "Difficulty preset for new games: easy, normal or hard")
+	fs.StringVar(&cfg.Targeting, "targeting", TargetingRandom, "How pods become invaders: random, workload to give every grid row one workload's pods and make the boss a workload, node to make invaders nodes that are drained when killed, or resource to make invaders objects of the --resource-target resources")
+	fs.StringArrayVar(&cfg.ResourceTargets, "resource-target", nil, `Resource whose objects are invaders in resource targeting, as group/version/resource=action with action delete, scale or patch:{json merge patch}, e.g. argoproj.io/v1alpha1/rollouts=scale (repeatable)`)
+	fs.StringVar(&cfg.BossDefeatAction, "boss-defeat-action", BossActionNone, "Action on the boss workload when it is defeated in workload targeting: none or restart")
+	fs.DurationVar(&cfg.NodeUncordonAfter, "node-uncordon-after", 5*time.Minute, "How long a node killed in node targeting stays cordoned before it is uncordoned")
+	fs.StringSliceVar(&cfg.HitPointRules, "hit-point-rules", scoring.DefaultRules, "Rules rating how important real pods are, as name=weight, from "+strings.Join(scoring.Names(), ", "))
+	fs.IntVar(&cfg.HitPointsBase, "hit-points-base", 1, "Hit points of a real pod that no rule rates as important")
+	fs.IntVar(&cfg.HitPointsMax, "hit-points-max", 20, "Maximum hit points of a real pod")
+
+	// Storage
+	fs.StringVar(&cfg.StorageBackend, "storage-backend", StorageBadger, "Storage for highscores and monitors: badger or memory")
+	fs.StringVar(&cfg.HighscoreDBPath, "highscore-db", "/tmp/highscores.db", "Path to the highscore database file")
+
+	// Auth
+	fs.BoolVar(&cfg.EnableOpenShiftAuth, "enable-openshift-auth", false, "Enable OpenShift OAuth authentication (default: false)")
+
+	// Kill policy
+	fs.BoolVar(&cfg.KillDryRun, "kill-dry-run", false, "Log kills of real pods without deleting them")
+	fs.StringSliceVar(&cfg.KillActions, "kill-actions", []string{k8s.ActionDelete}, "Actions performed on killed real pods, each real invader getting one of them at random: "+strings.Join(k8s.ActionNames(), ", "))
+	fs.DurationVar(&cfg.ActionTTL, "action-ttl", 2*time.Minute, "How long network partitions and scale-to-zero last before they are rolled back")
+	fs.StringSliceVar(&cfg.KillProtectedNamespaces, "kill-protected-namespaces", defaultProtectedNamespaces(), "Namespaces whose pods are never killed")
+
+	// Monitor lifecycle limits
+	fs.IntVar(&cfg.MonitorMaxCount, "monitor-max", 100, "Maximum number of concurrent URL monitors (0 = unlimited)")
+	fs.IntVar(&cfg.MonitorMaxPerSession, "monitor-max-per-session", 10, "Maximum number of concurrent URL monitors per session (0 = unlimited)")
+	fs.DurationVar(&cfg.MonitorIdleTimeout, "monitor-idle-timeout", 2*time.Minute, "Stop URL monitors after this long without a game heartbeat (0 = never)")
+
+	// Monitor egress policy
+	fs.StringSliceVar(&cfg.MonitorAllowedHosts, "monitor-allowed-hosts", nil, "Hostname patterns URL monitors may probe, e.g. *.svc.cluster.local (default: any host outside denied ranges)")
+	fs.StringSliceVar(&cfg.MonitorAllowedCIDRs, "monitor-allowed-cidrs", nil, "CIDR ranges URL monitors may probe; loopback, link-local and private ranges are denied unless listed here")
+	fs.Int64Var(&cfg.MonitorMaxResponseBytes, "monitor-max-response-bytes", 1<<20, "Maximum response body size in bytes read by a URL monitor probe")
+
+	// Rate limiting
+	fs.StringSliceVar(&cfg.TrustedProxies, "trusted-proxies", nil, "CIDR ranges of reverse proxies whose X-Forwarded-For header identifies the client")
+	fs.StringVar(&cfg.RateLimitPods, "rate-limit-pods", "1:10", "Pod listing budget per client, as requests per second:burst, or off")
+	fs.StringVar(&cfg.RateLimitActions, "rate-limit-actions", "10:30", "Kill, highscore and namespace budget per client, as requests per second:burst, or off")
+	fs.StringVar(&cfg.RateLimitMonitors, "rate-limit-monitors", "1:10", "Monitor budget per client, as requests per second:burst, or off")
+	fs.StringVar(&cfg.RateLimitAPI, "rate-limit-api", "10:50", "Budget per client for other API endpoints, as requests per second:burst, or off")
 
-	// Define flags using pflag for compatibility with cobra (though cobra is removed)
-	pflag.StringVar(&cfg.Kubeconfig, "kubeconfig", "", "(optional) absolute path to the kubeconfig file")
-	pflag.BoolVar(&cfg.EnableKube, "enable-kube", true, "Enable Kubernetes client (default: true)")
-	pflag.StringArrayVar(&cfg.NamespaceNames, "namespaces", []string{"default"}, "List of namespaces to query pods from (default: default)")
-	pflag.StringVar(&cfg.HighscoreDBPath, "highscore-db", "/tmp/highscores.db", "Path to the highscore database file")
-	// EnableOpenShiftAuth
-	pflag.BoolVar(&cfg.EnableOpenShiftAuth, "enable-openshift-auth", false, "Enable OpenShift OAuth authentication (default: false)")
+	// Monitor alerting
+	fs.StringArrayVar(&cfg.MonitorWebhooks, "monitor-webhook", nil, "Webhook notified when a monitor goes up or down, as format=url with format json, slack or alertmanager (repeatable)")
+	fs.IntVar(&cfg.MonitorWebhookRetries, "monitor-webhook-retries", 3, "Number of retries for failed monitor webhook deliveries")
+	fs.IntVar(&cfg.MonitorAlertDebounce, "monitor-alert-debounce", 2, "Consecutive checks a new monitor state must hold before webhooks fire")
 
 	// Add Go's standard flags to pflag
-	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
-	pflag.Parse()
+	fs.AddGoFlagSet(flag.CommandLine)
+	return fs
+}
 
-	return cfg
+// Load builds a Config from, in increasing order of precedence, built-in defaults,
+// the YAML config file (--config or POD_INVADERS_CONFIG), POD_INVADERS_* environment
+// variables and command-line flags. The result is validated and all problems are
+// reported together.
+func Load(args []string) (*Config, error) {
+	cfg := &Config{args: args}
+	fs := newFlagSet(cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Flags given on the command line win over every other source
+	fromArgs := make(map[string]bool)
+	fs.Visit(func(f *pflag.Flag) { fromArgs[f.Name] = true })
+
+	if cfg.ConfigFile == "" {
+		cfg.ConfigFile = os.Getenv(envName("config"))
+	}
+
+	var errs []error
+	if cfg.ConfigFile != "" {
+		values, err := readFile(cfg.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, applyFile(fs, values, fromArgs)...)
+	}
+	errs = append(errs, applyEnv(fs, fromArgs)...)
+	errs = append(errs, cfg.Validate())
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// envName returns the environment variable for a flag.
+func envName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// readFile parses a YAML config file into a map keyed by flag name.
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]interface{})
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber() // keep large integers such as byte limits exact
+	if err := dec.Decode(&values); err != nil && !bytes.Equal(bytes.TrimSpace(jsonData), []byte("null")) {
+		return nil, fmt.Errorf("config file %s must be a mapping of flag names to values: %w", path, err)
+	}
+	return values, nil
+}
+
+// applyFile sets flags from config file values, skipping those given on the command line.
+func applyFile(fs *pflag.FlagSet, values map[string]interface{}, fromArgs map[string]bool) []error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		f := fs.Lookup(key)
+		if f == nil || key == "config" {
+			errs = append(errs, fmt.Errorf("config file: unknown key %q", key))
+			continue
+		}
+		if fromArgs[key] {
+			continue
+		}
+
+		var list []string
+		switch v := values[key].(type) {
+		case []interface{}:
+			for _, item := range v {
+				list = append(list, fmt.Sprint(item))
+			}
+		case map[string]interface{}:
+			errs = append(errs, fmt.Errorf("config file: %s must be a scalar or a list", key))
+			continue
+		case nil:
+			continue
+		default:
+			list = []string{fmt.Sprint(v)}
+		}
+		if err := setFlag(f, list); err != nil {
+			errs = append(errs, fmt.Errorf("config file: %s: %w", key, err))
+		}
+	}
+	return errs
+}
+
+// applyEnv sets flags from POD_INVADERS_* environment variables, skipping those given on
+// the command line. List flags take comma-separated values.
+func applyEnv(fs *pflag.FlagSet, fromArgs map[string]bool) []error {
+	var errs []error
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Name == "config" || fromArgs[f.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		list := []string{value}
+		if _, isList := f.Value.(pflag.SliceValue); isList {
+			list = strings.Split(value, ",")
+		}
+		if err := setFlag(f, list); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", envName(f.Name), err))
+		}
+	})
+	return errs
+}
+
+// setFlag replaces the value of a flag. List flags take every value, others exactly one.
+func setFlag(f *pflag.Flag, values []string) error {
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+		return slice.Replace(values)
+	}
+	if len(values) != 1 {
+		return fmt.Errorf("expected a single value, got %d", len(values))
+	}
+	return f.Value.Set(values[0])
+}
+
+// Validate checks the configuration and returns every problem found, joined into one error.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.ListenAddress); err != nil {
+		add("listen-address %q is invalid: %v", c.ListenAddress, err)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		add("tls-cert-file and tls-key-file must be set together")
+	}
+	if c.TLSClientCA != "" && c.TLSCertFile == "" {
+		add("tls-client-ca-file requires tls-cert-file and tls-key-file")
+	}
+	if c.TLSClientAuth != TLSClientAuthRequire && c.TLSClientAuth != TLSClientAuthOptional {
+		add("tls-client-auth %q is invalid: must be %s or %s", c.TLSClientAuth, TLSClientAuthRequire, TLSClientAuthOptional)
+	}
+	for _, file := range []string{c.TLSCertFile, c.TLSKeyFile, c.TLSClientCA} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			add("TLS file %s is not readable: %v", file, err)
+		}
+	}
+
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		add("log-level: %v", err)
+	}
+	if c.LogFormat != logging.FormatText && c.LogFormat != logging.FormatJSON {
+		add("log-format %q is invalid: must be %s or %s", c.LogFormat, logging.FormatText, logging.FormatJSON)
+	}
+	if c.MaxRequestBodyBytes <= 0 {
+		add("max-request-body-bytes must be positive")
+	}
+	if c.CSRFSecretFile != "" {
+		if _, err := os.Stat(c.CSRFSecretFile); err != nil {
+			add("csrf-secret-file %s is not readable: %v", c.CSRFSecretFile, err)
+		}
+	}
+	for _, origin := range c.AllowedOrigins {
+		if !validOrigin(origin) {
+			add("allowed-origins: %q is invalid: must be scheme://host[:port]", origin)
+		}
+	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		add("trace-sample-ratio must be between 0 and 1")
+	}
+	if c.ShutdownDrain < 0 {
+		add("shutdown-drain must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		add("shutdown-timeout must be positive")
+	}
+
+	if c.EnableKube && len(c.NamespaceNames) == 0 {
+		add("namespaces must not be empty when enable-kube is set")
+	}
+	for _, ns := range c.NamespaceNames {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			add("namespace %q is invalid: %s", ns, msg)
+		}
+	}
+	for _, ns := range c.KillProtectedNamespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			add("kill-protected-namespaces: %q is invalid: %s", ns, msg)
+		}
+	}
+	switch c.Difficulty {
+	case DifficultyEasy, DifficultyNormal, DifficultyHard:
+	default:
+		add("difficulty %q is invalid: must be %s, %s or %s", c.Difficulty, DifficultyEasy, DifficultyNormal, DifficultyHard)
+	}
+	switch c.Targeting {
+	case TargetingRandom, TargetingWorkload, TargetingNode:
+	case TargetingResource:
+		if len(c.ResourceTargets) == 0 {
+			add("targeting %s requires at least one resource-target", TargetingResource)
+		}
+	default:
+		add("targeting %q is invalid: must be %s, %s, %s or %s", c.Targeting, TargetingRandom, TargetingWorkload, TargetingNode, TargetingResource)
+	}
+	for _, spec := range c.ResourceTargets {
+		if _, err := k8s.ParseResourceTarget(spec); err != nil {
+			add("resource-target: %v", err)
+		}
+	}
+	if len(c.KillActions) == 0 {
+		add("kill-actions must not be empty")
+	}
+	for _, name := range c.KillActions {
+		if _, ok := k8s.LookupAction(name); !ok {
+			add("kill-actions: unknown action %q: must be one of %s", name, strings.Join(k8s.ActionNames(), ", "))
+		}
+	}
+	if c.ActionTTL <= 0 {
+		add("action-ttl must be positive")
+	}
+	if c.NodeUncordonAfter <= 0 {
+		add("node-uncordon-after must be positive")
+	}
+	switch c.BossDefeatAction {
+	case BossActionNone, BossActionRestart:
+	default:
+		add("boss-defeat-action %q is invalid: must be %s or %s", c.BossDefeatAction, BossActionNone, BossActionRestart)
+	}
+	if _, err := scoring.New(c.HitPointRules, c.HitPointsBase, c.HitPointsMax); err != nil {
+		add("hit-point-rules: %v", err)
+	}
+	if c.EnableOpenShiftAuth && !c.EnableKube {
+		add("enable-openshift-auth requires enable-kube")
+	}
+	if c.SimRecreateDelay <= 0 {
+		add("sim-recreate-delay must be positive")
+	}
+	if c.SimCrashInterval < 0 {
+		add("sim-crash-interval must not be negative")
+	}
+	if c.SimAPIErrorRate < 0 || c.SimAPIErrorRate > 1 {
+		add("sim-api-error-rate must be between 0 and 1")
+	}
+	if c.FakePodPools != "" {
+		if _, err := game.LoadFakePools(c.FakePodPools); err != nil {
+			add("fake-pod-pools: %v", err)
+		}
+	}
+	clusters := make(map[string]bool)
+	for _, spec := range c.Clusters {
+		cluster, err := k8s.ParseClusterSpec(spec)
+		switch {
+		case err != nil:
+			add("cluster: %v", err)
+		case clusters[cluster.Name]:
+			add("cluster %s is given more than once", cluster.Name)
+		}
+		clusters[cluster.Name] = true
+	}
+	if len(c.Clusters) > 0 {
+		if !c.EnableKube {
+			add("cluster requires enable-kube")
+		}
+		if c.EnableOpenShiftAuth {
+			add("cluster cannot be combined with enable-openshift-auth, whose player tokens are valid in one cluster only")
+		}
+		if c.Targeting != TargetingRandom {
+			add("cluster only supports targeting %s", TargetingRandom)
+		}
+	}
+
+	switch c.StorageBackend {
+	case StorageBadger:
+		if c.HighscoreDBPath == "" {
+			add("highscore-db must be set for the %s storage backend", StorageBadger)
+		}
+	case StorageMemory:
+	default:
+		add("storage-backend %q is invalid: must be %s or %s", c.StorageBackend, StorageBadger, StorageMemory)
+	}
+
+	if c.MonitorMaxCount < 0 {
+		add("monitor-max must not be negative")
+	}
+	if c.MonitorMaxPerSession < 0 {
+		add("monitor-max-per-session must not be negative")
+	}
+	if c.MonitorIdleTimeout < 0 {
+		add("monitor-idle-timeout must not be negative")
+	}
+	if c.MonitorMaxResponseBytes <= 0 {
+		add("monitor-max-response-bytes must be positive")
+	}
+	if c.MonitorWebhookRetries < 0 {
+		add("monitor-webhook-retries must not be negative")
+	}
+	if c.MonitorAlertDebounce < 1 {
+		add("monitor-alert-debounce must be at least 1")
+	}
+	for _, cidr := range c.MonitorAllowedCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			add("monitor-allowed-cidrs: %v", err)
+		}
+	}
+	for _, spec := range c.MonitorWebhooks {
+		if _, err := monitor.ParseWebhook(spec, c.MonitorWebhookRetries); err != nil {
+			add("monitor-webhook: %v", err)
+		}
+	}
+
+	for _, cidr := range c.TrustedProxies {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			add("trusted-proxies: invalid CIDR %q: %v", cidr, err)
+		}
+	}
+	for _, limit := range []struct{ flag, value string }{
+		{"rate-limit-pods", c.RateLimitPods},
+		{"rate-limit-actions", c.RateLimitActions},
+		{"rate-limit-monitors", c.RateLimitMonitors},
+		{"rate-limit-api", c.RateLimitAPI},
+	} {
+		if _, err := ratelimit.ParseLimit(limit.value); err != nil {
+			add("%s: %v", limit.flag, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Reload loads the configuration again from the same command line, config file and environment.
+func (c *Config) Reload() (*Config, error) {
+	return Load(c.args)
+}
+
+// ParsedClusters returns the clusters of fleet mode, which Validate has checked.
+func (c *Config) ParsedClusters() []k8s.ClusterSpec {
+	clusters := make([]k8s.ClusterSpec, 0, len(c.Clusters))
+	for _, spec := range c.Clusters {
+		if cluster, err := k8s.ParseClusterSpec(spec); err == nil {
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters
+}
+
+// ResourceTarget returns the resource target for group/version/resource, if there is one.
+func (r *Runtime) ResourceTarget(gvr string) (k8s.ResourceTarget, bool) {
+	for _, t := range r.ParsedResourceTargets() {
+		if t.String() == gvr {
+			return t, true
+		}
+	}
+	return k8s.ResourceTarget{}, false
+}
+
+// ParsedResourceTargets returns the resource targets, which Validate has checked.
+func (r *Runtime) ParsedResourceTargets() []k8s.ResourceTarget {
+	targets := make([]k8s.ResourceTarget, 0, len(r.ResourceTargets))
+	for _, spec := range r.ResourceTargets {
+		if t, err := k8s.ParseResourceTarget(spec); err == nil {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// defaultProtectedNamespaces returns kube-system and the namespace the server is installed
+// in, whose pods no game should take down.
+func defaultProtectedNamespaces() []string {
+	namespaces := []string{"kube-system"}
+	if ns := k8s.OwnNamespace(); ns != "" && ns != "kube-system" {
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces
+}
+
+// IsProtectedNamespace reports whether the kill policy forbids killing pods in ns.
+func (r *Runtime) IsProtectedNamespace(ns string) bool {
+	for _, protected := range r.KillProtectedNamespaces {
+		if protected == ns {
+			return true
+		}
+	}
+	return false
+}
+
+// validOrigin reports whether origin is a bare web origin such as https://example.com:8443.
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return false
+	}
+	return u.Scheme == "http" || u.Scheme == "https"
 }
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cldmnky/pod-invaders/internal/k8s"
+)
+
+// writeConfigFile writes a YAML config file into a temporary directory.
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.ListenAddress != ":3000" {
+		t.Errorf("Expected listen address :3000, got %s", cfg.ListenAddress)
+	}
+	if cfg.StorageBackend != StorageBadger {
+		t.Errorf("Expected storage backend %s, got %s", StorageBadger, cfg.StorageBackend)
+	}
+	if !reflect.DeepEqual(cfg.NamespaceNames, []string{"default"}) {
+		t.Errorf("Expected default namespaces, got %v", cfg.NamespaceNames)
+	}
+	// Without protected namespaces, a game could kill the cluster's DNS or the server itself
+	for _, ns := range []string{"kube-system", k8s.OwnNamespace()} {
+		if ns != "" && !slices.Contains(cfg.KillProtectedNamespaces, ns) {
+			t.Errorf("Expected %s to be protected by default, got %v", ns, cfg.KillProtectedNamespaces)
+		}
+	}
+	if !cfg.Simulate || cfg.SimRecreateDelay != 5*time.Second {
+		t.Errorf("Expected the simulator on with a 5s recreate delay, got %t and %s", cfg.Simulate, cfg.SimRecreateDelay)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+listen-address: ":8080"
+namespaces: [team-a, team-b]
+monitor-max: 5
+monitor-idle-timeout: 30s
+monitor-max-response-bytes: 2097152
+kill-dry-run: true
+`)
+	t.Setenv("POD_INVADERS_MONITOR_MAX", "7")
+	t.Setenv("POD_INVADERS_KILL_PROTECTED_NAMESPACES", "kube-system, openshift")
+
+	cfg, err := Load([]string{"--config", path, "--listen-address", ":9090"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// Flags beat the environment and the file
+	if cfg.ListenAddress != ":9090" {
+		t.Errorf("Expected listen address from flag, got %s", cfg.ListenAddress)
+	}
+	// The environment beats the file
+	if cfg.MonitorMaxCount != 7 {
+		t.Errorf("Expected monitor-max from environment, got %d", cfg.MonitorMaxCount)
+	}
+	if !reflect.DeepEqual(cfg.KillProtectedNamespaces, []string{"kube-system", "openshift"}) {
+		t.Errorf("Unexpected protected namespaces: %v", cfg.KillProtectedNamespaces)
+	}
+	// The file beats the defaults
+	if !reflect.DeepEqual(cfg.NamespaceNames, []string{"team-a", "team-b"}) {
+		t.Errorf("Unexpected namespaces: %v", cfg.NamespaceNames)
+	}
+	if cfg.MonitorIdleTimeout != 30*time.Second {
+		t.Errorf("Expected idle timeout 30s, got %s", cfg.MonitorIdleTimeout)
+	}
+	if cfg.MonitorMaxResponseBytes != 2097152 {
+		t.Errorf("Expected max response bytes 2097152, got %d", cfg.MonitorMaxResponseBytes)
+	}
+	if !cfg.KillDryRun {
+		t.Error("Expected kill-dry-run from file")
+	}
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	t.Setenv("POD_INVADERS_CONFIG", writeConfigFile(t, "storage-backend: memory\n"))
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.StorageBackend != StorageMemory {
+		t.Errorf("Expected storage backend %s, got %s", StorageMemory, cfg.StorageBackend)
+	}
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	path := writeConfigFile(t, `
+listen-address: "no-port"
+storage-backend: floppy
+monitor-max: -1
+monitor-allowed-cidrs: [not-a-cidr]
+namespaces: [Not_Valid]
+unknown-key: true
+`)
+	t.Setenv("POD_INVADERS_MONITOR_WEBHOOK_RETRIES", "lots")
+
+	_, err := Load([]string{"--config", path, "--tls-cert-file", "/nonexistent/tls.crt", "--tls-client-auth", "sometimes", "--trace-sample-ratio", "2", "--max-request-body-bytes", "0", "--trusted-proxies", "bogus", "--rate-limit-pods", "fast", "--allowed-origins", "https://example.com/path", "--targeting", "rows", "--boss-defeat-action", "explode", "--hit-point-rules", "age=3", "--node-uncordon-after", "0s", "--kill-actions", "explode", "--action-ttl", "0s", "--resource-target", "argoproj.io/v1alpha1/rollouts=restart", "--sim-recreate-delay", "0s", "--sim-crash-interval", "-1s", "--sim-api-error-rate", "1.5", "--fake-pod-pools", "/nonexistent/pools.yaml"})
+	if err == nil {
+		t.Fatal("Expected Load to fail")
+	}
+
+	for _, want := range []string{
+		"listen-address",
+		"storage-backend",
+		"monitor-max must not be negative",
+		"monitor-allowed-cidrs",
+		`namespace "Not_Valid"`,
+		`unknown key "unknown-key"`,
+		"POD_INVADERS_MONITOR_WEBHOOK_RETRIES",
+		"tls-cert-file and tls-key-file must be set together",
+		`tls-client-auth "sometimes" is invalid`,
+		"trace-sample-ratio must be between 0 and 1",
+		"max-request-body-bytes must be positive",
+		`trusted-proxies: invalid CIDR "bogus"`,
+		"rate-limit-pods",
+		`allowed-origins: "https://example.com/path" is invalid`,
+		`targeting "rows" is invalid`,
+		`boss-defeat-action "explode" is invalid`,
+		`hit-point-rules: unknown rule "age"`,
+		"node-uncordon-after must be positive",
+		`kill-actions: unknown action "explode"`,
+		"action-ttl must be positive",
+		`resource-target: "argoproj.io/v1alpha1/rollouts=restart": unknown action "restart"`,
+		"sim-recreate-delay must be positive",
+		"sim-crash-interval must not be negative",
+		"sim-api-error-rate must be between 0 and 1",
+		"fake-pod-pools: failed to read fake pod pools",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestResourceTargets(t *testing.T) {
+	path := writeConfigFile(t, `
+targeting: resource
+resource-target:
+  - argoproj.io/v1alpha1/rollouts=scale
+  - 'kubevirt.io/v1/virtualmachines=patch:{"spec":{"running":false},"metadata":{"labels":{"killed":"true"}}}'
+  - v1/configmaps=delete
+`)
+	cfg, err := Load([]string{"--config", path})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := len(cfg.ParsedResourceTargets()); got != 3 {
+		t.Fatalf("Expected 3 resource targets, got %d", got)
+	}
+	vms, ok := cfg.ResourceTarget("kubevirt.io/v1/virtualmachines")
+	if !ok || vms.Action != "patch" || string(vms.Patch) != `{"spec":{"running":false},"metadata":{"labels":{"killed":"true"}}}` {
+		t.Errorf("Expected the VM patch target, got %+v", vms)
+	}
+	if cm, ok := cfg.ResourceTarget("v1/configmaps"); !ok || cm.GVR.Group != "" || cm.Action != "delete" {
+		t.Errorf("Expected the core ConfigMap target, got %+v", cm)
+	}
+
+	_, err = Load([]string{"--targeting", "resource"})
+	if err == nil || !strings.Contains(err.Error(), "targeting resource requires at least one resource-target") {
+		t.Errorf("Expected resource targeting without targets to fail, got %v", err)
+	}
+	for _, spec := range []string{"rollouts=delete", "argoproj.io/v1alpha1/rollouts", "kubevirt.io/v1/virtualmachines=patch", "kubevirt.io/v1/virtualmachines=patch:[]"} {
+		if _, err := Load([]string{"--resource-target", spec}); err == nil {
+			t.Errorf("Expected --resource-target %s to fail", spec)
+		}
+	}
+}
+
+func TestClusters(t *testing.T) {
+	path := writeConfigFile(t, `
+cluster:
+  - dev
+  - staging=gke_acme_europe-west1_staging
+  - edge=file:/etc/pod-invaders/clusters/edge/kubeconfig
+`)
+	cfg, err := Load([]string{"--config", path})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want := []k8s.ClusterSpec{
+		{Name: "dev", Context: "dev"},
+		{Name: "staging", Context: "gke_acme_europe-west1_staging"},
+		{Name: "edge", Kubeconfig: "/etc/pod-invaders/clusters/edge/kubeconfig"},
+	}
+	if got := cfg.ParsedClusters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected clusters:\n got %+v\nwant %+v", got, want)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--cluster", "Dev"}, "cluster name \"Dev\" is invalid"},
+		{[]string{"--cluster", "dev=", "--cluster", "edge=file:"}, "context must not be empty"},
+		{[]string{"--cluster", "dev", "--cluster", "dev=kind-dev"}, "cluster dev is given more than once"},
+		{[]string{"--cluster", "dev", "--enable-kube=false"}, "cluster requires enable-kube"},
+		{[]string{"--cluster", "dev", "--enable-openshift-auth"}, "cannot be combined with enable-openshift-auth"},
+		{[]string{"--cluster", "dev", "--targeting", "node"}, "cluster only supports targeting random"},
+	} {
+		if _, err := Load(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%v): expected an error containing %q, got %v", tt.args, tt.want, err)
+		}
+	}
+}
+
+func TestIsProtectedNamespace(t *testing.T) {
+	cfg := &Runtime{KillProtectedNamespaces: []string{"kube-system"}}
+	if !cfg.IsProtectedNamespace("kube-system") {
+		t.Error("Expected kube-system to be protected")
+	}
+	if cfg.IsProtectedNamespace("default") {
+		t.Error("Expected default not to be protected")
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay groups the burst of events produced by editors and ConfigMap updates into one reload.
+const reloadDelay = 200 * time.Millisecond
+
+// Change is a setting that differs between two configurations.
+type Change struct {
+	Field   string
+	Old     interface{}
+	New     interface{}
+	Runtime bool // Whether the change can be applied without a restart
+}
+
+// String renders the change as "Field: old -> new".
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Field, c.Old, c.New)
+}
+
+// Diff returns the settings that differ between old and new.
+func Diff(old, new *Config) []Change {
+	changes := diffStruct(reflect.ValueOf(*old), reflect.ValueOf(*new), false)
+	return append(changes, diffStruct(reflect.ValueOf(old.Runtime), reflect.ValueOf(new.Runtime), true)...)
+}
+
+// diffStruct compares the exported, non-embedded fields of two values of the same struct type.
+func diffStruct(old, new reflect.Value, runtime bool) []Change {
+	var changes []Change
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		o, n := old.Field(i).Interface(), new.Field(i).Interface()
+		if !reflect.DeepEqual(o, n) {
+			changes = append(changes, Change{Field: field.Name, Old: o, New: n, Runtime: runtime})
+		}
+	}
+	return changes
+}
+
+// Watch reloads the configuration whenever its config file changes, until ctx is done.
+// Valid reloads are passed to apply; invalid ones are logged and the previous
+// configuration stays in effect. Changes to settings outside Runtime are logged but
+// only take effect after a restart.
+func Watch(ctx context.Context, cfg *Config, apply func(*Config)) error {
+	if cfg.ConfigFile == "" {
+		return fmt.Errorf("no config file to watch")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	// Watch the directory so that files replaced by rename (editors, ConfigMap symlink swaps) are seen
+	if err := watcher.Add(filepath.Dir(cfg.ConfigFile)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config file %s: %w", cfg.ConfigFile, err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		current := cfg
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("Config watcher error", "error", err)
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				timer.Reset(reloadDelay)
+			case <-timer.C:
+				current = reload(current, apply)
+			}
+		}
+	}()
+	return nil
+}
+
+// reload loads the configuration again and applies it if it is valid and has changed.
+func reload(current *Config, apply func(*Config)) *Config {
+	next, err := current.Reload()
+	if err != nil {
+		slog.Error("Rejected config reload", "file", current.ConfigFile, "error", err)
+		return current
+	}
+
+	changes := Diff(current, next)
+	if len(changes) == 0 {
+		return current
+	}
+	for _, change := range changes {
+		if change.Runtime {
+			slog.Info("Config reloaded", "setting", change.Field, "old", change.Old, "new", change.New)
+		} else {
+			slog.Warn("Config change requires a restart to take effect", "setting", change.Field, "old", change.Old, "new", change.New)
+		}
+	}
+	apply(next)
+	return next
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Config{ListenAddress: ":3000", Runtime: Runtime{Difficulty: DifficultyNormal}}
+	new := &Config{ListenAddress: ":8080", Runtime: Runtime{Difficulty: DifficultyHard}}
+
+	changes := Diff(old, new)
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %v", changes)
+	}
+	if changes[0].Field != "ListenAddress" || changes[0].Runtime {
+		t.Errorf("Expected a restart-only ListenAddress change, got %+v", changes[0])
+	}
+	if changes[1].String() != "Difficulty: normal -> hard" || !changes[1].Runtime {
+		t.Errorf("Expected a runtime Difficulty change, got %+v", changes[1])
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := writeConfigFile(t, "namespaces: [team-a]\n")
+	cfg, err := Load([]string{"--config", path})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	applied := make(chan *Config, 4)
+	if err := Watch(ctx, cfg, func(c *Config) { applied <- c }); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+
+	write("namespaces: [team-a, team-b]\ndifficulty: hard\n")
+	select {
+	case next := <-applied:
+		if !reflect.DeepEqual(next.NamespaceNames, []string{"team-a", "team-b"}) || next.Difficulty != DifficultyHard {
+			t.Errorf("Unexpected reloaded settings: %+v", next.Runtime)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for reload")
+	}
+
+	// Invalid configurations are rejected without calling apply
+	write("difficulty: impossible\n")
+	select {
+	case next := <-applied:
+		t.Fatalf("Invalid config was applied: %+v", next.Runtime)
+	case <-time.After(3 * reloadDelay):
+	}
+
+	// Fixing the file applies it relative to the last valid configuration
+	write("namespaces: [team-c]\n")
+	select {
+	case next := <-applied:
+		if !reflect.DeepEqual(next.NamespaceNames, []string{"team-c"}) || next.Difficulty != DifficultyNormal {
+			t.Errorf("Unexpected reloaded settings: %+v", next.Runtime)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for reload")
+	}
+}
@@ -3,40 +3,104 @@ package game
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/dgraph-io/badger/v4"
 )
 
-// KillPodCache tracks pods that have been "killed".
+// maxKilledPods bounds the number of kills a KillPodCache remembers.
+const maxKilledPods = 10000
+
+// KillPodCache tracks pods that have been "killed". Kills of fake pods are remembered per
+// game, since a seeded game draws the same fake pods as the games before it. Kills count
+// for a ttl, and the oldest ones are forgotten once the cache is full.
 type KillPodCache struct {
 	mu   sync.Mutex
-	pods map[string]struct{} // Using a map for efficient lookups
+	ttl  time.Duration
+	pods map[killedPod]time.Time // When each pod was killed
+}
+
+// killedPod identifies a killed pod, and for a fake pod the session of the game it was
+// killed in.
+type killedPod struct {
+	session, pod string
 }
 
-// NewKillPodCache creates a new cache for killed pods.
-func NewKillPodCache() *KillPodCache {
+// NewKillPodCache creates a new cache for killed pods, remembered for ttl.
+func NewKillPodCache(ttl time.Duration) *KillPodCache {
 	return &KillPodCache{
-		pods: make(map[string]struct{}),
+		ttl:  ttl,
+		pods: make(map[killedPod]time.Time),
+	}
+}
+
+// Add records a pod as killed in the game of the given session.
+func (c *KillPodCache) Add(session string, p Pod) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	key := newKilledPod(session, p)
+	if _, ok := c.pods[key]; !ok && len(c.pods) >= maxKilledPods {
+		c.pruneLocked(now)
 	}
+	c.pods[key] = now
 }
 
-// Add records a pod as killed.
-func (c *KillPodCache) Add(p Pod) {
+// IsKilled checks if a pod has been recorded as killed in the game of the given session.
+// Real pods killed in any game count.
+func (c *KillPodCache) IsKilled(session string, p Pod) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	key := p.Namespace + "/" + p.Name
-	c.pods[key] = struct{}{}
+	at, found := c.pods[newKilledPod(session, p)]
+	return found && time.Since(at) <= c.ttl
 }
 
-// IsKilled checks if a pod has been recorded as killed.
-func (c *KillPodCache) IsKilled(p Pod) bool {
+// End forgets the fake pods killed in the game of the given session.
+func (c *KillPodCache) End(session string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	key := p.Namespace + "/" + p.Name
-	_, found := c.pods[key]
-	return found
+	for key := range c.pods {
+		if key.session == session {
+			delete(c.pods, key)
+		}
+	}
+}
+
+// pruneLocked makes room in a full cache, forgetting kills older than the ttl or, if there
+// are none, the oldest kill. c.mu must be held.
+func (c *KillPodCache) pruneLocked(now time.Time) {
+	var oldest killedPod
+	var oldestAt time.Time
+	for key, at := range c.pods {
+		if now.Sub(at) > c.ttl {
+			delete(c.pods, key)
+		} else if oldestAt.IsZero() || at.Before(oldestAt) {
+			oldest, oldestAt = key, at
+		}
+	}
+	if len(c.pods) >= maxKilledPods {
+		delete(c.pods, oldest)
+	}
+}
+
+// newKilledPod returns the key of a pod killed in the game of the given session. Real
+// pods are shared by all games.
+func newKilledPod(session string, p Pod) killedPod {
+	if p.IsRealPod {
+		session = ""
+	}
+	return killedPod{session: session, pod: killKey(p)}
+}
+
+// killKey identifies a pod across the clusters of a fleet.
+func killKey(p Pod) string {
+	if p.Cluster == "" {
+		return p.Namespace + "/" + p.Name
+	}
+	return p.Cluster + "/" + p.Namespace + "/" + p.Name
 }
 
 // HighscoreCache defines the interface for managing highscore data.
@@ -68,7 +132,7 @@ func NewHighscoreCache() HighscoreCache {
 func (c *InMemoryHighscoreCache) Add(hs Highscore) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	log.Printf("Highscore added: %+v", hs)
+	slog.Debug("Highscore added", "name", hs.Name, "score", hs.Score)
 	c.highscores = append(c.highscores, hs)
 }
 
@@ -82,8 +146,8 @@ func (c *InMemoryHighscoreCache) Get() []Highscore {
 	return scoresCopy
 }
 
-// NewBadgerCache creates a new cache for highscores using BadgerDB.
-func NewBadgerCache(dbPath string) (HighscoreCache, error) {
+// OpenBadgerDB opens the BadgerDB database at dbPath so it can be shared between stores.
+func OpenBadgerDB(dbPath string) (*badger.DB, error) {
 	opts := badger.DefaultOptions(dbPath)
 	opts.Logger = nil // Disable badger logging to reduce noise
 
@@ -91,15 +155,34 @@ func NewBadgerCache(dbPath string) (HighscoreCache, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open BadgerDB: %w", err)
 	}
+	return db, nil
+}
+
+// NewBadgerCache creates a new cache for highscores using BadgerDB.
+func NewBadgerCache(dbPath string) (HighscoreCache, error) {
+	db, err := OpenBadgerDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
 
 	return &BadgerHighscoreCache{
-		db: db,
+		db:     db,
+		ownsDB: true,
 	}, nil
 }
 
+// NewBadgerCacheFromDB creates a highscore cache backed by an already open BadgerDB.
+// The caller remains responsible for closing db.
+func NewBadgerCacheFromDB(db *badger.DB) HighscoreCache {
+	return &BadgerHighscoreCache{
+		db: db,
+	}
+}
+
 // BadgerHighscoreCache implements HighscoreCache using BadgerDB for persistent storage.
 type BadgerHighscoreCache struct {
-	db *badger.DB
+	db     *badger.DB
+	ownsDB bool // Whether Close should close db
 }
 
 // Add appends a new highscore to the BadgerDB cache.
@@ -119,9 +202,9 @@ func (c *BadgerHighscoreCache) Add(hs Highscore) {
 	})
 
 	if err != nil {
-		log.Printf("Failed to add highscore to BadgerDB: %v", err)
+		slog.Error("Failed to add highscore to BadgerDB", "error", err)
 	} else {
-		log.Printf("Highscore added to BadgerDB: %+v", hs)
+		slog.Debug("Highscore added to BadgerDB", "name", hs.Name, "score", hs.Score)
 	}
 }
 
@@ -141,30 +224,30 @@ func (c *BadgerHighscoreCache) Get() []Highscore {
 			err := item.Value(func(val []byte) error {
 				var hs Highscore
 				if err := json.Unmarshal(val, &hs); err != nil {
-					log.Printf("Failed to unmarshal highscore: %v", err)
+					slog.Error("Failed to unmarshal highscore", "error", err)
 					return nil // Continue iteration even if one item fails
 				}
 				highscores = append(highscores, hs)
 				return nil
 			})
 			if err != nil {
-				log.Printf("Failed to read highscore value: %v", err)
+				slog.Error("Failed to read highscore value", "error", err)
 			}
 		}
 		return nil
 	})
 
 	if err != nil {
-		log.Printf("Failed to read highscores from BadgerDB: %v", err)
+		slog.Error("Failed to read highscores from BadgerDB", "error", err)
 		return []Highscore{}
 	}
 
 	return highscores
 }
 
-// Close closes the BadgerDB connection.
+// Close closes the BadgerDB connection if it was opened by NewBadgerCache.
 func (c *BadgerHighscoreCache) Close() error {
-	if c.db != nil {
+	if c.db != nil && c.ownsDB {
 		return c.db.Close()
 	}
 	return nil
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"fmt"
 	"path/filepath"
 	"testing"
 	"time"
@@ -175,6 +176,69 @@ func TestInMemoryHighscoreCache(t *testing.T) {
 	}
 }
 
+func TestKillPodCacheClusters(t *testing.T) {
+	cache := NewKillPodCache(time.Hour)
+	dev := Pod{Name: "web-0", Namespace: "default", Cluster: "dev", IsRealPod: true}
+	cache.Add("alice", dev)
+
+	if !cache.IsKilled("alice", dev) {
+		t.Error("Expected the pod in dev to be killed")
+	}
+	edge := dev
+	edge.Cluster = "edge"
+	if cache.IsKilled("alice", edge) {
+		t.Error("Expected the pod of the same name in edge not to be killed")
+	}
+	local := dev
+	local.Cluster = ""
+	if cache.IsKilled("alice", local) {
+		t.Error("Expected a pod outside fleet mode not to match a fleet pod")
+	}
+}
+
+func TestKillPodCacheGames(t *testing.T) {
+	cache := NewKillPodCache(time.Hour)
+	running := Pod{Name: "web-0", Namespace: "default", IsRealPod: true}
+	fake := Pod{Name: "cart-1", Namespace: "shop"}
+	cache.Add("alice", running)
+	cache.Add("alice", fake)
+
+	// A real pod is gone for everyone, a fake one only in the game that killed it
+	if !cache.IsKilled("bob", running) {
+		t.Error("Expected a real pod killed in one game to be killed in all of them")
+	}
+	if cache.IsKilled("bob", fake) {
+		t.Error("Expected a fake pod killed in one game not to be killed in another")
+	}
+	cache.End("alice")
+	if cache.IsKilled("alice", fake) {
+		t.Error("Expected the fake pods of a game to be forgotten when it ends")
+	}
+	if !cache.IsKilled("alice", running) {
+		t.Error("Expected real pods to be remembered when a game ends")
+	}
+}
+
+func TestKillPodCacheBounded(t *testing.T) {
+	cache := NewKillPodCache(time.Hour)
+	stale := Pod{Name: "stale", Namespace: "default", IsRealPod: true}
+	cache.Add("alice", stale)
+	cache.pods[newKilledPod("alice", stale)] = time.Now().Add(-2 * time.Hour)
+	if cache.IsKilled("alice", stale) {
+		t.Error("Expected a kill older than the ttl to be forgotten")
+	}
+
+	for i := range maxKilledPods + 1 {
+		cache.Add("alice", Pod{Name: fmt.Sprintf("web-%d", i), Namespace: "default"})
+	}
+	if len(cache.pods) != maxKilledPods {
+		t.Errorf("Expected the cache to hold %d kills, got %d", maxKilledPods, len(cache.pods))
+	}
+	if _, ok := cache.pods[newKilledPod("alice", stale)]; ok {
+		t.Error("Expected the stale kill to be pruned once the cache is full")
+	}
+}
+
 func TestHighscoreCacheInterface(t *testing.T) {
 	// Test that both implementations satisfy the HighscoreCache interface
 	var cache HighscoreCache
@@ -218,3 +282,28 @@ func testInterface(t *testing.T, cache HighscoreCache, implName string) {
 		t.Errorf("%s implementation: Expected at least 1 score, got 0", implName)
 	}
 }
+
+func TestBadgerCacheFromSharedDB(t *testing.T) {
+	db, err := OpenBadgerDB(filepath.Join(t.TempDir(), "shareddb"))
+	if err != nil {
+		t.Fatalf("Failed to open BadgerDB: %v", err)
+	}
+	defer db.Close()
+
+	cache := NewBadgerCacheFromDB(db)
+	cache.Add(Highscore{GameStarted: time.Now().Unix(), Score: 100, Name: "Shared"})
+
+	// Closing a cache built on a shared database must leave the database open
+	if bc, ok := cache.(*BadgerHighscoreCache); ok {
+		if err := bc.Close(); err != nil {
+			t.Fatalf("Failed to close cache: %v", err)
+		}
+	}
+	if db.IsClosed() {
+		t.Fatal("Expected shared database to remain open")
+	}
+
+	if scores := NewBadgerCacheFromDB(db).Get(); len(scores) != 1 {
+		t.Errorf("Expected 1 highscore, got %d", len(scores))
+	}
+}
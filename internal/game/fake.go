@@ -0,0 +1,369 @@
+package game
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	mrand "math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// suffixAlphabet is the alphabet Kubernetes builds generated names from, free of vowels
+// and look-alike characters.
+const suffixAlphabet = "bcdfghjklmnpqrstvwxz2456789"
+
+// maxAppNameLength leaves room in a pod name for the ReplicaSet hash and pod suffix.
+const maxAppNameLength = validation.DNS1123LabelMaxLength - len("-1234567890-12345")
+
+// FakePools are the words fake pods are made of. Empty pools are taken from
+// DefaultFakePools.
+type FakePools struct {
+	Apps       []string `json:"apps"` // Names of the workloads fake pods belong to
+	Namespaces []string `json:"namespaces"`
+	Nodes      []string `json:"nodes"`
+	Images     []string `json:"images"`
+}
+
+// DefaultFakePools are the pools fake pods are made of unless others are loaded.
+var DefaultFakePools = FakePools{
+	Apps:       fakePodNames,
+	Namespaces: fakeNamespaceNames,
+	Nodes:      fakeNodeNames,
+	Images:     fakeImages,
+}
+
+// LoadFakePools reads pools from a YAML file with the keys apps, namespaces, nodes and
+// images, each a list of strings.
+func LoadFakePools(path string) (FakePools, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FakePools{}, fmt.Errorf("failed to read fake pod pools: %w", err)
+	}
+	var pools FakePools
+	if err := yaml.UnmarshalStrict(data, &pools); err != nil {
+		return FakePools{}, fmt.Errorf("failed to parse fake pod pools %s: %w", path, err)
+	}
+	if err := pools.validate(); err != nil {
+		return FakePools{}, fmt.Errorf("fake pod pools %s: %w", path, err)
+	}
+	return pools.withDefaults(), nil
+}
+
+// validate checks that the pools make valid Kubernetes names.
+func (p FakePools) validate() error {
+	var problems []string
+	for _, app := range p.Apps {
+		if len(app) > maxAppNameLength {
+			problems = append(problems, fmt.Sprintf("app %q is longer than %d characters", app, maxAppNameLength))
+		}
+		for _, msg := range validation.IsDNS1123Label(app) {
+			problems = append(problems, fmt.Sprintf("app %q is invalid: %s", app, msg))
+		}
+	}
+	for _, ns := range p.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			problems = append(problems, fmt.Sprintf("namespace %q is invalid: %s", ns, msg))
+		}
+	}
+	for _, node := range p.Nodes {
+		for _, msg := range validation.IsDNS1123Subdomain(node) {
+			problems = append(problems, fmt.Sprintf("node %q is invalid: %s", node, msg))
+		}
+	}
+	for _, image := range p.Images {
+		if strings.TrimSpace(image) == "" {
+			problems = append(problems, "images must not be empty")
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// withDefaults fills empty pools from DefaultFakePools.
+func (p FakePools) withDefaults() FakePools {
+	pick := func(pool, fallback []string) []string {
+		if len(pool) == 0 {
+			return fallback
+		}
+		return pool
+	}
+	return FakePools{
+		Apps:       pick(p.Apps, DefaultFakePools.Apps),
+		Namespaces: pick(p.Namespaces, DefaultFakePools.Namespaces),
+		Nodes:      pick(p.Nodes, DefaultFakePools.Nodes),
+		Images:     pick(p.Images, DefaultFakePools.Images),
+	}
+}
+
+// FakePodGenerator makes fake pods named like those of real controllers, such as
+// frontend-7d9c5b8f4-x2x7q. Generators with the same seed and pools make the same pods
+// in the same order, apart from their creation time, which is relative to now. No two
+// pods of a generator share a namespace and name. It is safe for concurrent use.
+type FakePodGenerator struct {
+	mu          sync.Mutex
+	source      *mrand.ChaCha8
+	rng         *mrand.Rand
+	pools       FakePools
+	replicaSets map[string]string // ReplicaSet of every namespace/app, so that its pods share it
+	issued      map[string]bool   // namespace/name of every pod made
+}
+
+// NewFakePodGenerator creates a generator seeded with seed that draws from pools.
+func NewFakePodGenerator(seed uint64, pools FakePools) *FakePodGenerator {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], seed)
+	source := mrand.NewChaCha8(key)
+	return &FakePodGenerator{
+		source:      source,
+		rng:         mrand.New(source),
+		pools:       pools.withDefaults(),
+		replicaSets: make(map[string]string),
+		issued:      make(map[string]bool),
+	}
+}
+
+// Pod makes a pod of a Deployment.
+func (g *FakePodGenerator) Pod() Pod {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.replicaSetPod(g.choice(g.pools.Namespaces), g.choice(g.pools.Apps))
+}
+
+// Workload makes a Deployment with replicas pods.
+func (g *FakePodGenerator) Workload(replicas int) Workload {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	w := Workload{
+		Kind:      KindDeployment,
+		Name:      g.choice(g.pools.Apps),
+		Namespace: g.choice(g.pools.Namespaces),
+		Replicas:  int32(replicas),
+	}
+	for range replicas {
+		w.Pods = append(w.Pods, g.replicaSetPod(w.Namespace, w.Name))
+	}
+	return w
+}
+
+// Boss makes a StatefulSet of one to five pods as the boss.
+func (g *FakePodGenerator) Boss() Boss {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	app := g.choice(g.pools.Apps)
+	w := Workload{
+		Kind:      KindStatefulSet,
+		Name:      app,
+		Namespace: g.choice(g.pools.Namespaces),
+		Replicas:  int32(1 + g.rng.IntN(5)),
+	}
+	// A set of the same name may have been made before, in which case its pods are taken
+	for !g.free(w.Namespace, w.Name, int(w.Replicas)) {
+		w.Name = app + "-" + g.suffix(5)
+	}
+	for i := range int(w.Replicas) {
+		pod := g.pod(w.Namespace, fmt.Sprintf("%s-%d", w.Name, i), app)
+		pod.OwnerKind = KindStatefulSet
+		pod.OwnerName = w.Name
+		w.Pods = append(w.Pods, pod)
+	}
+	return NewBoss(w)
+}
+
+// replicaSetPod makes a pod of the current ReplicaSet of the app's Deployment. g.mu must
+// be held.
+func (g *FakePodGenerator) replicaSetPod(ns, app string) Pod {
+	rs, ok := g.replicaSets[ns+"/"+app]
+	if !ok {
+		// Template hashes are 9 or 10 characters long, depending on their value
+		rs = app + "-" + g.suffix(9+g.rng.IntN(2))
+		g.replicaSets[ns+"/"+app] = rs
+	}
+	name := rs + "-" + g.suffix(5)
+	for g.issued[ns+"/"+name] {
+		name = rs + "-" + g.suffix(5)
+	}
+	pod := g.pod(ns, name, app)
+	pod.OwnerKind = KindReplicaSet
+	pod.OwnerName = rs
+	pod.Labels["pod-template-hash"] = strings.TrimPrefix(rs, app+"-")
+	return pod
+}
+
+// free reports whether no pod of a StatefulSet of that name has been made. g.mu must be
+// held.
+func (g *FakePodGenerator) free(ns, set string, replicas int) bool {
+	for i := range replicas {
+		if g.issued[fmt.Sprintf("%s/%s-%d", ns, set, i)] {
+			return false
+		}
+	}
+	return true
+}
+
+// pod makes a running pod of the app and records its name. g.mu must be held.
+func (g *FakePodGenerator) pod(ns, name, app string) Pod {
+	g.issued[ns+"/"+name] = true
+	uid, _ := uuid.NewRandomFromReader(g.source) // ChaCha8 reads never fail
+	return Pod{
+		Name:         name,
+		Namespace:    ns,
+		IsRealPod:    false,
+		UID:          uid.String(),
+		Node:         g.choice(g.pools.Nodes),
+		Phase:        "Running",
+		Ready:        g.rng.IntN(10) > 0,
+		RestartCount: int32(g.rng.IntN(4)),
+		Images:       []string{g.choice(g.pools.Images)},
+		CreatedAt:    time.Now().Add(-time.Duration(g.rng.Int64N(int64(7 * 24 * time.Hour)))).Truncate(time.Second),
+		Labels:       map[string]string{"app": app},
+		QOSClass:     g.choice(fakeQOSClasses),
+	}
+}
+
+// choice selects an element of list. g.mu must be held.
+func (g *FakePodGenerator) choice(list []string) string {
+	return list[g.rng.IntN(len(list))]
+}
+
+// suffix returns n random characters of a generated name. g.mu must be held.
+func (g *FakePodGenerator) suffix(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = suffixAlphabet[g.rng.IntN(len(suffixAlphabet))]
+	}
+	return string(b)
+}
+
+// FakePodGenerators gives every game a generator of its own, so that fake pods are
+// unique within a game. With a seed, every game starts from it and gets the same pods.
+type FakePodGenerators struct {
+	mu    sync.Mutex
+	seed  uint64 // 0 for a random seed per game
+	pools FakePools
+	ttl   time.Duration
+	games map[string]*fakePodGame
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// fakePodGame is the generator of a game in progress.
+type fakePodGame struct {
+	generator *FakePodGenerator
+	seen      time.Time
+}
+
+// NewFakePodGenerators creates generators drawing from pools, seeded with seed or at
+// random if it is 0. The generator of a game is forgotten once it has not been used for
+// ttl, by a background reaper that Close stops.
+func NewFakePodGenerators(seed uint64, pools FakePools, ttl time.Duration) *FakePodGenerators {
+	f := &FakePodGenerators{
+		seed:  seed,
+		pools: pools,
+		ttl:   ttl,
+		games: make(map[string]*fakePodGame),
+		done:  make(chan struct{}),
+	}
+	if ttl > 0 {
+		f.wg.Add(1)
+		go f.reap()
+	}
+	return f
+}
+
+// Close stops the reaper and waits for it to exit. It is safe to call more than once.
+func (f *FakePodGenerators) Close() {
+	f.closeOnce.Do(func() { close(f.done) })
+	f.wg.Wait()
+}
+
+// reap periodically forgets the generators of games that have gone quiet.
+func (f *FakePodGenerators) reap() {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(f.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+			f.mu.Lock()
+			f.pruneLocked(time.Now())
+			f.mu.Unlock()
+		}
+	}
+}
+
+// pruneLocked forgets the generators not used for longer than the ttl. f.mu must be held.
+func (f *FakePodGenerators) pruneLocked(now time.Time) {
+	for id, g := range f.games {
+		if now.Sub(g.seen) > f.ttl {
+			delete(f.games, id)
+		}
+	}
+}
+
+// ForGame returns the generator of the game of the given session, starting one if there
+// is none. Without a session, every call gets a new generator.
+func (f *FakePodGenerators) ForGame(id string) *FakePodGenerator {
+	if id == "" {
+		return f.newGenerator()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	g, ok := f.games[id]
+	if !ok {
+		f.pruneLocked(now)
+		g = &fakePodGame{generator: f.newGenerator()}
+		f.games[id] = g
+	}
+	g.seen = now
+	return g.generator
+}
+
+// End forgets the generator of the given session, so that its next game starts afresh.
+func (f *FakePodGenerators) End(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.games, id)
+}
+
+func (f *FakePodGenerators) newGenerator() *FakePodGenerator {
+	seed := f.seed
+	if seed == 0 {
+		seed = mrand.Uint64()
+	}
+	return NewFakePodGenerator(seed, f.pools)
+}
+
+type fakePodGeneratorKey struct{}
+
+// WithFakePodGenerator returns a context that carries get, which returns the generator
+// of the fake pods made further down the call chain. It is only called once fake pods
+// are needed, so that requests served with real pods alone start no generator.
+func WithFakePodGenerator(ctx context.Context, get func() *FakePodGenerator) context.Context {
+	return context.WithValue(ctx, fakePodGeneratorKey{}, get)
+}
+
+// FakePodGeneratorFromContext returns the generator carried by ctx, or a new randomly
+// seeded one.
+func FakePodGeneratorFromContext(ctx context.Context) *FakePodGenerator {
+	if get, ok := ctx.Value(fakePodGeneratorKey{}).(func() *FakePodGenerator); ok {
+		return get()
+	}
+	return NewFakePodGenerator(mrand.Uint64(), DefaultFakePools)
+}
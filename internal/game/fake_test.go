@@ -0,0 +1,198 @@
+package game
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// names returns the namespace/name of pods.
+func names(pods []Pod) []string {
+	var keys []string
+	for _, p := range pods {
+		keys = append(keys, p.Namespace+"/"+p.Name)
+	}
+	return keys
+}
+
+// generate makes pods, a workload and a boss.
+func generate(g *FakePodGenerator) []Pod {
+	pods := []Pod{g.Pod(), g.Pod(), g.Pod()}
+	pods = append(pods, g.Workload(3).Pods...)
+	return append(pods, g.Boss().Pods...)
+}
+
+func TestFakePodGeneratorSeed(t *testing.T) {
+	a := generate(NewFakePodGenerator(42, DefaultFakePools))
+	b := generate(NewFakePodGenerator(42, DefaultFakePools))
+	if !reflect.DeepEqual(names(a), names(b)) {
+		t.Errorf("Expected the same pods from the same seed, got %v and %v", names(a), names(b))
+	}
+	for i := range a {
+		if a[i].UID != b[i].UID || a[i].Node != b[i].Node || a[i].Images[0] != b[i].Images[0] {
+			t.Errorf("Expected the same metadata from the same seed, got %+v and %+v", a[i], b[i])
+		}
+	}
+
+	c := generate(NewFakePodGenerator(43, DefaultFakePools))
+	if reflect.DeepEqual(names(a), names(c)) {
+		t.Errorf("Expected different pods from another seed, got %v", names(c))
+	}
+}
+
+func TestFakePodGeneratorNames(t *testing.T) {
+	// With a single app and namespace, every pod competes for the same names
+	g := NewFakePodGenerator(1, FakePools{Apps: []string{"web"}, Namespaces: []string{"shop"}})
+	replicaSetPod := regexp.MustCompile(`^web-[` + suffixAlphabet + `]{9,10}-[` + suffixAlphabet + `]{5}$`)
+	seen := make(map[string]bool)
+	check := func(p Pod) {
+		t.Helper()
+		if seen[p.Namespace+"/"+p.Name] {
+			t.Fatalf("Pod %s/%s was made twice", p.Namespace, p.Name)
+		}
+		seen[p.Namespace+"/"+p.Name] = true
+	}
+
+	for range 2000 {
+		p := g.Pod()
+		check(p)
+		if !replicaSetPod.MatchString(p.Name) || p.OwnerKind != KindReplicaSet || !strings.HasPrefix(p.Name, p.OwnerName+"-") {
+			t.Fatalf("Expected a ReplicaSet pod name, got %+v", p)
+		}
+		if p.OwnerName != "web-"+p.Labels["pod-template-hash"] {
+			t.Fatalf("Expected the pod-template-hash label of %s, got %v", p.OwnerName, p.Labels)
+		}
+	}
+	w := g.Workload(5)
+	for _, p := range w.Pods {
+		check(p)
+		if p.OwnerName != w.Pods[0].OwnerName {
+			t.Errorf("Expected the pods of a workload to share a ReplicaSet, got %s and %s", p.OwnerName, w.Pods[0].OwnerName)
+		}
+	}
+	for range 20 {
+		boss := g.Boss()
+		for i, p := range boss.Pods {
+			check(p)
+			if p.Name != boss.Name+"-"+string(rune('0'+i)) || p.OwnerKind != KindStatefulSet {
+				t.Errorf("Expected StatefulSet pod names, got %+v", p)
+			}
+		}
+	}
+}
+
+func TestLoadFakePools(t *testing.T) {
+	write := func(content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "pools.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write pools: %v", err)
+		}
+		return path
+	}
+
+	pools, err := LoadFakePools(write("apps: [checkout, cart]\nnamespaces: [shop]\n"))
+	if err != nil {
+		t.Fatalf("LoadFakePools failed: %v", err)
+	}
+	if !reflect.DeepEqual(pools.Apps, []string{"checkout", "cart"}) || !reflect.DeepEqual(pools.Namespaces, []string{"shop"}) {
+		t.Errorf("Unexpected pools: %+v", pools)
+	}
+	if !reflect.DeepEqual(pools.Images, DefaultFakePools.Images) || !reflect.DeepEqual(pools.Nodes, DefaultFakePools.Nodes) {
+		t.Errorf("Expected missing pools to keep the defaults, got %+v", pools)
+	}
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"apps: [Not_Valid]\n", `app "Not_Valid" is invalid`},
+		{"apps: [" + strings.Repeat("a", maxAppNameLength+1) + "]\n", "longer than"},
+		{"namespaces: [kube.system]\n", `namespace "kube.system" is invalid`},
+		{"images: [\"\"]\n", "images must not be empty"},
+		{"pods: [web]\n", `unknown field "pods"`},
+	}
+	for _, tt := range tests {
+		if _, err := LoadFakePools(write(tt.content)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadFakePools(%q): expected an error mentioning %q, got %v", tt.content, tt.want, err)
+		}
+	}
+	if _, err := LoadFakePools(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestFakePodGenerators(t *testing.T) {
+	generators := NewFakePodGenerators(7, DefaultFakePools, time.Minute)
+	defer generators.Close()
+	g := generators.ForGame("alice")
+	if generators.ForGame("alice") != g {
+		t.Error("Expected a game to keep its generator")
+	}
+	if generators.ForGame("bob") == g || generators.ForGame("") == generators.ForGame("") {
+		t.Error("Expected every game to get a generator of its own")
+	}
+
+	// Seeded games all get the same pods
+	first := g.Pod()
+	if other := generators.ForGame("bob").Pod(); other.Name != first.Name {
+		t.Errorf("Expected seeded games to start with the same pod, got %s and %s", first.Name, other.Name)
+	}
+	generators.End("alice")
+	if again := generators.ForGame("alice").Pod(); again.Name != first.Name {
+		t.Errorf("Expected a new game to start afresh, got %s instead of %s", again.Name, first.Name)
+	}
+
+	stale := NewFakePodGenerators(0, DefaultFakePools, 0)
+	g = stale.ForGame("alice")
+	time.Sleep(time.Millisecond)
+	stale.ForGame("bob") // Forgets alice's game
+	if stale.ForGame("alice") == g {
+		t.Error("Expected a stale game to be forgotten")
+	}
+}
+
+func TestFakePodGeneratorsReaper(t *testing.T) {
+	generators := NewFakePodGenerators(0, DefaultFakePools, 20*time.Millisecond)
+	defer generators.Close()
+	generators.ForGame("alice")
+
+	// No other game starts, so only the reaper can forget alice's
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		generators.mu.Lock()
+		n := len(generators.games)
+		generators.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the reaper to forget an idle game")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	generators.Close() // Safe to call twice
+}
+
+func TestFakePodGeneratorFromContext(t *testing.T) {
+	g := NewFakePodGenerator(1, DefaultFakePools)
+	calls := 0
+	ctx := WithFakePodGenerator(context.Background(), func() *FakePodGenerator {
+		calls++
+		return g
+	})
+	if calls != 0 {
+		t.Fatal("Expected the generator not to be made before it is needed")
+	}
+	if FakePodGeneratorFromContext(ctx) != g || calls != 1 {
+		t.Errorf("Expected the generator carried by the context, after %d calls", calls)
+	}
+	if FakePodGeneratorFromContext(context.Background()) == nil {
+		t.Error("Expected a generator without one in the context")
+	}
+}
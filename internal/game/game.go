@@ -1,14 +1,36 @@
 package game
 
 import (
-	"crypto/rand"
+	mrand "math/rand/v2"
+	"time"
 )
 
 // Pod represents a Kubernetes pod, which can be real or fake.
 type Pod struct {
 	Name      string `json:"name"`
 	Namespace string `json:"namespace,omitempty"`
+	Cluster   string `json:"cluster,omitempty"` // Cluster of the fleet the pod runs in, empty outside fleet mode
 	IsRealPod bool   `json:"isRealPod,omitempty"`
+
+	// Metadata shown to players before they shoot
+	UID          string            `json:"uid,omitempty"`
+	OwnerKind    string            `json:"ownerKind,omitempty"` // Kind of the controlling owner, e.g. ReplicaSet
+	OwnerName    string            `json:"ownerName,omitempty"`
+	Node         string            `json:"node,omitempty"`
+	Phase        string            `json:"phase,omitempty"`
+	Ready        bool              `json:"ready,omitempty"`
+	RestartCount int32             `json:"restartCount,omitempty"` // Summed over all containers
+	Images       []string          `json:"images,omitempty"`
+	CreatedAt    time.Time         `json:"createdAt,omitzero"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	QOSClass     string            `json:"qosClass,omitempty"`
+
+	// Signals of how important the pod is, which decide its hit points
+	PriorityClass string `json:"priorityClass,omitempty"`
+	Priority      int32  `json:"priority,omitempty"`
+	CoveredByPDB  bool   `json:"coveredByPDB,omitempty"`  // A PodDisruptionBudget selects the pod
+	OwnerReplicas int32  `json:"ownerReplicas,omitempty"` // Desired replicas of the owning workload
+	HitPoints     int    `json:"hitPoints,omitempty"`     // Hits it takes to kill the pod in the game
 }
 
 // Namespaces is a list of Kubernetes namespaces.
@@ -37,25 +59,24 @@ var fakeNamespaceNames = []string{
 	"penguin", "dolphin", "whale", "shark", "octopus", "crab", "lobster", "jellyfish",
 }
 
+var fakeNodeNames = []string{"worker-0", "worker-1", "worker-2", "worker-3"}
+
+var fakeImages = []string{
+	"nginx:1.27", "redis:7.4", "postgres:16", "busybox:1.36", "quay.io/prometheus/node-exporter:v1.8.2",
+}
+
+var fakeQOSClasses = []string{"Guaranteed", "Burstable", "BestEffort"}
+
 // randomChoice selects a random element from a slice of strings.
 func randomChoice(list []string) string {
 	if len(list) == 0 {
 		return ""
 	}
-	b := make([]byte, 1)
-	_, err := rand.Read(b)
-	if err != nil {
-		// Fallback to a less random choice if crypto/rand fails
-		return list[0]
-	}
-	return list[int(b[0])%len(list)]
+	return list[mrand.IntN(len(list))]
 }
 
-// GenerateFakePod creates a pod with a randomized name and namespace.
+// GenerateFakePod creates a pod with a randomized name, namespace and metadata. Pods of
+// a game should come from its FakePodGenerator instead, which keeps them unique.
 func GenerateFakePod() Pod {
-	return Pod{
-		Name:      randomChoice(fakePodNames),
-		Namespace: randomChoice(fakeNamespaceNames),
-		IsRealPod: false,
-	}
+	return NewFakePodGenerator(mrand.Uint64(), DefaultFakePools).Pod()
 }
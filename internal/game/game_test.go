@@ -0,0 +1,77 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateFakePod(t *testing.T) {
+	pod := GenerateFakePod()
+	if pod.IsRealPod {
+		t.Error("Expected a fake pod")
+	}
+	if pod.Name == "" || pod.Namespace == "" || pod.UID == "" || pod.OwnerName == "" || pod.Node == "" || len(pod.Images) == 0 || pod.QOSClass == "" {
+		t.Errorf("Expected fake pods to carry metadata, got %+v", pod)
+	}
+	if pod.Phase != "Running" {
+		t.Errorf("Expected a running pod, got phase %q", pod.Phase)
+	}
+	if age := time.Since(pod.CreatedAt); age < 0 || age > 7*24*time.Hour+time.Second {
+		t.Errorf("Expected an age of up to a week, got %v", age)
+	}
+	if GenerateFakePod().UID == pod.UID {
+		t.Error("Expected fake pods to have unique UIDs")
+	}
+}
+
+func TestBossHitPoints(t *testing.T) {
+	tests := []struct {
+		replicas int32
+		want     int
+	}{
+		{0, minBossHitPoints},
+		{1, minBossHitPoints},
+		{3, 9},
+		{10, 30},
+		{1000, maxBossHitPoints},
+	}
+	for _, tt := range tests {
+		if got := BossHitPoints(tt.replicas); got != tt.want {
+			t.Errorf("BossHitPoints(%d) = %d, want %d", tt.replicas, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateFakeWorkload(t *testing.T) {
+	w := GenerateFakeWorkload(4)
+	if w.IsReal || w.Kind != KindDeployment || w.Replicas != 4 || len(w.Pods) != 4 {
+		t.Fatalf("Unexpected fake workload: %+v", w)
+	}
+	for _, pod := range w.Pods {
+		if pod.Namespace != w.Namespace || pod.OwnerName != w.Pods[0].OwnerName || pod.Labels["app"] != w.Name {
+			t.Errorf("Expected pods to belong to the workload, got %+v", pod)
+		}
+	}
+
+	boss := GenerateFakeBoss()
+	if boss.Kind != KindStatefulSet || boss.HitPoints != BossHitPoints(boss.Replicas) {
+		t.Errorf("Unexpected fake boss: %+v", boss)
+	}
+	if boss.Pods[0].Name != boss.Name+"-0" {
+		t.Errorf("Expected StatefulSet pod names, got %s", boss.Pods[0].Name)
+	}
+}
+
+func TestGenerateFakeNode(t *testing.T) {
+	node := GenerateFakeNode()
+	if node.IsReal || !node.Ready || node.Name == "" || node.KubeletVersion == "" || len(node.Roles) == 0 {
+		t.Errorf("Unexpected fake node: %+v", node)
+	}
+}
+
+func TestGenerateFakeResource(t *testing.T) {
+	r := GenerateFakeResource("argoproj.io", "v1alpha1", "rollouts", "Rollout", "scale")
+	if r.IsReal || r.Name == "" || r.Namespace == "" || r.Kind != "Rollout" || r.Resource != "rollouts" || r.Action != "scale" {
+		t.Errorf("Unexpected fake resource: %+v", r)
+	}
+}
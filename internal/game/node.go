@@ -0,0 +1,38 @@
+package game
+
+import (
+	"fmt"
+	mrand "math/rand/v2"
+)
+
+var fakeNodePools = []string{"worker", "infra", "gpu", "storage"}
+
+var fakeKubeletVersions = []string{"v1.30.6", "v1.31.2", "v1.32.0"}
+
+// Node is a cluster node standing in for an invader in node targeting.
+type Node struct {
+	Name           string   `json:"name"`
+	IsReal         bool     `json:"isRealNode,omitempty"`
+	Ready          bool     `json:"ready"`
+	Roles          []string `json:"roles,omitempty"`
+	KubeletVersion string   `json:"kubeletVersion,omitempty"`
+}
+
+// GenerateFakeNode creates a fake worker node.
+func GenerateFakeNode() Node {
+	return Node{
+		Name:           fmt.Sprintf("%s-%04x", randomChoice(fakeNodePools), mrand.IntN(1<<16)),
+		Ready:          true,
+		Roles:          []string{"worker"},
+		KubeletVersion: randomChoice(fakeKubeletVersions),
+	}
+}
+
+// NodeDrain is the outcome of cordoning and draining a node.
+type NodeDrain struct {
+	Node    string   `json:"node"`
+	Evicted []string `json:"evicted"`           // Pods evicted, as namespace/name
+	Blocked []string `json:"blocked,omitempty"` // Pods whose eviction a PodDisruptionBudget refused
+	Skipped []string `json:"skipped,omitempty"` // DaemonSet, mirror and protected pods left in place
+	Failed  []string `json:"failed,omitempty"`  // Pods whose eviction failed for other reasons
+}
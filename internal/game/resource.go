@@ -0,0 +1,33 @@
+package game
+
+import (
+	"fmt"
+	mrand "math/rand/v2"
+)
+
+// Resource is an object of a targeted resource type, such as an Argo Rollout, standing in
+// for an invader in resource targeting.
+type Resource struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"` // Plural resource name, e.g. rollouts
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	IsReal    bool   `json:"isRealResource,omitempty"`
+	Action    string `json:"action"`             // What a kill does: delete, scale or patch
+	Replicas  *int64 `json:"replicas,omitempty"` // spec.replicas, for resources that have one
+}
+
+// GenerateFakeResource creates a fake object of the given resource type and kind.
+func GenerateFakeResource(group, version, resource, kind, action string) Resource {
+	return Resource{
+		Group:     group,
+		Version:   version,
+		Resource:  resource,
+		Kind:      kind,
+		Namespace: randomChoice(fakeNamespaceNames),
+		Name:      fmt.Sprintf("%s-%04x", randomChoice(fakePodNames), mrand.IntN(1<<16)),
+		Action:    action,
+	}
+}
@@ -0,0 +1,61 @@
+package game
+
+import (
+	"sync"
+	"time"
+)
+
+// SessionTracker keeps track of games in progress using heartbeats from the browser.
+type SessionTracker struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	seen map[string]time.Time
+}
+
+// NewSessionTracker creates a tracker that considers a game over once no heartbeat
+// has been received for ttl.
+func NewSessionTracker(ttl time.Duration) *SessionTracker {
+	return &SessionTracker{
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// Touch records a heartbeat for the given session. A new session forgets stale ones, so
+// that the tracker stays bounded without anything reading it.
+func (t *SessionTracker) Touch(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := t.seen[id]; !ok {
+		t.pruneLocked(now)
+	}
+	t.seen[id] = now
+}
+
+// End marks the game of the given session as finished.
+func (t *SessionTracker) End(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.seen, id)
+}
+
+// Active returns the number of sessions with a recent heartbeat, forgetting stale ones.
+func (t *SessionTracker) Active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pruneLocked(time.Now())
+	return len(t.seen)
+}
+
+// pruneLocked forgets sessions without a heartbeat for longer than the ttl. t.mu must be
+// held.
+func (t *SessionTracker) pruneLocked(now time.Time) {
+	for id, last := range t.seen {
+		if now.Sub(last) > t.ttl {
+			delete(t.seen, id)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionTracker(t *testing.T) {
+	tracker := NewSessionTracker(50 * time.Millisecond)
+
+	tracker.Touch("alice")
+	tracker.Touch("bob")
+	tracker.Touch("alice")
+	if active := tracker.Active(); active != 2 {
+		t.Errorf("Expected 2 active games, got %d", active)
+	}
+
+	tracker.End("bob")
+	if active := tracker.Active(); active != 1 {
+		t.Errorf("Expected 1 active game after ending one, got %d", active)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if active := tracker.Active(); active != 0 {
+		t.Errorf("Expected stale games to expire, got %d active", active)
+	}
+}
+
+func TestSessionTrackerPrunesOnTouch(t *testing.T) {
+	tracker := NewSessionTracker(0)
+	tracker.Touch("alice")
+	time.Sleep(time.Millisecond)
+	tracker.Touch("bob") // Forgets alice's game
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if _, ok := tracker.seen["alice"]; ok || len(tracker.seen) != 1 {
+		t.Errorf("Expected a new session to forget stale ones, got %v", tracker.seen)
+	}
+}
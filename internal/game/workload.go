@@ -0,0 +1,55 @@
+package game
+
+import mrand "math/rand/v2"
+
+// Workload kinds
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindDaemonSet   = "DaemonSet"
+	KindReplicaSet  = "ReplicaSet"
+	KindPod         = "Pod" // Pods without a controller are their own workload
+)
+
+// Boss hit points, derived from the replica count of the boss workload
+const (
+	bossHitsPerReplica = 3
+	minBossHitPoints   = 5
+	maxBossHitPoints   = 60
+)
+
+// Workload is a group of pods with the same owner, such as the replicas of a Deployment.
+type Workload struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Replicas  int32  `json:"replicas"` // Desired replicas, which may differ from len(Pods)
+	IsReal    bool   `json:"isRealWorkload,omitempty"`
+	Pods      []Pod  `json:"pods"`
+}
+
+// Boss is the workload a boss level stands for.
+type Boss struct {
+	Workload
+	HitPoints int `json:"hitPoints"`
+}
+
+// NewBoss makes w the boss, giving it hit points according to its replica count.
+func NewBoss(w Workload) Boss {
+	return Boss{Workload: w, HitPoints: BossHitPoints(w.Replicas)}
+}
+
+// BossHitPoints returns the hit points of a boss workload with the given replica count.
+func BossHitPoints(replicas int32) int {
+	return min(max(int(replicas)*bossHitsPerReplica, minBossHitPoints), maxBossHitPoints)
+}
+
+// GenerateFakeWorkload creates a Deployment-like workload of fake pods.
+func GenerateFakeWorkload(replicas int) Workload {
+	return NewFakePodGenerator(mrand.Uint64(), DefaultFakePools).Workload(replicas)
+}
+
+// GenerateFakeBoss creates a StatefulSet-like boss of fake pods.
+func GenerateFakeBoss() Boss {
+	return NewFakePodGenerator(mrand.Uint64(), DefaultFakePools).Boss()
+}
@@ -0,0 +1,335 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
+)
+
+// Built-in action names
+const (
+	ActionDelete           = "delete"            // Delete the pod
+	ActionContainerKill    = "container-kill"    // Restart the pod's first container
+	ActionNetworkPartition = "network-partition" // Cut the pod off the network for a while
+	ActionScaleToZero      = "scale-to-zero"     // Scale the pod's owner to zero for a while
+)
+
+const (
+	// containerKillImage runs kill in the ephemeral container of a container kill.
+	containerKillImage = "busybox:1.36"
+	// partitionLabel selects a partitioned pod in its deny-all NetworkPolicy.
+	partitionLabel = "pod-invaders/partition"
+	// managedByLabel marks the objects pod-invaders creates.
+	managedByLabel = "app.kubernetes.io/managed-by"
+)
+
+// Action is a chaos action performed on the pod of a killed invader.
+type Action interface {
+	// Name identifies the action in configuration and kill requests.
+	Name() string
+	// Apply performs the action on a pod. It returns the temporary change it made, or
+	// nil when there is nothing to undo, e.g. for a deleted pod its owner replaces.
+	// Changes are undone through registered undo operations, see RegisterUndo.
+	Apply(ctx context.Context, client kubernetes.Interface, pod game.Pod) (*Change, error)
+}
+
+// Change is a temporary change an action made to the cluster.
+type Change struct {
+	Key  string // Identifies what was changed, e.g. Deployment/default/web
+	Undo Undo   // Undoes the change
+}
+
+var (
+	actionsMu sync.RWMutex
+	actions   = map[string]Action{
+		ActionDelete:           deleteAction{},
+		ActionContainerKill:    containerKillAction{},
+		ActionNetworkPartition: networkPartitionAction{},
+		ActionScaleToZero:      scaleToZeroAction{},
+	}
+)
+
+// RegisterAction makes an action available under its name, replacing any action
+// registered under the same name.
+func RegisterAction(a Action) {
+	actionsMu.Lock()
+	defer actionsMu.Unlock()
+	actions[a.Name()] = a
+}
+
+// LookupAction returns the action registered under name.
+func LookupAction(name string) (Action, bool) {
+	actionsMu.RLock()
+	defer actionsMu.RUnlock()
+	a, ok := actions[name]
+	return a, ok
+}
+
+// ActionNames returns the names of the registered actions in alphabetical order.
+func ActionNames() []string {
+	actionsMu.RLock()
+	defer actionsMu.RUnlock()
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyAction performs an action on a pod, tracing it as k8s.Action.
+func ApplyAction(ctx context.Context, client kubernetes.Interface, action Action, pod game.Pod) (change *Change, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.Action", trace.WithAttributes(
+		attribute.String("k8s.action", action.Name()),
+		attribute.String("k8s.namespace.name", pod.Namespace),
+		attribute.String("k8s.pod.name", pod.Name),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if !pod.IsRealPod {
+		return nil, fmt.Errorf("cannot act on fake pod: %s/%s", pod.Namespace, pod.Name)
+	}
+	return action.Apply(ctx, client, pod)
+}
+
+// deleteAction deletes the pod.
+type deleteAction struct{}
+
+func (deleteAction) Name() string { return ActionDelete }
+
+func (deleteAction) Apply(ctx context.Context, client kubernetes.Interface, pod game.Pod) (*Change, error) {
+	return nil, KillPod(ctx, client, pod)
+}
+
+// containerKillAction restarts the pod's first container by running kill 1 in an
+// ephemeral container sharing its process namespace. Processes that ignore SIGTERM
+// keep running. Ephemeral containers cannot be removed, so there is nothing to undo.
+type containerKillAction struct{}
+
+func (containerKillAction) Name() string { return ActionContainerKill }
+
+func (containerKillAction) Apply(ctx context.Context, client kubernetes.Interface, pod game.Pod) (*Change, error) {
+	obj, err := getPodObject(ctx, client, pod)
+	if err != nil {
+		return nil, err
+	}
+	if len(obj.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("pod %s/%s has no containers", pod.Namespace, pod.Name)
+	}
+	if obj.Spec.ShareProcessNamespace != nil && *obj.Spec.ShareProcessNamespace {
+		// PID 1 would be the pause container, taking down the whole pod
+		return nil, fmt.Errorf("pod %s/%s shares its process namespace", pod.Namespace, pod.Name)
+	}
+
+	target := obj.Spec.Containers[0].Name
+	obj.Spec.EphemeralContainers = append(obj.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:    fmt.Sprintf("pod-invaders-kill-%05x", rand.Intn(1<<20)),
+			Image:   containerKillImage,
+			Command: []string{"kill", "1"},
+		},
+		TargetContainerName: target,
+	})
+	start := time.Now()
+	_, err = client.CoreV1().Pods(pod.Namespace).UpdateEphemeralContainers(ctx, pod.Name, obj, metav1.UpdateOptions{})
+	observe("update_ephemeral_containers", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add ephemeral container to pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	logging.FromContext(ctx).Info("Killed container", "namespace", pod.Namespace, "pod", pod.Name, "container", target)
+	return nil, nil
+}
+
+// networkPartitionAction labels the pod and applies a NetworkPolicy selecting it that
+// denies all ingress and egress. It only has an effect with a network plugin that
+// enforces NetworkPolicies.
+type networkPartitionAction struct{}
+
+func (networkPartitionAction) Name() string { return ActionNetworkPartition }
+
+func (networkPartitionAction) Apply(ctx context.Context, client kubernetes.Interface, pod game.Pod) (*Change, error) {
+	obj, err := getPodObject(ctx, client, pod)
+	if err != nil {
+		return nil, err
+	}
+	id := string(obj.UID)
+	policyName := "pod-invaders-partition-" + id
+	policies := client.NetworkingV1().NetworkPolicies(pod.Namespace)
+
+	if err := patchPodLabel(ctx, client, pod.Namespace, pod.Name, fmt.Sprintf("%q", id)); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	_, err = policies.Create(ctx, &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      policyName,
+			Namespace: pod.Namespace,
+			Labels:    map[string]string{managedByLabel: "pod-invaders"},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{partitionLabel: id}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}, metav1.CreateOptions{})
+	observe("create_network_policy", start, err)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		_ = patchPodLabel(ctx, client, pod.Namespace, pod.Name, "null") // Best effort, the label selects nothing without the policy
+		return nil, fmt.Errorf("failed to create NetworkPolicy %s/%s: %w", pod.Namespace, policyName, err)
+	}
+	logging.FromContext(ctx).Info("Partitioned pod", "namespace", pod.Namespace, "pod", pod.Name, "policy", policyName)
+
+	return &Change{
+		Key: "NetworkPolicy/" + pod.Namespace + "/" + policyName,
+		Undo: Undo{
+			Op:        UndoUnpartition,
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			Args:      map[string]string{"policy": policyName},
+		},
+	}, nil
+}
+
+// patchPodLabel sets the partition label of a pod to value, a JSON string or null.
+func patchPodLabel(ctx context.Context, client kubernetes.Interface, namespace, name, value string) error {
+	patch := fmt.Appendf(nil, `{"metadata":{"labels":{%q:%s}}}`, partitionLabel, value)
+	start := time.Now()
+	_, err := client.CoreV1().Pods(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	observe("patch_pod", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to label pod %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// scaleToZeroAction scales the Deployment, StatefulSet or ReplicaSet owning the pod to
+// zero replicas, and back to its previous replica count on rollback.
+type scaleToZeroAction struct{}
+
+func (scaleToZeroAction) Name() string { return ActionScaleToZero }
+
+func (scaleToZeroAction) Apply(ctx context.Context, client kubernetes.Interface, pod game.Pod) (*Change, error) {
+	obj, err := getPodObject(ctx, client, pod)
+	if err != nil {
+		return nil, err
+	}
+	owner := newAnnotator(client).owner(ctx, PodFromObject(obj))
+	switch owner.kind {
+	case game.KindDeployment, game.KindStatefulSet, game.KindReplicaSet:
+	default:
+		return nil, fmt.Errorf("pod %s/%s has no scalable owner", pod.Namespace, pod.Name)
+	}
+
+	// The count is restored on rollback, so a guess would leave the workload at the wrong scale
+	replicas, err := specReplicas(ctx, client, owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := scaleWorkload(ctx, client, owner, 0); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("Scaled workload to zero", "kind", owner.kind, "namespace", owner.namespace, "name", owner.name, "replicas", replicas)
+
+	return &Change{
+		Key: owner.kind + "/" + owner.namespace + "/" + owner.name,
+		Undo: Undo{
+			Op:        UndoScale,
+			Namespace: owner.namespace,
+			Name:      owner.name,
+			Args:      map[string]string{"kind": owner.kind, "replicas": strconv.Itoa(int(replicas))},
+		},
+	}, nil
+}
+
+// scaleWorkload sets the replicas of a Deployment, StatefulSet or ReplicaSet.
+func scaleWorkload(ctx context.Context, client kubernetes.Interface, w workloadKey, replicas int32) error {
+	patch := fmt.Appendf(nil, `{"spec":{"replicas":%d}}`, replicas)
+	var err error
+	start := time.Now()
+	switch w.kind {
+	case game.KindDeployment:
+		_, err = client.AppsV1().Deployments(w.namespace).Patch(ctx, w.name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case game.KindStatefulSet:
+		_, err = client.AppsV1().StatefulSets(w.namespace).Patch(ctx, w.name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case game.KindReplicaSet:
+		_, err = client.AppsV1().ReplicaSets(w.namespace).Patch(ctx, w.name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	default:
+		return fmt.Errorf("cannot scale %s %s/%s", w.kind, w.namespace, w.name)
+	}
+	observe("scale_workload", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to scale %s %s/%s to %d: %w", w.kind, w.namespace, w.name, replicas, err)
+	}
+	return nil
+}
+
+// specReplicas returns the replicas in the spec of a Deployment, StatefulSet or
+// ReplicaSet. A ReplicaSet controlled by a Deployment is refused, since the Deployment
+// would scale it back up.
+func specReplicas(ctx context.Context, client kubernetes.Interface, w workloadKey) (int32, error) {
+	var replicas *int32
+	var controller *metav1.OwnerReference
+	var err error
+	start := time.Now()
+	switch w.kind {
+	case game.KindDeployment:
+		d, e := client.AppsV1().Deployments(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		err = e
+		if err == nil {
+			replicas = d.Spec.Replicas
+		}
+	case game.KindStatefulSet:
+		sts, e := client.AppsV1().StatefulSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		err = e
+		if err == nil {
+			replicas = sts.Spec.Replicas
+		}
+	case game.KindReplicaSet:
+		rs, e := client.AppsV1().ReplicaSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		err = e
+		if err == nil {
+			replicas = rs.Spec.Replicas
+			controller = metav1.GetControllerOf(rs)
+		}
+	default:
+		return 0, fmt.Errorf("cannot scale %s %s/%s", w.kind, w.namespace, w.name)
+	}
+	observe("get_workload", start, err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s %s/%s: %w", w.kind, w.namespace, w.name, err)
+	}
+	if controller != nil && controller.Kind == game.KindDeployment {
+		return 0, fmt.Errorf("%s %s/%s is controlled by Deployment %s", w.kind, w.namespace, w.name, controller.Name)
+	}
+	if replicas == nil {
+		return 0, fmt.Errorf("%s %s/%s has no replica count", w.kind, w.namespace, w.name)
+	}
+	return *replicas, nil
+}
+
+// getPodObject gets the Kubernetes object of a pod an action is performed on.
+func getPodObject(ctx context.Context, client kubernetes.Interface, pod game.Pod) (*corev1.Pod, error) {
+	start := time.Now()
+	obj, err := client.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	observe("get_pod", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	return obj, nil
+}
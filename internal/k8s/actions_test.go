@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+)
+
+// controlledBy returns the owner references making kind/name the controller.
+func controlledBy(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, Controller: &controller}}
+}
+
+// shopObjects returns the Deployment cart with the given replicas, its ReplicaSet and a pod.
+func shopObjects(replicas *int32) []runtime.Object {
+	return []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "cart", Namespace: "shop"},
+			Spec:       appsv1.DeploymentSpec{Replicas: replicas},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "cart-5d8f7c9b4", Namespace: "shop", OwnerReferences: controlledBy(game.KindDeployment, "cart")},
+			Spec:       appsv1.ReplicaSetSpec{Replicas: replicas},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "cart-5d8f7c9b4-x2x7q", Namespace: "shop", OwnerReferences: controlledBy(game.KindReplicaSet, "cart-5d8f7c9b4")},
+		},
+	}
+}
+
+func TestScaleToZeroAction(t *testing.T) {
+	ctx := context.Background()
+	action, _ := LookupAction(ActionScaleToZero)
+	pod := game.Pod{Name: "cart-5d8f7c9b4-x2x7q", Namespace: "shop", IsRealPod: true}
+
+	replicas := int32(3)
+	client := fake.NewSimpleClientset(shopObjects(&replicas)...)
+	change, err := ApplyAction(ctx, client, action, pod)
+	if err != nil {
+		t.Fatalf("ApplyAction failed: %v", err)
+	}
+	if change.Key != "Deployment/shop/cart" || change.Undo.Op != UndoScale || change.Undo.Args["kind"] != game.KindDeployment {
+		t.Errorf("Expected the Deployment to be scaled, got %+v", change)
+	}
+	if got := change.Undo.Args["replicas"]; got != "3" {
+		t.Errorf("Expected 3 replicas to be recorded, got %s", got)
+	}
+	d, err := client.AppsV1().Deployments("shop").Get(ctx, "cart", metav1.GetOptions{})
+	if err != nil || d.Spec.Replicas == nil || *d.Spec.Replicas != 0 {
+		t.Fatalf("Expected the Deployment at zero replicas, got %+v (%v)", d, err)
+	}
+
+	if err := change.Undo.Apply(ctx, Clients{Kube: client}); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	d, err = client.AppsV1().Deployments("shop").Get(ctx, "cart", metav1.GetOptions{})
+	if err != nil || *d.Spec.Replicas != 3 {
+		t.Errorf("Expected the undo to restore 3 replicas, got %+v (%v)", d, err)
+	}
+}
+
+func TestScaleToZeroActionRefused(t *testing.T) {
+	ctx := context.Background()
+	action, _ := LookupAction(ActionScaleToZero)
+	pod := game.Pod{Name: "cart-5d8f7c9b4-x2x7q", Namespace: "shop", IsRealPod: true}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    string
+	}{
+		// Without replicas, the count to restore is unknown
+		{name: "no replicas", objects: shopObjects(nil), want: "no replica count"},
+		// The pod's owner cannot be resolved to its Deployment, which would scale it back up
+		{name: "missing ReplicaSet", objects: shopObjects(new(int32))[2:], want: "failed to get ReplicaSet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.objects...)
+			if _, err := ApplyAction(ctx, client, action, pod); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error mentioning %q, got %v", tt.want, err)
+			}
+			for _, a := range client.Actions() {
+				if a.GetVerb() == "patch" {
+					t.Errorf("Expected nothing to be scaled, got %s of %s", a.GetVerb(), a.GetResource().Resource)
+				}
+			}
+		})
+	}
+
+	// A ReplicaSet its Deployment controls is never scaled by itself
+	replicas := int32(2)
+	client := fake.NewSimpleClientset(shopObjects(&replicas)...)
+	key := workloadKey{kind: game.KindReplicaSet, namespace: "shop", name: "cart-5d8f7c9b4"}
+	if _, err := specReplicas(ctx, client, key); err == nil || !strings.Contains(err.Error(), "controlled by Deployment cart") {
+		t.Errorf("Expected a controlled ReplicaSet to be refused, got %v", err)
+	}
+}
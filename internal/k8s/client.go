@@ -3,35 +3,72 @@ package k8s
 import (
 	"fmt"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/cldmnky/pod-invaders/internal/tracing"
 )
 
-// GetKubeClient creates a Kubernetes client from either a kubeconfig file or in-cluster configuration.
-func GetKubeClient(kubeconfigPath string) (kubernetes.Interface, error) {
-	var config *rest.Config
-	var err error
+// Clients are the Kubernetes clients used to make and undo changes.
+type Clients struct {
+	Kube    kubernetes.Interface
+	Dynamic dynamic.Interface // For custom resources, may be nil when none are targeted
+}
 
+// GetKubeConfig loads the Kubernetes configuration from either a kubeconfig file or the cluster the server runs in.
+func GetKubeConfig(kubeconfigPath string) (*rest.Config, error) {
 	if kubeconfigPath != "" {
 		// Use the provided kubeconfig file
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+		config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
 		if err != nil {
 			return nil, fmt.Errorf("unable to load kubeconfig from %s: %w", kubeconfigPath, err)
 		}
-	} else {
-		// Use in-cluster configuration
-		config, err = rest.InClusterConfig()
-		if err != nil {
-			return nil, fmt.Errorf("unable to load in-cluster config: %w", err)
-		}
+		return config, nil
+	}
+	// Use in-cluster configuration
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load in-cluster config: %w", err)
+	}
+	return config, nil
+}
+
+// GetKubeClient creates a Kubernetes client from either a kubeconfig file or in-cluster configuration.
+func GetKubeClient(kubeconfigPath string) (kubernetes.Interface, error) {
+	config, err := GetKubeConfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create the clientset
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, err := NewClientForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create a Kubernetes client: %w", err)
 	}
 
 	return clientset, nil
 }
+
+// NewClientForConfig creates a Kubernetes client whose API requests are traced.
+func NewClientForConfig(config *rest.Config) (kubernetes.Interface, error) {
+	config = rest.CopyConfig(config)
+	config.Wrap(tracing.WrapTransport)
+	return kubernetes.NewForConfig(config)
+}
+
+// NewClientsForConfig creates a Kubernetes client and a dynamic client whose API requests are traced.
+func NewClientsForConfig(config *rest.Config) (Clients, error) {
+	kube, err := NewClientForConfig(config)
+	if err != nil {
+		return Clients{}, err
+	}
+	config = rest.CopyConfig(config)
+	config.Wrap(tracing.WrapTransport)
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return Clients{}, err
+	}
+	return Clients{Kube: kube, Dynamic: dyn}, nil
+}
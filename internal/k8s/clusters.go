@@ -0,0 +1,176 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
+)
+
+// clusterFilePrefix marks a cluster whose kubeconfig is a file of its own.
+const clusterFilePrefix = "file:"
+
+// ClusterSpec names a cluster of the fleet and says where its kubeconfig comes from.
+type ClusterSpec struct {
+	Name       string
+	Context    string // Context in the default kubeconfig, empty with Kubeconfig
+	Kubeconfig string // Kubeconfig file whose current context is used, e.g. one mounted from a Secret
+}
+
+// ParseClusterSpec parses a cluster given as name, for the context of that name in the
+// default kubeconfig, as name=context, or as name=file:path for the current context of
+// another kubeconfig file.
+func ParseClusterSpec(spec string) (ClusterSpec, error) {
+	name, source, hasSource := strings.Cut(spec, "=")
+	for _, msg := range validation.IsDNS1123Label(name) {
+		return ClusterSpec{}, fmt.Errorf("cluster name %q is invalid: %s", name, msg)
+	}
+	c := ClusterSpec{Name: name, Context: name}
+	switch {
+	case !hasSource:
+	case strings.HasPrefix(source, clusterFilePrefix):
+		c.Context = ""
+		c.Kubeconfig = strings.TrimPrefix(source, clusterFilePrefix)
+		if c.Kubeconfig == "" {
+			return ClusterSpec{}, fmt.Errorf("cluster %s: kubeconfig file must not be empty", name)
+		}
+	case source == "":
+		return ClusterSpec{}, fmt.Errorf("cluster %s: context must not be empty", name)
+	default:
+		c.Context = source
+	}
+	return c, nil
+}
+
+// RESTConfig loads the configuration of the cluster. defaultKubeconfig is the kubeconfig
+// contexts are looked up in; if empty, the usual KUBECONFIG rules apply.
+func (c ClusterSpec) RESTConfig(defaultKubeconfig string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = defaultKubeconfig
+	if c.Kubeconfig != "" {
+		rules.ExplicitPath = c.Kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: c.Context}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load kubeconfig of cluster %s: %w", c.Name, err)
+	}
+	return config, nil
+}
+
+// Fleet holds the clients of several named clusters that invaders are taken from together.
+type Fleet struct {
+	names    []string
+	clusters map[string]Clients
+}
+
+// NewFleet creates an empty fleet.
+func NewFleet() *Fleet {
+	return &Fleet{clusters: make(map[string]Clients)}
+}
+
+// LoadFleet creates the clients of every cluster in specs.
+func LoadFleet(specs []ClusterSpec, defaultKubeconfig string) (*Fleet, error) {
+	f := NewFleet()
+	for _, spec := range specs {
+		config, err := spec.RESTConfig(defaultKubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		clients, err := NewClientsForConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create clients for cluster %s: %w", spec.Name, err)
+		}
+		f.Add(spec.Name, clients)
+	}
+	return f, nil
+}
+
+// Add adds a cluster to the fleet, replacing any cluster of the same name.
+func (f *Fleet) Add(name string, clients Clients) {
+	if _, ok := f.clusters[name]; !ok {
+		f.names = append(f.names, name)
+	}
+	f.clusters[name] = clients
+}
+
+// Names returns the names of the clusters in the order they were added.
+func (f *Fleet) Names() []string {
+	return append([]string(nil), f.names...)
+}
+
+// Clients returns the clients of the named cluster.
+func (f *Fleet) Clients(name string) (Clients, bool) {
+	c, ok := f.clusters[name]
+	return c, ok
+}
+
+// GetFleetPods retrieves running pods from the namespaces of every cluster in the fleet,
+// each tagged with its cluster. Like GetPods, it tops the list up with fake pods, which
+// are spread over the clusters. Clusters that cannot be reached contribute no pods.
+func GetFleetPods(ctx context.Context, fleet *Fleet, count int, namespaces ...string) ([]game.Pod, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.GetFleetPods", trace.WithAttributes(
+		attribute.Int("pods.count", count),
+		attribute.StringSlice("k8s.namespaces", namespaces),
+		attribute.StringSlice("k8s.clusters", fleet.names),
+	))
+	defer span.End()
+
+	// Edge clusters may be slow to answer, so they are all asked at once
+	listed := make([][]game.Pod, len(fleet.names))
+	var wg sync.WaitGroup
+	for i, name := range fleet.names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clusterCtx := logging.WithLogger(ctx, logging.FromContext(ctx).With("cluster", name))
+			pods := listPods(clusterCtx, fleet.clusters[name].Kube, namespaces)
+			for j := range pods {
+				pods[j].Cluster = name
+			}
+			listed[i] = pods
+		}()
+	}
+	wg.Wait()
+
+	pods := make([]game.Pod, 0)
+	for _, p := range listed {
+		pods = append(pods, p...)
+	}
+	span.SetAttributes(attribute.Int("pods.real", min(len(pods), count)))
+
+	rand.Shuffle(len(pods), func(i, j int) {
+		pods[i], pods[j] = pods[j], pods[i]
+	})
+	pods = pods[:min(len(pods), count)]
+	annotators := make(map[string]*annotator)
+	for i := range pods {
+		a, ok := annotators[pods[i].Cluster]
+		if !ok {
+			a = newAnnotator(fleet.clusters[pods[i].Cluster].Kube)
+			annotators[pods[i].Cluster] = a
+		}
+		a.annotate(ctx, &pods[i])
+	}
+
+	realPods := len(pods)
+	pods = fillWithFakePods(ctx, pods, count)
+	for i := realPods; i < len(pods) && len(fleet.names) > 0; i++ {
+		pods[i].Cluster = fleet.names[rand.Intn(len(fleet.names))]
+	}
+	rand.Shuffle(len(pods), func(i, j int) {
+		pods[i], pods[j] = pods[j], pods[i]
+	})
+	return pods, nil
+}
@@ -0,0 +1,225 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
+)
+
+// nodeRolePrefix prefixes the labels naming node roles, e.g. node-role.kubernetes.io/worker.
+const nodeRolePrefix = "node-role.kubernetes.io/"
+
+// serviceAccountNamespaceFile holds the namespace of the service account of a pod.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// ownNamespace returns the namespace the server is installed in, from POD_NAMESPACE or
+// its service account, or "" outside a cluster. Tests replace it.
+var ownNamespace = sync.OnceValue(func() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	data, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+})
+
+// OwnNamespace returns the namespace the server is installed in, or "" outside a cluster.
+func OwnNamespace() string {
+	return ownNamespace()
+}
+
+// GetNodes returns up to count schedulable nodes, topped up with fake nodes.
+// Nodes that are already cordoned are left out.
+func GetNodes(ctx context.Context, client kubernetes.Interface, count int) (nodes []game.Node, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.GetNodes", trace.WithAttributes(
+		attribute.Int("nodes.count", count),
+	))
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	list, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	observe("list_nodes", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for i := range list.Items {
+		if !list.Items[i].Spec.Unschedulable {
+			nodes = append(nodes, NodeFromObject(&list.Items[i]))
+		}
+	}
+	rand.Shuffle(len(nodes), func(i, j int) {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	})
+	nodes = nodes[:min(len(nodes), count)]
+	span.SetAttributes(attribute.Int("nodes.real", len(nodes)))
+
+	if len(nodes) < count {
+		logging.FromContext(ctx).Debug("Not enough schedulable nodes, generating fake nodes", "real", len(nodes), "count", count)
+	}
+	for len(nodes) < count {
+		nodes = append(nodes, game.GenerateFakeNode())
+	}
+	rand.Shuffle(len(nodes), func(i, j int) {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	})
+	return nodes, nil
+}
+
+// NodeFromObject converts a Kubernetes node into a game node.
+func NodeFromObject(node *corev1.Node) game.Node {
+	n := game.Node{
+		Name:           node.Name,
+		IsReal:         true,
+		KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+	}
+	for label := range node.Labels {
+		if role, ok := strings.CutPrefix(label, nodeRolePrefix); ok && role != "" {
+			n.Roles = append(n.Roles, role)
+		}
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			n.Ready = cond.Status == corev1.ConditionTrue
+		}
+	}
+	return n
+}
+
+// ErrNodeCordoned is returned by CordonNode for a node that is cordoned already, e.g. by
+// an operator for maintenance, which uncordoning it later would undo.
+var ErrNodeCordoned = errors.New("node is already cordoned")
+
+// CordonNode marks a node unschedulable. It fails with ErrNodeCordoned if the node is
+// unschedulable already.
+func CordonNode(ctx context.Context, client kubernetes.Interface, name string) error {
+	start := time.Now()
+	node, err := client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	observe("get_node", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	if node.Spec.Unschedulable {
+		return fmt.Errorf("%w: %s", ErrNodeCordoned, name)
+	}
+	return setUnschedulable(ctx, client, name, true)
+}
+
+// UncordonNode marks a node schedulable again.
+func UncordonNode(ctx context.Context, client kubernetes.Interface, name string) error {
+	return setUnschedulable(ctx, client, name, false)
+}
+
+func setUnschedulable(ctx context.Context, client kubernetes.Interface, name string, unschedulable bool) (err error) {
+	op := "uncordon_node"
+	if unschedulable {
+		op = "cordon_node"
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.SetNodeUnschedulable", trace.WithAttributes(
+		attribute.String("k8s.node.name", name),
+		attribute.Bool("k8s.node.unschedulable", unschedulable),
+	))
+	defer func() { endSpan(span, err) }()
+
+	patch := fmt.Appendf(nil, `{"spec":{"unschedulable":%t}}`, unschedulable)
+	start := time.Now()
+	_, err = client.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	observe(op, start, err)
+	if err != nil {
+		return fmt.Errorf("failed to patch node %s: %w", name, err)
+	}
+	logging.FromContext(ctx).Info("Set node schedulability", "node", name, "unschedulable", unschedulable)
+	return nil
+}
+
+// DrainNode evicts the pods in namespaces of a node, which should be cordoned first,
+// through the eviction API so that PodDisruptionBudgets are respected. Pods a budget
+// protects are left running rather than waited for. DaemonSet and mirror pods are
+// skipped, as are pods in kube-system, in the namespace the server runs in and in
+// namespaces protected reports true for.
+func DrainNode(ctx context.Context, client kubernetes.Interface, name string, namespaces []string, protected func(namespace string) bool) (drain game.NodeDrain, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.DrainNode", trace.WithAttributes(
+		attribute.String("k8s.node.name", name),
+	))
+	defer func() { endSpan(span, err) }()
+
+	drain = game.NodeDrain{Node: name, Evicted: []string{}}
+	start := time.Now()
+	list, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	observe("list_pods", start, err)
+	if err != nil {
+		return drain, fmt.Errorf("failed to list pods on node %s: %w", name, err)
+	}
+
+	logger := logging.FromContext(ctx).With("node", name)
+	for i := range list.Items {
+		pod := &list.Items[i]
+		// The FieldSelector handles the node, but we double-check; finished pods hold no capacity
+		if pod.Spec.NodeName != name || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		id := pod.Namespace + "/" + pod.Name
+		if !evictable(pod, namespaces, protected) {
+			drain.Skipped = append(drain.Skipped, id)
+			continue
+		}
+
+		start := time.Now()
+		err := client.CoreV1().Pods(pod.Namespace).EvictV1(ctx, &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		})
+		observe("evict_pod", start, err)
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+			drain.Evicted = append(drain.Evicted, id)
+		case apierrors.IsTooManyRequests(err):
+			logger.Info("PodDisruptionBudget refused eviction, leaving pod running", "pod", id)
+			drain.Blocked = append(drain.Blocked, id)
+		default:
+			logger.Warn("Failed to evict pod", "pod", id, "error", err)
+			drain.Failed = append(drain.Failed, id)
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("drain.evicted", len(drain.Evicted)),
+		attribute.Int("drain.blocked", len(drain.Blocked)),
+	)
+	logger.Info("Drained node", "evicted", len(drain.Evicted), "blocked", len(drain.Blocked),
+		"skipped", len(drain.Skipped), "failed", len(drain.Failed))
+	return drain, nil
+}
+
+// evictable reports whether a drain may evict pod.
+func evictable(pod *corev1.Pod, namespaces []string, protected func(namespace string) bool) bool {
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == game.KindDaemonSet {
+		return false
+	}
+	if pod.Annotations[corev1.MirrorPodAnnotationKey] != "" {
+		return false
+	}
+	ns := pod.Namespace
+	return slices.Contains(namespaces, ns) && ns != metav1.NamespaceSystem && ns != ownNamespace() && !protected(ns)
+}
@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+)
+
+// nodePod returns a running pod on worker-1, controlled by a workload of kind.
+func nodePod(namespace, name, kind string) *corev1.Pod {
+	controller := true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: kind, Name: name + "-owner", Controller: &controller},
+			},
+		},
+		Spec:   corev1.PodSpec{NodeName: "worker-1"},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestDrainNode(t *testing.T) {
+	defer func(original func() string) { ownNamespace = original }(ownNamespace)
+	ownNamespace = func() string { return "pod-invaders" }
+
+	client := fake.NewSimpleClientset(
+		nodePod("default", "web-1", game.KindReplicaSet),
+		nodePod("default", "db-0", game.KindStatefulSet),
+		nodePod("default", "node-exporter-1", game.KindDaemonSet),
+		nodePod("kube-system", "coredns-1", game.KindReplicaSet),
+		nodePod("pod-invaders", "pod-invaders-1", game.KindReplicaSet),
+		nodePod("shop", "cart-1", game.KindReplicaSet),
+		nodePod("untargeted", "api-1", game.KindReplicaSet),
+	)
+	var evicted []string
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		evicted = append(evicted, eviction.Namespace+"/"+eviction.Name)
+		if eviction.Name == "db-0" {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10)
+		}
+		return false, nil, nil
+	})
+
+	// kube-system and the server's namespace are targeted, but must never be drained
+	namespaces := []string{"default", "kube-system", "pod-invaders", "shop"}
+	protected := func(ns string) bool { return ns == "shop" }
+	drain, err := DrainNode(context.Background(), client, "worker-1", namespaces, protected)
+	if err != nil {
+		t.Fatalf("DrainNode failed: %v", err)
+	}
+	slices.Sort(drain.Skipped)
+	want := game.NodeDrain{
+		Node:    "worker-1",
+		Evicted: []string{"default/web-1"},
+		Blocked: []string{"default/db-0"},
+		Skipped: []string{
+			"default/node-exporter-1",
+			"kube-system/coredns-1",
+			"pod-invaders/pod-invaders-1",
+			"shop/cart-1",
+			"untargeted/api-1",
+		},
+	}
+	if !reflect.DeepEqual(drain, want) {
+		t.Errorf("Unexpected drain:\n got %+v\nwant %+v", drain, want)
+	}
+	slices.Sort(evicted)
+	if want := []string{"default/db-0", "default/web-1"}; !reflect.DeepEqual(evicted, want) {
+		t.Errorf("Expected evictions of %v only, got %v", want, evicted)
+	}
+}
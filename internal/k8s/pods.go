@@ -3,95 +3,226 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"log"
 	"math/rand"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/cldmnky/pod-invaders/internal/game"
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/metrics"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
 )
 
+// observe records the latency and outcome of a Kubernetes API call.
+func observe(operation string, start time.Time, err error) {
+	metrics.KubeRequestDuration.WithLabelValues(operation, metrics.Result(err)).Observe(time.Since(start).Seconds())
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 // GetPods retrieves a list of running pods from the specified namespaces.
 // If not enough real pods are found, it supplements the list with fake pods.
-func GetPods(client kubernetes.Interface, count int, namespaces ...string) ([]game.Pod, error) {
-	pods := make([]game.Pod, 0, count)
+func GetPods(ctx context.Context, client kubernetes.Interface, count int, namespaces ...string) ([]game.Pod, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.GetPods", trace.WithAttributes(
+		attribute.Int("pods.count", count),
+		attribute.StringSlice("k8s.namespaces", namespaces),
+	))
+	defer span.End()
+
+	pods := listPods(ctx, client, namespaces)
+	span.SetAttributes(attribute.Int("pods.real", min(len(pods), count)))
+
+	// Pick the requested count at random and annotate only the pods that are used
+	rand.Shuffle(len(pods), func(i, j int) {
+		pods[i], pods[j] = pods[j], pods[i]
+	})
+	pods = pods[:min(len(pods), count)]
+	a := newAnnotator(client)
+	for i := range pods {
+		a.annotate(ctx, &pods[i])
+	}
+	if len(pods) == count {
+		return pods, nil
+	}
+
+	pods = fillWithFakePods(ctx, pods, count)
+
+	// Shuffle the final list
+	rand.Shuffle(len(pods), func(i, j int) {
+		pods[i], pods[j] = pods[j], pods[i]
+	})
 
+	return pods, nil
+}
+
+// listPods lists the running pods of the namespaces, searched in random order.
+func listPods(ctx context.Context, client kubernetes.Interface, namespaces []string) []game.Pod {
 	if len(namespaces) == 0 {
 		namespaces = []string{"default"}
 	}
+	// Shuffle a copy; the caller's slice may be shared between requests
+	namespaces = append([]string(nil), namespaces...)
 
 	// Shuffle namespaces to randomize the search order
 	rand.Shuffle(len(namespaces), func(i, j int) {
 		namespaces[i], namespaces[j] = namespaces[j], namespaces[i]
 	})
 
+	pods := make([]game.Pod, 0)
 	for _, ns := range namespaces {
-		if ns == "" {
-			continue
-		}
-
-		// Verify the namespace exists, matching original functionality.
-		_, err := client.CoreV1().Namespaces().Get(context.TODO(), ns, metav1.GetOptions{})
+		running, err := listRunningPods(ctx, client, ns)
 		if err != nil {
-			log.Printf("Namespace %s does not exist or could not be retrieved, skipping: %v", ns, err)
 			continue
 		}
-
-		log.Printf("Getting pods in namespace: %s", ns)
-		podList, err := client.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{
-			FieldSelector: "status.phase=Running",
-		})
-		if err != nil {
-			log.Printf("Failed to list pods in namespace %s: %v. Skipping.", ns, err)
-			continue
-		}
-
-		for _, pod := range podList.Items {
-			// The FieldSelector handles the 'Running' phase, but we double-check for a deletion timestamp.
-			if pod.DeletionTimestamp == nil {
-				pods = append(pods, game.Pod{
-					Name:      pod.Name,
-					Namespace: pod.Namespace,
-					IsRealPod: true,
-				})
-			}
+		for i := range running {
+			pods = append(pods, PodFromObject(&running[i]))
 		}
 	}
+	return pods
+}
 
-	// If we have enough pods, shuffle and return the requested count
+// fillWithFakePods appends fake pods until there are count pods.
+func fillWithFakePods(ctx context.Context, pods []game.Pod, count int) []game.Pod {
 	if len(pods) >= count {
-		rand.Shuffle(len(pods), func(i, j int) {
-			pods[i], pods[j] = pods[j], pods[i]
-		})
-		return pods[:count], nil
+		return pods
 	}
-
-	// Otherwise, fill the rest with fake pods
-	log.Printf("Found %d real pods, generating %d fake pods to reach count %d", len(pods), count-len(pods), count)
+	logging.FromContext(ctx).Debug("Not enough real pods, generating fake pods", "real", len(pods), "fake", count-len(pods), "count", count)
+	fakes := game.FakePodGeneratorFromContext(ctx)
 	for len(pods) < count {
-		pods = append(pods, game.GenerateFakePod())
+		pods = append(pods, fakes.Pod())
 	}
+	return pods
+}
 
-	// Shuffle the final list
-	rand.Shuffle(len(pods), func(i, j int) {
-		pods[i], pods[j] = pods[j], pods[i]
+// listRunningPods lists the running pods of a namespace that are not being deleted.
+// Namespaces that do not exist or cannot be listed are logged and return an error.
+func listRunningPods(ctx context.Context, client kubernetes.Interface, ns string) ([]corev1.Pod, error) {
+	logger := logging.FromContext(ctx)
+	if ns == "" {
+		return nil, fmt.Errorf("empty namespace")
+	}
+
+	// Verify the namespace exists, matching original functionality.
+	start := time.Now()
+	nsCtx, nsSpan := tracing.Tracer().Start(ctx, "k8s.GetNamespace", trace.WithAttributes(attribute.String("k8s.namespace.name", ns)))
+	_, err := client.CoreV1().Namespaces().Get(nsCtx, ns, metav1.GetOptions{})
+	endSpan(nsSpan, err)
+	observe("get_namespace", start, err)
+	if err != nil {
+		logger.Warn("Namespace does not exist or could not be retrieved, skipping", "namespace", ns, "error", err)
+		return nil, err
+	}
+
+	logger.Debug("Getting pods", "namespace", ns)
+	start = time.Now()
+	listCtx, listSpan := tracing.Tracer().Start(ctx, "k8s.ListPods", trace.WithAttributes(attribute.String("k8s.namespace.name", ns)))
+	podList, err := client.CoreV1().Pods(ns).List(listCtx, metav1.ListOptions{
+		FieldSelector: "status.phase=Running",
 	})
+	endSpan(listSpan, err)
+	observe("list_pods", start, err)
+	if err != nil {
+		logger.Warn("Failed to list pods, skipping", "namespace", ns, "error", err)
+		return nil, err
+	}
 
-	return pods, nil
+	running := podList.Items[:0]
+	for _, pod := range podList.Items {
+		// The FieldSelector handles the 'Running' phase, but we double-check for a deletion timestamp.
+		if pod.DeletionTimestamp == nil {
+			running = append(running, pod)
+		}
+	}
+	return running, nil
+}
+
+// GetPod retrieves a single pod with its metadata.
+func GetPod(ctx context.Context, client kubernetes.Interface, namespace, name string) (pod game.Pod, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.GetPod", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", namespace),
+		attribute.String("k8s.pod.name", name),
+	))
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	obj, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	observe("get_pod", start, err)
+	if err != nil {
+		return game.Pod{}, err
+	}
+	pod = PodFromObject(obj)
+	newAnnotator(client).annotate(ctx, &pod)
+	return pod, nil
+}
+
+// PodFromObject converts a Kubernetes pod into a game pod carrying its metadata.
+func PodFromObject(pod *corev1.Pod) game.Pod {
+	p := game.Pod{
+		Name:          pod.Name,
+		Namespace:     pod.Namespace,
+		IsRealPod:     true,
+		UID:           string(pod.UID),
+		Node:          pod.Spec.NodeName,
+		Phase:         string(pod.Status.Phase),
+		CreatedAt:     pod.CreationTimestamp.Time,
+		Labels:        pod.Labels,
+		QOSClass:      string(pod.Status.QOSClass),
+		PriorityClass: pod.Spec.PriorityClassName,
+	}
+	if pod.Spec.Priority != nil {
+		p.Priority = *pod.Spec.Priority
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		p.OwnerKind = owner.Kind
+		p.OwnerName = owner.Name
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			p.Ready = cond.Status == corev1.ConditionTrue
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		p.RestartCount += status.RestartCount
+	}
+	for _, container := range pod.Spec.Containers {
+		p.Images = append(p.Images, container.Image)
+	}
+	return p
 }
 
 // KillPod deletes a real Kubernetes pod. It returns an error for fake pods.
-func KillPod(client kubernetes.Interface, pod game.Pod) error {
+func KillPod(ctx context.Context, client kubernetes.Interface, pod game.Pod) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.KillPod", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", pod.Namespace),
+		attribute.String("k8s.pod.name", pod.Name),
+	))
+	defer func() { endSpan(span, err) }()
+
+	logger := logging.FromContext(ctx).With("namespace", pod.Namespace, "pod", pod.Name)
 	if !pod.IsRealPod {
 		return fmt.Errorf("cannot kill fake pod: %s/%s", pod.Namespace, pod.Name)
 	}
 
-	log.Printf("Attempting to delete pod %s/%s", pod.Namespace, pod.Name)
-	err := client.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{})
+	logger.Info("Deleting pod")
+	start := time.Now()
+	err = client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+	observe("delete_pod", start, err)
 	if err != nil {
 		return fmt.Errorf("failed to delete pod %s/%s: %w", pod.Namespace, pod.Name, err)
 	}
-	log.Printf("Successfully deleted pod %s/%s", pod.Namespace, pod.Name)
+	logger.Info("Deleted pod")
 	return nil
 }
@@ -0,0 +1,368 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
+)
+
+// What a kill does to the objects of a resource target
+const (
+	ResourceActionDelete = "delete" // Delete the object
+	ResourceActionScale  = "scale"  // Scale the object to zero through its scale subresource for a while
+	ResourceActionPatch  = "patch"  // Apply a JSON merge patch for a while
+)
+
+// Undo operations for resource targets
+const (
+	UndoResourceScale = "resource-scale" // Scale the object back to its previous replicas
+	UndoResourcePatch = "resource-patch" // Apply the merge patch restoring the fields a patch changed
+)
+
+// ResourceTarget is a resource type whose objects become invaders in resource targeting,
+// and what a kill does to them.
+type ResourceTarget struct {
+	GVR    schema.GroupVersionResource
+	Action string
+	Patch  []byte // JSON merge patch applied by the patch action
+}
+
+// ParseResourceTarget parses a target given as group/version/resource=action, e.g.
+// argoproj.io/v1alpha1/rollouts=scale. Core resources have no group, as in
+// v1/configmaps=delete. The patch action is followed by a JSON merge patch, e.g.
+// kubevirt.io/v1/virtualmachines=patch:{"spec":{"running":false}}.
+func ParseResourceTarget(spec string) (ResourceTarget, error) {
+	resource, action, ok := strings.Cut(spec, "=")
+	if !ok {
+		return ResourceTarget{}, fmt.Errorf("%q must be group/version/resource=action", spec)
+	}
+	gvr, err := parseGVR(resource)
+	if err != nil {
+		return ResourceTarget{}, err
+	}
+	t := ResourceTarget{GVR: gvr, Action: action}
+	if action, patch, ok := strings.Cut(action, ":"); ok && action == ResourceActionPatch {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(patch), &fields); err != nil || len(fields) == 0 {
+			return ResourceTarget{}, fmt.Errorf("%q: patch must be a non-empty JSON object", spec)
+		}
+		t.Action, t.Patch = action, []byte(patch)
+	}
+	switch t.Action {
+	case ResourceActionDelete, ResourceActionScale:
+	case ResourceActionPatch:
+		if t.Patch == nil {
+			return ResourceTarget{}, fmt.Errorf("%q: patch must be followed by :{json merge patch}", spec)
+		}
+	default:
+		return ResourceTarget{}, fmt.Errorf("%q: unknown action %q: must be %s, %s or %s", spec, t.Action,
+			ResourceActionDelete, ResourceActionScale, ResourceActionPatch)
+	}
+	return t, nil
+}
+
+// parseGVR parses group/version/resource, or version/resource for core resources.
+func parseGVR(s string) (schema.GroupVersionResource, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) == 2 {
+		parts = append([]string{""}, parts...)
+	}
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return schema.GroupVersionResource{}, fmt.Errorf("%q must be group/version/resource or version/resource", s)
+	}
+	return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}, nil
+}
+
+// formatGVR formats a resource as group/version/resource, the inverse of parseGVR.
+func formatGVR(gvr schema.GroupVersionResource) string {
+	return strings.TrimPrefix(gvr.Group+"/"+gvr.Version+"/"+gvr.Resource, "/")
+}
+
+// String returns the target's resource as group/version/resource.
+func (t ResourceTarget) String() string {
+	return formatGVR(t.GVR)
+}
+
+// discoverKind checks through discovery that the target's resource is served, namespaced
+// and supports the target's action, and returns its kind.
+func discoverKind(disc discovery.DiscoveryInterface, t ResourceTarget) (string, error) {
+	start := time.Now()
+	list, err := disc.ServerResourcesForGroupVersion(t.GVR.GroupVersion().String())
+	observe("discover_resources", start, err)
+	if err != nil {
+		return "", fmt.Errorf("failed to discover %s: %w", t.GVR.GroupVersion(), err)
+	}
+
+	var found *metav1.APIResource
+	scalable := false
+	for i, r := range list.APIResources {
+		switch r.Name {
+		case t.GVR.Resource:
+			found = &list.APIResources[i]
+		case t.GVR.Resource + "/scale":
+			scalable = true
+		}
+	}
+	switch {
+	case found == nil:
+		return "", fmt.Errorf("resource %s is not served", t)
+	case !found.Namespaced:
+		return "", fmt.Errorf("resource %s is not namespaced", t)
+	case !slices.Contains(found.Verbs, "list"):
+		return "", fmt.Errorf("resource %s cannot be listed", t)
+	case t.Action == ResourceActionScale && !scalable:
+		return "", fmt.Errorf("resource %s has no scale subresource", t)
+	case t.Action != ResourceActionScale && !slices.Contains(found.Verbs, t.Action):
+		return "", fmt.Errorf("resource %s does not support %s", t, t.Action)
+	}
+	return found.Kind, nil
+}
+
+// GetResources returns up to count objects of the targeted resources in the given
+// namespaces, topped up with fake objects. Targets discovery does not confirm are skipped.
+func GetResources(ctx context.Context, clients Clients, targets []ResourceTarget, count int, namespaces ...string) (resources []game.Resource, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.GetResources", trace.WithAttributes(
+		attribute.Int("resources.count", count),
+		attribute.StringSlice("k8s.namespaces", namespaces),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if clients.Dynamic == nil {
+		return nil, fmt.Errorf("no dynamic client to list resources with")
+	}
+	logger := logging.FromContext(ctx)
+	var fakes []game.Resource // One template per usable target
+	for _, t := range targets {
+		kind, err := discoverKind(clients.Kube.Discovery(), t)
+		if err != nil {
+			logger.Warn("Skipping resource target", "resource", t.String(), "error", err)
+			continue
+		}
+		template := game.Resource{Group: t.GVR.Group, Version: t.GVR.Version, Resource: t.GVR.Resource, Kind: kind, Action: t.Action}
+		fakes = append(fakes, template)
+		for _, ns := range namespaces {
+			start := time.Now()
+			list, err := clients.Dynamic.Resource(t.GVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+			observe("list_resources", start, err)
+			if err != nil {
+				logger.Warn("Failed to list resources", "resource", t.String(), "namespace", ns, "error", err)
+				continue
+			}
+			for i := range list.Items {
+				resources = append(resources, resourceFromObject(template, &list.Items[i]))
+			}
+		}
+	}
+	rand.Shuffle(len(resources), func(i, j int) {
+		resources[i], resources[j] = resources[j], resources[i]
+	})
+	resources = resources[:min(len(resources), count)]
+	span.SetAttributes(attribute.Int("resources.real", len(resources)))
+
+	if len(fakes) == 0 {
+		for _, t := range targets {
+			fakes = append(fakes, game.Resource{Group: t.GVR.Group, Version: t.GVR.Version, Resource: t.GVR.Resource, Kind: t.GVR.Resource, Action: t.Action})
+		}
+	}
+	for len(resources) < count && len(fakes) > 0 {
+		f := fakes[rand.Intn(len(fakes))]
+		resources = append(resources, game.GenerateFakeResource(f.Group, f.Version, f.Resource, f.Kind, f.Action))
+	}
+	rand.Shuffle(len(resources), func(i, j int) {
+		resources[i], resources[j] = resources[j], resources[i]
+	})
+	return resources, nil
+}
+
+// resourceFromObject converts an object listed for a target into a game resource.
+func resourceFromObject(template game.Resource, obj *unstructured.Unstructured) game.Resource {
+	r := template
+	r.Namespace = obj.GetNamespace()
+	r.Name = obj.GetName()
+	r.IsReal = true
+	if replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas"); found && err == nil {
+		r.Replicas = &replicas
+	}
+	return r
+}
+
+// ApplyResourceAction performs a target's action on one of its objects, tracing it as
+// k8s.ResourceAction. It returns the temporary change it made, if any.
+func ApplyResourceAction(ctx context.Context, clients Clients, t ResourceTarget, namespace, name string) (change *Change, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.ResourceAction", trace.WithAttributes(
+		attribute.String("k8s.resource", t.String()),
+		attribute.String("k8s.action", t.Action),
+		attribute.String("k8s.namespace.name", namespace),
+		attribute.String("k8s.object.name", name),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if clients.Dynamic == nil {
+		return nil, fmt.Errorf("no dynamic client to change resources with")
+	}
+	objects := clients.Dynamic.Resource(t.GVR).Namespace(namespace)
+	id := fmt.Sprintf("%s %s/%s", t, namespace, name)
+	logger := logging.FromContext(ctx).With("resource", t.String(), "namespace", namespace, "name", name)
+	undo := Undo{Namespace: namespace, Name: name, Args: map[string]string{"resource": t.String()}}
+
+	switch t.Action {
+	case ResourceActionDelete:
+		start := time.Now()
+		err := objects.Delete(ctx, name, metav1.DeleteOptions{})
+		observe("delete_resource", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", id, err)
+		}
+		logger.Info("Deleted resource")
+		return nil, nil
+
+	case ResourceActionScale:
+		start := time.Now()
+		scale, err := objects.Get(ctx, name, metav1.GetOptions{}, "scale")
+		observe("get_scale", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get scale of %s: %w", id, err)
+		}
+		// The count is restored on rollback, so a guess would leave the object at the wrong scale
+		replicas, found, err := unstructured.NestedInt64(scale.Object, "spec", "replicas")
+		if err != nil {
+			return nil, fmt.Errorf("invalid replicas in scale of %s: %w", id, err)
+		}
+		if !found {
+			return nil, fmt.Errorf("scale of %s has no replica count", id)
+		}
+		if err := patchScale(ctx, objects, name, id, 0); err != nil {
+			return nil, err
+		}
+		logger.Info("Scaled resource to zero", "replicas", replicas)
+		undo.Op = UndoResourceScale
+		undo.Args["replicas"] = strconv.FormatInt(replicas, 10)
+
+	case ResourceActionPatch:
+		start := time.Now()
+		obj, err := objects.Get(ctx, name, metav1.GetOptions{})
+		observe("get_resource", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", id, err)
+		}
+		var patch map[string]any
+		if err := json.Unmarshal(t.Patch, &patch); err != nil {
+			return nil, fmt.Errorf("invalid patch for %s: %w", t, err)
+		}
+		inverse, err := json.Marshal(inverseMergePatch(obj.Object, patch))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal inverse patch for %s: %w", id, err)
+		}
+		if err := patchResource(ctx, objects, name, id, t.Patch); err != nil {
+			return nil, err
+		}
+		logger.Info("Patched resource")
+		undo.Op = UndoResourcePatch
+		undo.Args["patch"] = string(inverse)
+
+	default:
+		return nil, fmt.Errorf("unknown action %q for %s", t.Action, t)
+	}
+	return &Change{Key: t.String() + "/" + namespace + "/" + name, Undo: undo}, nil
+}
+
+// inverseMergePatch returns the JSON merge patch undoing patch on obj: every field the
+// patch sets is restored to its value in obj, or removed if obj lacks it.
+func inverseMergePatch(obj, patch map[string]any) map[string]any {
+	inverse := make(map[string]any, len(patch))
+	for key, value := range patch {
+		old, ok := obj[key]
+		if !ok {
+			inverse[key] = nil
+			continue
+		}
+		oldFields, oldIsObject := old.(map[string]any)
+		fields, isObject := value.(map[string]any)
+		if oldIsObject && isObject {
+			inverse[key] = inverseMergePatch(oldFields, fields)
+		} else {
+			inverse[key] = old
+		}
+	}
+	return inverse
+}
+
+// patchScale sets the replicas of an object through its scale subresource.
+func patchScale(ctx context.Context, objects dynamic.ResourceInterface, name, id string, replicas int64) error {
+	patch := fmt.Appendf(nil, `{"spec":{"replicas":%d}}`, replicas)
+	start := time.Now()
+	_, err := objects.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}, "scale")
+	observe("patch_scale", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to scale %s to %d: %w", id, replicas, err)
+	}
+	return nil
+}
+
+// patchResource applies a JSON merge patch to an object.
+func patchResource(ctx context.Context, objects dynamic.ResourceInterface, name, id string, patch []byte) error {
+	start := time.Now()
+	_, err := objects.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	observe("patch_resource", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to patch %s: %w", id, err)
+	}
+	return nil
+}
+
+// undoObjects returns the objects of the resource an undo operation applies to.
+func undoObjects(c Clients, u Undo) (dynamic.ResourceInterface, string, error) {
+	gvr, err := parseGVR(u.Args["resource"])
+	if err != nil {
+		return nil, "", err
+	}
+	if c.Dynamic == nil {
+		return nil, "", fmt.Errorf("no dynamic client to undo changes to %s with", u.Args["resource"])
+	}
+	return c.Dynamic.Resource(gvr).Namespace(u.Namespace), fmt.Sprintf("%s %s/%s", u.Args["resource"], u.Namespace, u.Name), nil
+}
+
+func undoResourceScale(ctx context.Context, c Clients, u Undo) error {
+	replicas, err := strconv.ParseInt(u.Args["replicas"], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid replicas %q: %w", u.Args["replicas"], err)
+	}
+	objects, id, err := undoObjects(c, u)
+	if err != nil {
+		return err
+	}
+	if err := patchScale(ctx, objects, u.Name, id, replicas); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func undoResourcePatch(ctx context.Context, c Clients, u Undo) error {
+	objects, id, err := undoObjects(c, u)
+	if err != nil {
+		return err
+	}
+	if err := patchResource(ctx, objects, u.Name, id, []byte(u.Args["patch"])); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestApplyResourceActionScale(t *testing.T) {
+	ctx := context.Background()
+	target, err := ParseResourceTarget("argoproj.io/v1alpha1/rollouts=scale")
+	if err != nil {
+		t.Fatalf("ParseResourceTarget failed: %v", err)
+	}
+	rollout := func(name string, spec map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Rollout",
+			"metadata":   map[string]any{"name": name, "namespace": "shop"},
+			"spec":       spec,
+		}}
+	}
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		rollout("checkout", map[string]any{"replicas": int64(4)}),
+		rollout("unscaled", map[string]any{}),
+		rollout("malformed", map[string]any{"replicas": "four"}),
+	)
+	clients := Clients{Dynamic: dyn}
+
+	change, err := ApplyResourceAction(ctx, clients, target, "shop", "checkout")
+	if err != nil {
+		t.Fatalf("ApplyResourceAction failed: %v", err)
+	}
+	if change.Undo.Op != UndoResourceScale || change.Undo.Args["replicas"] != "4" {
+		t.Errorf("Expected 4 replicas to be recorded, got %+v", change.Undo)
+	}
+
+	// Without a readable count, the rollback would scale the object to zero for good
+	tests := []struct {
+		name, want string
+	}{
+		{name: "unscaled", want: "no replica count"},
+		{name: "malformed", want: "invalid replicas"},
+	}
+	for _, tt := range tests {
+		dyn.ClearActions()
+		if _, err := ApplyResourceAction(ctx, clients, target, "shop", tt.name); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error mentioning %q, got %v", tt.name, tt.want, err)
+		}
+		for _, a := range dyn.Actions() {
+			if a.GetVerb() == "patch" {
+				t.Errorf("%s: expected nothing to be scaled, got a patch of %s", tt.name, a.GetResource().Resource)
+			}
+		}
+	}
+}
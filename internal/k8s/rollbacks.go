@@ -0,0 +1,304 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/metrics"
+)
+
+const (
+	// rollbackTimeout bounds a rollback, which runs outside of any request.
+	rollbackTimeout = 30 * time.Second
+	// rollbackRetryDelay is how long a failed rollback waits before it is retried.
+	rollbackRetryDelay = time.Minute
+)
+
+// Built-in undo operations
+const (
+	UndoUncordon    = "uncordon"    // Uncordon the node Name
+	UndoUnpartition = "unpartition" // Delete the partition NetworkPolicy of the pod Name
+	UndoScale       = "scale"       // Scale a workload back to its previous replicas
+)
+
+// Undo describes how to undo a change. Unlike a closure it can be persisted, so that the
+// change is still undone if the server restarts before its time is up.
+type Undo struct {
+	Op        string            `json:"op"`                // Registered undo operation, e.g. uncordon
+	Cluster   string            `json:"cluster,omitempty"` // Cluster of the fleet the change was made in
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name"`
+	Args      map[string]string `json:"args,omitempty"` // Operation specific, e.g. the replicas to restore
+}
+
+// UndoFunc performs an undo operation. It should succeed if there is nothing left to
+// undo, e.g. because the changed object was deleted meanwhile.
+type UndoFunc func(ctx context.Context, c Clients, u Undo) error
+
+var (
+	undoMu sync.RWMutex
+	undos  = map[string]UndoFunc{
+		UndoUncordon:      undoUncordon,
+		UndoUnpartition:   undoUnpartition,
+		UndoScale:         undoScale,
+		UndoResourceScale: undoResourceScale,
+		UndoResourcePatch: undoResourcePatch,
+	}
+)
+
+// RegisterUndo makes an undo operation available under op, replacing any operation
+// registered under the same name. Register custom operations before restoring
+// rollbacks that use them.
+func RegisterUndo(op string, fn UndoFunc) {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+	undos[op] = fn
+}
+
+// Apply performs the undo operation.
+func (u Undo) Apply(ctx context.Context, c Clients) error {
+	undoMu.RLock()
+	fn, ok := undos[u.Op]
+	undoMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w %q", errUnknownUndo, u.Op)
+	}
+	return fn(ctx, c, u)
+}
+
+func undoUncordon(ctx context.Context, c Clients, u Undo) error {
+	if err := UncordonNode(ctx, c.Kube, u.Name); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func undoUnpartition(ctx context.Context, c Clients, u Undo) error {
+	policy := u.Args["policy"]
+	start := time.Now()
+	err := c.Kube.NetworkingV1().NetworkPolicies(u.Namespace).Delete(ctx, policy, metav1.DeleteOptions{})
+	observe("delete_network_policy", start, err)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete NetworkPolicy %s/%s: %w", u.Namespace, policy, err)
+	}
+	// The pod may be gone already, taking its label with it
+	if err := patchPodLabel(ctx, c.Kube, u.Namespace, u.Name, "null"); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func undoScale(ctx context.Context, c Clients, u Undo) error {
+	replicas, err := strconv.ParseInt(u.Args["replicas"], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid replicas %q: %w", u.Args["replicas"], err)
+	}
+	w := workloadKey{kind: u.Args["kind"], namespace: u.Namespace, name: u.Name}
+	if err := scaleWorkload(ctx, c.Kube, w, int32(replicas)); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Rollbacks undoes temporary changes, such as a cordoned node or a workload scaled to
+// zero, once their time is up. Pending rollbacks are kept in a RollbackStore until they
+// succeed, so that Restore can pick them up after a restart.
+type Rollbacks struct {
+	mu      sync.Mutex
+	store   RollbackStore
+	pending map[string]*pendingRollback
+	closed  bool
+	running sync.WaitGroup // Rollbacks run by their timer
+}
+
+// pendingRollback is a rollback waiting for its timer.
+type pendingRollback struct {
+	timer   *time.Timer
+	clients Clients
+	record  RollbackRecord
+}
+
+// NewRollbacks creates a Rollbacks with nothing scheduled that persists rollbacks in store.
+func NewRollbacks(store RollbackStore) *Rollbacks {
+	return &Rollbacks{store: store, pending: make(map[string]*pendingRollback)}
+}
+
+// Schedule undoes a change with clients after d and returns when that will be. key
+// identifies the change; if a rollback is already pending for it, that one is kept,
+// since it restores the state from before the first change, and only its time is moved.
+func (r *Rollbacks) Schedule(ctx context.Context, clients Clients, key string, d time.Duration, undo Undo) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := RollbackRecord{Key: key, Undo: undo, Due: time.Now().Add(d)}
+	if p, ok := r.pending[key]; ok {
+		p.timer.Stop()
+		rec.Undo, clients = p.record.Undo, p.clients
+	}
+	if err := r.store.Save(rec); err != nil {
+		logging.FromContext(ctx).Error("Failed to persist rollback, it is lost if the server restarts", "change", key, "error", err)
+	}
+	r.schedule(clients, rec)
+	return rec.Due
+}
+
+// schedule starts the timer of a rollback. r.mu must be held.
+func (r *Rollbacks) schedule(clients Clients, rec RollbackRecord) {
+	p := &pendingRollback{clients: clients, record: rec}
+	p.timer = time.AfterFunc(time.Until(rec.Due), func() { r.fire(p) })
+	r.pending[rec.Key] = p
+	metrics.PendingRollbacks.Set(float64(len(r.pending)))
+}
+
+// fire runs a rollback whose time is up, retrying it later if it fails.
+func (r *Rollbacks) fire(p *pendingRollback) {
+	key := p.record.Key
+	r.mu.Lock()
+	if r.pending[key] != p {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.pending, key)
+	metrics.PendingRollbacks.Set(float64(len(r.pending)))
+	r.running.Add(1)
+	r.mu.Unlock()
+	defer r.running.Done()
+
+	err := run(context.Background(), p)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch _, rescheduled := r.pending[key]; {
+	case rescheduled:
+		// Scheduled again while running; the new record stays
+	case err == nil:
+		r.forget(key)
+	case r.closed || errors.Is(err, errUnknownUndo):
+		// Left persisted for the next start
+	default:
+		p.record.Due = time.Now().Add(rollbackRetryDelay)
+		r.schedule(p.clients, p.record)
+	}
+}
+
+// forget removes the record of a rollback that is done.
+func (r *Rollbacks) forget(key string) {
+	if err := r.store.Delete(key); err != nil {
+		slog.Warn("Failed to forget rollback, it runs again after a restart", "change", key, "error", err)
+	}
+}
+
+// errUnknownUndo marks rollbacks that cannot run until their operation is registered.
+var errUnknownUndo = errors.New("unknown undo operation")
+
+// run undoes a change before ctx is done.
+func run(ctx context.Context, p *pendingRollback) error {
+	ctx, cancel := context.WithTimeout(ctx, rollbackTimeout)
+	defer cancel()
+	logger := logging.FromContext(ctx).With("change", p.record.Key, "op", p.record.Undo.Op)
+
+	err := p.record.Undo.Apply(ctx, p.clients)
+	metrics.Rollbacks.WithLabelValues(p.record.Undo.Op, metrics.Result(err)).Inc()
+	if err != nil {
+		logger.Error("Rollback failed", "error", err)
+		return err
+	}
+	logger.Info("Rolled back")
+	return nil
+}
+
+// Pending reports whether a rollback is scheduled for key.
+func (r *Rollbacks) Pending(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.pending[key]
+	return ok
+}
+
+// Restore schedules the rollbacks persisted by an earlier run with clients, or in fleet
+// mode with the clients of the cluster each change was made in. Rollbacks that are overdue
+// run right away; those of clusters that are not configured stay persisted. It returns
+// the number of rollbacks restored.
+func (r *Rollbacks) Restore(ctx context.Context, clients Clients, fleet *Fleet) (int, error) {
+	records, err := r.store.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list rollbacks: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	restored := 0
+	for _, rec := range records {
+		if _, ok := r.pending[rec.Key]; ok {
+			continue
+		}
+		c, ok := clients, rec.Undo.Cluster == ""
+		if fleet != nil {
+			c, ok = fleet.Clients(rec.Undo.Cluster)
+		}
+		if !ok {
+			logging.FromContext(ctx).Warn("Not restoring rollback of unknown cluster", "change", rec.Key, "cluster", rec.Undo.Cluster)
+			continue
+		}
+		logging.FromContext(ctx).Info("Restoring rollback", "change", rec.Key, "op", rec.Undo.Op, "due", rec.Due)
+		r.schedule(c, rec)
+		restored++
+	}
+	return restored, nil
+}
+
+// Close runs the pending rollbacks right away and all at once, so that no change outlives
+// the server, giving up on those not done when ctx is. Rollbacks that fail or do not run
+// in time stay persisted for the next start.
+func (r *Rollbacks) Close(ctx context.Context) {
+	r.mu.Lock()
+	r.closed = true
+	pending := r.pending
+	r.pending = make(map[string]*pendingRollback)
+	metrics.PendingRollbacks.Set(0)
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for key, p := range pending {
+		p.timer.Stop()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger := logging.FromContext(ctx).With("change", key, "op", p.record.Undo.Op)
+			if ctx.Err() != nil {
+				logger.Warn("Rollback did not run before shutdown, it runs after the next start")
+				return
+			}
+			err := run(ctx, p)
+			switch {
+			case err == nil:
+				r.forget(key)
+			case ctx.Err() != nil:
+				logger.Warn("Rollback did not finish before shutdown, it runs after the next start")
+			}
+		}()
+	}
+	wg.Wait()
+	r.running.Wait()
+}
+
+// RollbackRecord is the persisted form of a pending rollback.
+type RollbackRecord struct {
+	Key  string    `json:"key"`
+	Undo Undo      `json:"undo"`
+	Due  time.Time `json:"due"`
+}
+
+// sortRollbacks orders records by due time.
+func sortRollbacks(records []RollbackRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Due.Before(records[j].Due)
+	})
+}
@@ -0,0 +1,211 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// cordoned returns whether the node is unschedulable.
+func cordoned(t *testing.T, clients Clients, name string) bool {
+	t.Helper()
+	node, err := clients.Kube.CoreV1().Nodes().Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get node %s: %v", name, err)
+	}
+	return node.Spec.Unschedulable
+}
+
+// keys returns the keys of the stored rollbacks.
+func keys(t *testing.T, store RollbackStore) []string {
+	t.Helper()
+	records, err := store.List()
+	if err != nil {
+		t.Fatalf("Failed to list rollbacks: %v", err)
+	}
+	var keys []string
+	for _, rec := range records {
+		keys = append(keys, rec.Key)
+	}
+	return keys
+}
+
+func TestRollbacksRestoreAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	clients := Clients{Kube: fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-2"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+	)}
+	store := NewMemoryRollbackStore()
+
+	// The first run schedules a rollback and crashes before it is due
+	crashed := NewRollbacks(store)
+	crashed.Schedule(ctx, clients, "node/worker-1", time.Hour, Undo{Op: UndoUncordon, Name: "worker-1"})
+	defer func() {
+		crashed.mu.Lock()
+		defer crashed.mu.Unlock()
+		for _, p := range crashed.pending {
+			p.timer.Stop()
+		}
+	}()
+	// A rollback that fell due while the server was down
+	if err := store.Save(RollbackRecord{Key: "node/worker-2", Undo: Undo{Op: UndoUncordon, Name: "worker-2"}, Due: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("Failed to save rollback: %v", err)
+	}
+
+	restarted := NewRollbacks(store)
+	restored, err := restarted.Restore(ctx, clients, nil)
+	if err != nil || restored != 2 {
+		t.Fatalf("Expected 2 rollbacks restored, got %d (%v)", restored, err)
+	}
+	if !restarted.Pending("node/worker-1") {
+		t.Error("Expected the rollback that is not due yet to wait")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for cordoned(t, clients, "worker-2") {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected an overdue rollback to run right away")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !cordoned(t, clients, "worker-1") {
+		t.Error("Expected worker-1 to stay cordoned until its rollback is due")
+	}
+
+	restarted.Close(ctx)
+	if cordoned(t, clients, "worker-1") {
+		t.Error("Expected Close to run the restored rollback")
+	}
+	if left := keys(t, store); len(left) != 0 {
+		t.Errorf("Expected no rollbacks left once done, got %v", left)
+	}
+}
+
+func TestRollbacksScheduleRepeatedKey(t *testing.T) {
+	ctx := context.Background()
+	clients := Clients{Kube: fake.NewSimpleClientset()}
+	store := NewMemoryRollbackStore()
+	rollbacks := NewRollbacks(store)
+	defer rollbacks.Close(ctx)
+
+	undo := func(replicas string) Undo {
+		return Undo{Op: UndoScale, Namespace: "shop", Name: "cart", Args: map[string]string{"kind": "Deployment", "replicas": replicas}}
+	}
+	first := rollbacks.Schedule(ctx, clients, "Deployment/shop/cart", time.Minute, undo("3"))
+	// Killing another pod of the workload scaled to zero finds no replicas to restore
+	second := rollbacks.Schedule(ctx, clients, "Deployment/shop/cart", time.Hour, undo("0"))
+	if !second.After(first) {
+		t.Errorf("Expected the rollback to be postponed, got %v and then %v", first, second)
+	}
+
+	records, err := store.List()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Expected one stored rollback, got %v (%v)", records, err)
+	}
+	if got := records[0].Undo.Args["replicas"]; got != "3" {
+		t.Errorf("Expected the first undo to be kept, restoring 3 replicas, got %s", got)
+	}
+	if !records[0].Due.Equal(second) {
+		t.Errorf("Expected the stored rollback to be due at %v, got %v", second, records[0].Due)
+	}
+}
+
+func TestRollbacksRestoreSkipsUnknownClusters(t *testing.T) {
+	ctx := context.Background()
+	gvr := "kubevirt.io/v1/virtualmachines"
+	vm := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "kubevirt.io/v1",
+		"kind":       "VirtualMachine",
+		"metadata":   map[string]any{"name": "db", "namespace": "shop"},
+		"spec":       map[string]any{"running": false},
+	}}
+	edge := Clients{Kube: fake.NewSimpleClientset(), Dynamic: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), vm)}
+	fleet := NewFleet()
+	fleet.Add("edge", edge)
+
+	store := NewMemoryRollbackStore()
+	due := time.Now().Add(time.Hour)
+	for _, rec := range []RollbackRecord{
+		{Key: "edge/" + gvr + "/shop/db", Due: due, Undo: Undo{
+			Op: UndoResourcePatch, Cluster: "edge", Namespace: "shop", Name: "db",
+			Args: map[string]string{"resource": gvr, "patch": `{"spec":{"running":true}}`},
+		}},
+		{Key: "gone/node/worker-1", Due: due, Undo: Undo{Op: UndoUncordon, Cluster: "gone", Name: "worker-1"}},
+	} {
+		if err := store.Save(rec); err != nil {
+			t.Fatalf("Failed to save rollback: %v", err)
+		}
+	}
+
+	rollbacks := NewRollbacks(store)
+	restored, err := rollbacks.Restore(ctx, Clients{}, fleet)
+	if err != nil || restored != 1 {
+		t.Fatalf("Expected 1 rollback restored, got %d (%v)", restored, err)
+	}
+	if rollbacks.Pending("gone/node/worker-1") {
+		t.Error("Expected the rollback of an unknown cluster not to be scheduled")
+	}
+	rollbacks.Close(ctx)
+
+	obj, err := edge.Dynamic.Resource(mustParseGVR(t, gvr)).Namespace("shop").Get(ctx, "db", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get VirtualMachine: %v", err)
+	}
+	if running, _, _ := unstructured.NestedBool(obj.Object, "spec", "running"); !running {
+		t.Error("Expected the rollback to run against its own cluster")
+	}
+	if left := keys(t, store); len(left) != 1 || left[0] != "gone/node/worker-1" {
+		t.Errorf("Expected the rollback of an unknown cluster to stay persisted, got %v", left)
+	}
+}
+
+func TestRollbacksCloseDeadline(t *testing.T) {
+	ctx := context.Background()
+	clients := Clients{Kube: fake.NewSimpleClientset()}
+	store := NewMemoryRollbackStore()
+	rollbacks := NewRollbacks(store)
+
+	// Rollbacks that hang until they are given up on
+	started := make(chan string, 3)
+	RegisterUndo("hang", func(ctx context.Context, _ Clients, u Undo) error {
+		started <- u.Name
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	for _, name := range []string{"a", "b", "c"} {
+		rollbacks.Schedule(ctx, clients, "hang/"+name, time.Hour, Undo{Op: "hang", Name: name})
+	}
+
+	closeCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	rollbacks.Close(closeCtx)
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Errorf("Expected Close to give up at the deadline, took %v", elapsed)
+	}
+	// Run one after another, only the first would have started before the deadline
+	if len(started) != 3 {
+		t.Errorf("Expected all rollbacks to run at once, %d started", len(started))
+	}
+	if left := keys(t, store); len(left) != 3 {
+		t.Errorf("Expected the rollbacks that did not finish to stay persisted, got %v", left)
+	}
+}
+
+// mustParseGVR parses a group/version/resource.
+func mustParseGVR(t *testing.T, s string) schema.GroupVersionResource {
+	t.Helper()
+	gvr, err := parseGVR(s)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", s, err)
+	}
+	return gvr
+}
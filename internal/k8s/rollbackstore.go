@@ -0,0 +1,121 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// RollbackStore persists pending rollbacks.
+type RollbackStore interface {
+	Save(rec RollbackRecord) error
+	Delete(key string) error
+	List() ([]RollbackRecord, error)
+}
+
+// MemoryRollbackStore keeps rollback records in memory. It does not survive restarts.
+type MemoryRollbackStore struct {
+	mu      sync.Mutex
+	records map[string]RollbackRecord
+}
+
+// NewMemoryRollbackStore creates a new in-memory rollback store.
+func NewMemoryRollbackStore() *MemoryRollbackStore {
+	return &MemoryRollbackStore{
+		records: make(map[string]RollbackRecord),
+	}
+}
+
+// Save stores or replaces a rollback record.
+func (s *MemoryRollbackStore) Save(rec RollbackRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.Key] = rec
+	return nil
+}
+
+// Delete removes a rollback record.
+func (s *MemoryRollbackStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// List returns all stored rollback records, the earliest due first.
+func (s *MemoryRollbackStore) List() ([]RollbackRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := make([]RollbackRecord, 0, len(s.records))
+	for _, rec := range s.records {
+		records = append(records, rec)
+	}
+	sortRollbacks(records)
+	return records, nil
+}
+
+// rollbackKeyPrefix namespaces rollback records within a shared BadgerDB.
+const rollbackKeyPrefix = "rollback_"
+
+// BadgerRollbackStore persists rollback records in BadgerDB.
+type BadgerRollbackStore struct {
+	db *badger.DB
+}
+
+// NewBadgerRollbackStore creates a rollback store backed by an already open BadgerDB.
+// The caller remains responsible for closing db.
+func NewBadgerRollbackStore(db *badger.DB) *BadgerRollbackStore {
+	return &BadgerRollbackStore{db: db}
+}
+
+// Save stores or replaces a rollback record.
+func (s *BadgerRollbackStore) Save(rec RollbackRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rollback %s: %w", rec.Key, err)
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(rollbackKeyPrefix+rec.Key), data)
+	})
+}
+
+// Delete removes a rollback record.
+func (s *BadgerRollbackStore) Delete(key string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(rollbackKeyPrefix + key))
+	})
+}
+
+// List returns all stored rollback records, the earliest due first.
+func (s *BadgerRollbackStore) List() ([]RollbackRecord, error) {
+	var records []RollbackRecord
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(rollbackKeyPrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var rec RollbackRecord
+				if err := json.Unmarshal(val, &rec); err != nil {
+					return fmt.Errorf("failed to unmarshal rollback %s: %w", it.Item().Key(), err)
+				}
+				records = append(records, rec)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortRollbacks(records)
+	return records, nil
+}
@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+	"github.com/cldmnky/pod-invaders/internal/logging"
+)
+
+// annotator fills in the signals that pod scoring uses and that need more than the pod
+// itself. It caches lookups, so one annotator should serve one request.
+type annotator struct {
+	client   kubernetes.Interface
+	pdbs     map[string][]labels.Selector // Selectors of the PodDisruptionBudgets by namespace
+	owners   map[workloadKey]workloadKey  // ReplicaSets resolved to their Deployment
+	replicas map[workloadKey]int32        // Desired replicas by workload
+}
+
+// newAnnotator creates an annotator that looks things up with client.
+func newAnnotator(client kubernetes.Interface) *annotator {
+	return &annotator{
+		client:   client,
+		pdbs:     make(map[string][]labels.Selector),
+		owners:   make(map[workloadKey]workloadKey),
+		replicas: make(map[workloadKey]int32),
+	}
+}
+
+// annotate sets whether a PodDisruptionBudget covers the pod and how many replicas its
+// owning workload wants.
+func (a *annotator) annotate(ctx context.Context, pod *game.Pod) {
+	set := labels.Set(pod.Labels)
+	for _, selector := range a.selectors(ctx, pod.Namespace) {
+		if selector.Matches(set) {
+			pod.CoveredByPDB = true
+			break
+		}
+	}
+
+	pod.OwnerReplicas = a.workloadReplicas(ctx, a.owner(ctx, *pod), 1)
+}
+
+// workloadReplicas returns the desired replicas of a workload, or running when the
+// workload has none or cannot be read.
+func (a *annotator) workloadReplicas(ctx context.Context, key workloadKey, running int) int32 {
+	replicas, ok := a.replicas[key]
+	if !ok {
+		replicas = workloadReplicas(ctx, a.client, key, running)
+		a.replicas[key] = replicas
+	}
+	return replicas
+}
+
+// selectors returns the pod selectors of the PodDisruptionBudgets in a namespace.
+func (a *annotator) selectors(ctx context.Context, namespace string) []labels.Selector {
+	if selectors, ok := a.pdbs[namespace]; ok {
+		return selectors
+	}
+
+	var selectors []labels.Selector
+	start := time.Now()
+	list, err := a.client.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	observe("list_pdbs", start, err)
+	if err != nil {
+		logging.FromContext(ctx).Debug("Failed to list PodDisruptionBudgets", "namespace", namespace, "error", err)
+	} else {
+		for _, pdb := range list.Items {
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil || pdb.Spec.Selector == nil {
+				continue // A missing selector selects no pods
+			}
+			selectors = append(selectors, selector)
+		}
+	}
+	a.pdbs[namespace] = selectors
+	return selectors
+}
+
+// owner returns the workload a pod belongs to: its controller, or the Deployment owning
+// its ReplicaSet. Pods without a controller are their own workload.
+func (a *annotator) owner(ctx context.Context, pod game.Pod) workloadKey {
+	if pod.OwnerKind == "" {
+		return workloadKey{kind: game.KindPod, namespace: pod.Namespace, name: pod.Name}
+	}
+	key := workloadKey{kind: pod.OwnerKind, namespace: pod.Namespace, name: pod.OwnerName}
+	if key.kind != game.KindReplicaSet {
+		return key
+	}
+	if owner, ok := a.owners[key]; ok {
+		return owner
+	}
+
+	owner := key
+	start := time.Now()
+	rs, err := a.client.AppsV1().ReplicaSets(key.namespace).Get(ctx, key.name, metav1.GetOptions{})
+	observe("get_replicaset", start, err)
+	if err != nil {
+		logging.FromContext(ctx).Debug("Failed to get ReplicaSet, grouping by it instead", "namespace", key.namespace, "replicaset", key.name, "error", err)
+	} else if ref := metav1.GetControllerOf(rs); ref != nil && ref.Kind == game.KindDeployment {
+		owner = workloadKey{kind: game.KindDeployment, namespace: key.namespace, name: ref.Name}
+	}
+	a.owners[key] = owner
+	return owner
+}
@@ -0,0 +1,202 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
+)
+
+// restartedAtAnnotation is the pod template annotation kubectl rollout restart sets.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// workloadKey identifies a workload within a listing.
+type workloadKey struct {
+	kind, namespace, name string
+}
+
+// GetWorkloads groups the running pods of the namespaces by owning workload and returns up
+// to rows workloads with up to cols of their pods each. Rows are topped up with fake workloads.
+func GetWorkloads(ctx context.Context, client kubernetes.Interface, rows, cols int, namespaces ...string) ([]game.Workload, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.GetWorkloads", trace.WithAttributes(
+		attribute.Int("workloads.rows", rows),
+		attribute.Int("workloads.cols", cols),
+		attribute.StringSlice("k8s.namespaces", namespaces),
+	))
+	defer span.End()
+
+	a := newAnnotator(client)
+	workloads := groupWorkloads(ctx, a, namespaces)
+	rand.Shuffle(len(workloads), func(i, j int) {
+		workloads[i], workloads[j] = workloads[j], workloads[i]
+	})
+	workloads = workloads[:min(len(workloads), rows)]
+	for i := range workloads {
+		rand.Shuffle(len(workloads[i].Pods), func(a, b int) {
+			workloads[i].Pods[a], workloads[i].Pods[b] = workloads[i].Pods[b], workloads[i].Pods[a]
+		})
+		annotateWorkload(ctx, a, &workloads[i])
+		workloads[i].Pods = workloads[i].Pods[:min(len(workloads[i].Pods), cols)]
+	}
+	span.SetAttributes(attribute.Int("workloads.real", len(workloads)))
+
+	if len(workloads) < rows {
+		logging.FromContext(ctx).Debug("Not enough workloads, generating fake workloads", "real", len(workloads), "rows", rows)
+	}
+	fakes := game.FakePodGeneratorFromContext(ctx)
+	for len(workloads) < rows {
+		workloads = append(workloads, fakes.Workload(cols))
+	}
+	return workloads, nil
+}
+
+// GetBoss picks the workload a boss level stands for, preferring StatefulSets, then
+// Deployments. Without any running workload it returns a fake boss.
+func GetBoss(ctx context.Context, client kubernetes.Interface, namespaces ...string) (game.Boss, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.GetBoss", trace.WithAttributes(
+		attribute.StringSlice("k8s.namespaces", namespaces),
+	))
+	defer span.End()
+
+	a := newAnnotator(client)
+	workloads := groupWorkloads(ctx, a, namespaces)
+	for _, kind := range []string{game.KindStatefulSet, game.KindDeployment} {
+		var candidates []game.Workload
+		for _, w := range workloads {
+			if w.Kind == kind {
+				candidates = append(candidates, w)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		w := candidates[rand.Intn(len(candidates))]
+		annotateWorkload(ctx, a, &w)
+		span.SetAttributes(
+			attribute.String("k8s.workload.kind", w.Kind),
+			attribute.String("k8s.workload.name", w.Name),
+		)
+		return game.NewBoss(w), nil
+	}
+	return game.FakePodGeneratorFromContext(ctx).Boss(), nil
+}
+
+// groupWorkloads lists the running pods of the namespaces, grouped by owning workload in
+// the order they were first seen.
+func groupWorkloads(ctx context.Context, a *annotator, namespaces []string) []game.Workload {
+	if len(namespaces) == 0 {
+		namespaces = []string{"default"}
+	}
+
+	var workloads []game.Workload
+	index := make(map[workloadKey]int)
+	for _, ns := range namespaces {
+		running, err := listRunningPods(ctx, a.client, ns)
+		if err != nil {
+			continue
+		}
+		for i := range running {
+			pod := PodFromObject(&running[i])
+			key := a.owner(ctx, pod)
+			n, ok := index[key]
+			if !ok {
+				n = len(workloads)
+				index[key] = n
+				workloads = append(workloads, game.Workload{Kind: key.kind, Name: key.name, Namespace: key.namespace, IsReal: true})
+			}
+			workloads[n].Pods = append(workloads[n].Pods, pod)
+		}
+	}
+	return workloads
+}
+
+// annotateWorkload sets the replica count of a workload and the signals of its pods.
+func annotateWorkload(ctx context.Context, a *annotator, w *game.Workload) {
+	w.Replicas = a.workloadReplicas(ctx, workloadKey{kind: w.Kind, namespace: w.Namespace, name: w.Name}, len(w.Pods))
+	for i := range w.Pods {
+		a.annotate(ctx, &w.Pods[i])
+	}
+}
+
+// workloadReplicas returns the desired replica count of a workload, falling back to
+// running, its number of running pods, when the workload has none or cannot be read.
+func workloadReplicas(ctx context.Context, client kubernetes.Interface, w workloadKey, running int) int32 {
+	var replicas *int32
+	var err error
+	start := time.Now()
+	switch w.kind {
+	case game.KindDeployment:
+		d, e := client.AppsV1().Deployments(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		err = e
+		if err == nil {
+			replicas = d.Spec.Replicas
+		}
+	case game.KindStatefulSet:
+		sts, e := client.AppsV1().StatefulSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		err = e
+		if err == nil {
+			replicas = sts.Spec.Replicas
+		}
+	case game.KindReplicaSet:
+		rs, e := client.AppsV1().ReplicaSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		err = e
+		if err == nil {
+			replicas = rs.Spec.Replicas
+		}
+	case game.KindDaemonSet:
+		ds, e := client.AppsV1().DaemonSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		err = e
+		if err == nil {
+			n := ds.Status.DesiredNumberScheduled
+			replicas = &n
+		}
+	default:
+		return int32(running)
+	}
+	observe("get_workload", start, err)
+	if err != nil || replicas == nil {
+		return int32(running)
+	}
+	return *replicas
+}
+
+// RestartWorkload triggers a rolling restart of a Deployment, StatefulSet or DaemonSet,
+// the way kubectl rollout restart does.
+func RestartWorkload(ctx context.Context, client kubernetes.Interface, kind, namespace, name string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "k8s.RestartWorkload", trace.WithAttributes(
+		attribute.String("k8s.workload.kind", kind),
+		attribute.String("k8s.namespace.name", namespace),
+		attribute.String("k8s.workload.name", name),
+	))
+	defer func() { endSpan(span, err) }()
+
+	patch := fmt.Appendf(nil, `{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`,
+		restartedAtAnnotation, time.Now().Format(time.RFC3339))
+	start := time.Now()
+	switch kind {
+	case game.KindDeployment:
+		_, err = client.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case game.KindStatefulSet:
+		_, err = client.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case game.KindDaemonSet:
+		_, err = client.AppsV1().DaemonSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	default:
+		return fmt.Errorf("cannot restart %s %s/%s", kind, namespace, name)
+	}
+	observe("restart_workload", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to restart %s %s/%s: %w", kind, namespace, name, err)
+	}
+	logging.FromContext(ctx).Info("Restarted workload", "kind", kind, "namespace", namespace, "name", name)
+	return nil
+}
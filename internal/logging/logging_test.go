@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestNewHandler(t *testing.T) {
+	if _, err := NewHandler(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+
+	var buf bytes.Buffer
+	handler, err := NewHandler(&buf, FormatJSON)
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	if err := SetLevel("warn"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	defer SetLevel("info")
+
+	logger := slog.New(handler).With("request_id", "abc")
+	logger.Info("dropped")
+	logger.Warn("kept")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected exactly one JSON entry, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "kept" || entry["request_id"] != "abc" {
+		t.Errorf("Unexpected log entry: %v", entry)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, s := range []string{"debug", "info", "warn", "error", "INFO"} {
+		if _, err := ParseLevel(s); err != nil {
+			t.Errorf("ParseLevel(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("Expected the default logger without a logger in the context")
+	}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	if FromContext(WithLogger(context.Background(), logger)) != logger {
+		t.Error("Expected the logger carried by the context")
+	}
+}
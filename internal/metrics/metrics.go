@@ -0,0 +1,194 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every pod-invaders metric.
+const namespace = "pod_invaders"
+
+// Registry holds all pod-invaders metrics along with the Go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+// Game metrics
+var (
+	// Kills counts kill requests by target namespace, result (success, failure, skipped, denied) and strategy.
+	Kills = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kills_total",
+		Help:      "Number of kill requests by namespace, result and strategy.",
+	}, []string{"namespace", "result", "strategy"})
+
+	// BossDefeats counts defeated boss workloads by kind, action taken and result.
+	BossDefeats = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "boss_defeats_total",
+		Help:      "Number of defeated boss workloads by kind, action and result.",
+	}, []string{"kind", "action", "result"})
+
+	// NodeDrains counts node kills in node targeting by result and strategy.
+	NodeDrains = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "node_drains_total",
+		Help:      "Number of node kill requests by result and strategy.",
+	}, []string{"result", "strategy"})
+
+	// NodeDrainPods counts the pods on drained nodes by outcome (evicted, blocked, skipped, failed).
+	NodeDrainPods = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "node_drain_pods_total",
+		Help:      "Number of pods on drained nodes by outcome.",
+	}, []string{"outcome"})
+
+	// ResourceKills counts kills in resource targeting by resource, result and strategy.
+	ResourceKills = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resource_kills_total",
+		Help:      "Number of resource kill requests by resource, result and strategy.",
+	}, []string{"resource", "result", "strategy"})
+
+	// NamesDuration observes how long /names takes to build a wave of invaders.
+	NamesDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "names_request_duration_seconds",
+		Help:      "Latency of /names requests by mode (kube or standalone).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"mode"})
+
+	// PodsServed counts the pods handed out by /names, split into real and fake.
+	PodsServed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pods_served_total",
+		Help:      "Number of pods returned by /names by kind (real or fake).",
+	}, []string{"kind"})
+
+	// RealPodRatio is the share of real pods in the most recent /names response.
+	RealPodRatio = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "names_real_pod_ratio",
+		Help:      "Fraction of real pods in the most recent /names response.",
+	})
+
+	// ActiveGames is the number of browser sessions that recently sent a game heartbeat.
+	ActiveGames = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_games",
+		Help:      "Number of games with a recent heartbeat.",
+	})
+
+	// HighscoreSubmissions counts highscore submissions by result (accepted or rejected).
+	HighscoreSubmissions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "highscore_submissions_total",
+		Help:      "Number of highscore submissions by result.",
+	}, []string{"result"})
+)
+
+// API metrics
+var (
+	// RateLimited counts requests rejected by the rate limiter, by route group.
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Number of requests rejected by the rate limiter by route group.",
+	}, []string{"group"})
+)
+
+// Kubernetes metrics
+var (
+	// KubeRequestDuration observes Kubernetes API calls by operation and result.
+	KubeRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kube_request_duration_seconds",
+		Help:      "Latency of Kubernetes API calls by operation and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "result"})
+
+	// Rollbacks counts rollbacks of temporary changes by undo operation and result.
+	Rollbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rollbacks_total",
+		Help:      "Number of rollbacks of temporary changes by operation and result.",
+	}, []string{"op", "result"})
+
+	// PendingRollbacks is the number of temporary changes waiting to be rolled back.
+	PendingRollbacks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_rollbacks",
+		Help:      "Number of temporary changes waiting to be rolled back.",
+	})
+)
+
+// Monitor metrics
+var (
+	// Monitors is the number of running URL monitors.
+	Monitors = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "monitors",
+		Help:      "Number of running URL monitors.",
+	})
+
+	// MonitorUp is 1 while a monitored URL is up and 0 while it is down.
+	MonitorUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "monitor_up",
+		Help:      "Whether a monitored URL is up (1) or down (0).",
+	}, []string{"id", "url"})
+
+	// MonitorProbeDuration observes how long each monitor probe takes.
+	MonitorProbeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "monitor_probe_duration_seconds",
+		Help:      "Latency of monitor probes by monitor ID and result.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"id", "result"})
+
+	// MonitorTransitions counts confirmed up/down transitions.
+	MonitorTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "monitor_transitions_total",
+		Help:      "Number of confirmed monitor state transitions by new state.",
+	}, []string{"to"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Kills,
+		BossDefeats,
+		NodeDrains,
+		NodeDrainPods,
+		ResourceKills,
+		NamesDuration,
+		PodsServed,
+		RealPodRatio,
+		ActiveGames,
+		HighscoreSubmissions,
+		RateLimited,
+		KubeRequestDuration,
+		Rollbacks,
+		PendingRollbacks,
+		Monitors,
+		MonitorUp,
+		MonitorProbeDuration,
+		MonitorTransitions,
+	)
+}
+
+// Handler returns an HTTP handler that serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Result returns "success" if err is nil and "failure" otherwise.
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
@@ -0,0 +1,210 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrEgressDenied is returned when a monitor URL or the address it resolves to is not permitted.
+var ErrEgressDenied = errors.New("egress denied")
+
+// DefaultMaxResponseBytes is the largest response body a probe will read before marking the target down.
+const DefaultMaxResponseBytes = 1 << 20
+
+// maxRedirects bounds how many redirects a probe follows.
+const maxRedirects = 5
+
+// deniedPrefixes are always rejected unless explicitly listed in EgressPolicy.AllowedCIDRs.
+var deniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),         // "this" network
+	netip.MustParsePrefix("10.0.0.0/8"),        // RFC 1918 private, e.g. in-cluster Services and the API server
+	netip.MustParsePrefix("100.64.0.0/10"),     // Carrier-grade NAT, used for cloud-internal addresses
+	netip.MustParsePrefix("127.0.0.0/8"),       // IPv4 loopback
+	netip.MustParsePrefix("169.254.0.0/16"),    // IPv4 link-local, including cloud metadata endpoints
+	netip.MustParsePrefix("172.16.0.0/12"),     // RFC 1918 private
+	netip.MustParsePrefix("192.0.0.0/24"),      // IETF protocol assignments
+	netip.MustParsePrefix("192.168.0.0/16"),    // RFC 1918 private
+	netip.MustParsePrefix("198.18.0.0/15"),     // Benchmarking, sometimes used for internal networks
+	netip.MustParsePrefix("224.0.0.0/4"),       // IPv4 multicast
+	netip.MustParsePrefix("240.0.0.0/4"),       // Reserved, including broadcast
+	netip.MustParsePrefix("::/128"),            // IPv6 unspecified
+	netip.MustParsePrefix("::1/128"),           // IPv6 loopback
+	netip.MustParsePrefix("64:ff9b::/96"),      // NAT64, which embeds any IPv4 address, private ones included
+	netip.MustParsePrefix("fc00::/7"),          // IPv6 unique local
+	netip.MustParsePrefix("fe80::/10"),         // IPv6 link-local
+	netip.MustParsePrefix("ff00::/8"),          // IPv6 multicast
+	netip.MustParsePrefix("fd00:ec2::254/128"), // AWS IPv6 metadata endpoint
+}
+
+// EgressPolicy decides which hosts monitors may probe.
+//
+// Loopback, link-local, private and other special ranges are always denied unless
+// they appear in AllowedCIDRs. If AllowedHosts or AllowedCIDRs is set, targets must
+// also match one of them. Resolved addresses are checked at dial time, so a
+// hostname cannot be rebound to a denied address after validation.
+type EgressPolicy struct {
+	AllowedHosts     []string       // Hostname patterns, e.g. "api.example.com" or "*.svc.cluster.local"
+	AllowedCIDRs     []netip.Prefix // Address ranges that may be probed
+	MaxResponseBytes int64          // Maximum response body size (0 = DefaultMaxResponseBytes)
+}
+
+// NewEgressPolicy builds an EgressPolicy from host patterns and CIDR strings.
+func NewEgressPolicy(hosts, cidrs []string, maxResponseBytes int64) (EgressPolicy, error) {
+	policy := EgressPolicy{MaxResponseBytes: maxResponseBytes}
+
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		policy.AllowedHosts = append(policy.AllowedHosts, h)
+	}
+
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(c)
+		if err != nil {
+			return EgressPolicy{}, fmt.Errorf("invalid allowed CIDR %q: %w", c, err)
+		}
+		policy.AllowedCIDRs = append(policy.AllowedCIDRs, prefix.Masked())
+	}
+
+	return policy, nil
+}
+
+// restricted reports whether the policy uses an allowlist.
+func (p EgressPolicy) restricted() bool {
+	return len(p.AllowedHosts) > 0 || len(p.AllowedCIDRs) > 0
+}
+
+// maxResponseBytes returns the effective response size limit.
+func (p EgressPolicy) maxResponseBytes() int64 {
+	if p.MaxResponseBytes > 0 {
+		return p.MaxResponseBytes
+	}
+	return DefaultMaxResponseBytes
+}
+
+// hostAllowed reports whether host matches one of the allowed host patterns.
+func (p EgressPolicy) hostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range p.AllowedHosts {
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL validates a monitor URL before any connection is made.
+func (p EgressPolicy) CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid URL: %v", ErrEgressDenied, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrEgressDenied, u.Scheme)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: URL has no host", ErrEgressDenied)
+	}
+	hostAllowed := p.hostAllowed(host)
+
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return p.checkAddr(ip, hostAllowed)
+	}
+
+	if (host == "localhost" || strings.HasSuffix(host, ".localhost")) && !hostAllowed {
+		return fmt.Errorf("%w: host %s is a loopback name", ErrEgressDenied, host)
+	}
+
+	// Without a matching host pattern the target can only be allowed by a CIDR,
+	// which is checked once the name has been resolved.
+	if p.restricted() && !hostAllowed && len(p.AllowedCIDRs) == 0 {
+		return fmt.Errorf("%w: host %s is not in the allowed hosts", ErrEgressDenied, host)
+	}
+
+	return nil
+}
+
+// checkAddr validates a resolved address.
+func (p EgressPolicy) checkAddr(ip netip.Addr, hostAllowed bool) error {
+	ip = ip.Unmap()
+
+	for _, prefix := range p.AllowedCIDRs {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("%w: address %s is in the denied range %s", ErrEgressDenied, ip, prefix)
+		}
+	}
+	if p.restricted() && !hostAllowed {
+		return fmt.Errorf("%w: address %s is not in the allowed ranges", ErrEgressDenied, ip)
+	}
+
+	return nil
+}
+
+// dialContext dials addr, rejecting the connection if the resolved address is not permitted.
+func (p EgressPolicy) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	hostAllowed := p.hostAllowed(host)
+
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		// Control runs after name resolution, on the exact address being dialled.
+		Control: func(_, address string, _ syscall.RawConn) error {
+			ipStr, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(ipStr)
+			if err != nil {
+				return fmt.Errorf("%w: cannot parse dialled address %s", ErrEgressDenied, address)
+			}
+			return p.checkAddr(ip, hostAllowed)
+		},
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// newHTTPClient returns an HTTP client that enforces the policy on every connection and redirect.
+func (p EgressPolicy) newHTTPClient(transport *http.Transport) *http.Client {
+	transport.Proxy = nil
+	transport.DialContext = p.dialContext
+	transport.MaxResponseHeaderBytes = 64 << 10
+
+	return &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return p.CheckURL(req.URL.String())
+		},
+	}
+}
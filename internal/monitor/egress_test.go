@@ -0,0 +1,179 @@
+package monitor_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cldmnky/pod-invaders/internal/monitor"
+)
+
+var _ = Describe("EgressPolicy", func() {
+	Describe("NewEgressPolicy", func() {
+		It("should parse hosts and CIDRs", func() {
+			policy, err := monitor.NewEgressPolicy([]string{" API.example.com ", ""}, []string{"10.0.0.0/8"}, 512)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.AllowedHosts).To(Equal([]string{"api.example.com"}))
+			Expect(policy.AllowedCIDRs).To(Equal([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}))
+			Expect(policy.MaxResponseBytes).To(Equal(int64(512)))
+		})
+
+		It("should reject invalid CIDRs", func() {
+			_, err := monitor.NewEgressPolicy(nil, []string{"not-a-cidr"}, 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CheckURL", func() {
+		DescribeTable("with the default policy",
+			func(url string, allowed bool) {
+				err := monitor.EgressPolicy{}.CheckURL(url)
+				if allowed {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(errors.Is(err, monitor.ErrEgressDenied)).To(BeTrue(), "expected %s to be denied, got %v", url, err)
+				}
+			},
+			Entry("public hostname", "https://example.com/health", true),
+			Entry("private address", "http://10.0.0.12:8080/", false),
+			Entry("RFC 1918 172.16/12", "http://172.20.0.1/", false),
+			Entry("RFC 1918 192.168/16", "http://192.168.1.1/", false),
+			Entry("carrier-grade NAT", "http://100.64.0.1/", false),
+			Entry("IPv6 unique local", "http://[fd12:3456::1]/", false),
+			Entry("cluster service", "http://api.default.svc.cluster.local/", true),
+			Entry("cloud metadata", "http://169.254.169.254/latest/meta-data/", false),
+			Entry("IPv4 loopback", "http://127.0.0.1/", false),
+			Entry("IPv6 loopback", "http://[::1]:8080/", false),
+			Entry("IPv4-mapped loopback", "http://[::ffff:127.0.0.1]/", false),
+			Entry("NAT64-mapped private address", "http://[64:ff9b::a00:1]/", false),
+			Entry("IETF protocol assignments", "http://192.0.0.8/", false),
+			Entry("benchmarking range", "http://198.18.0.1/", false),
+			Entry("reserved range", "http://240.0.0.1/", false),
+			Entry("broadcast", "http://255.255.255.255/", false),
+			Entry("unspecified address", "http://0.0.0.0:3000/", false),
+			Entry("localhost name", "http://localhost/", false),
+			Entry("localhost subdomain", "http://admin.localhost/", false),
+			Entry("non-http scheme", "file:///etc/passwd", false),
+		)
+
+		It("should restrict hosts to the allowlist", func() {
+			policy := monitor.EgressPolicy{AllowedHosts: []string{"*.example.com", "status.acme.io"}}
+
+			Expect(policy.CheckURL("https://api.example.com/")).To(Succeed())
+			Expect(policy.CheckURL("https://status.acme.io/")).To(Succeed())
+			Expect(policy.CheckURL("https://example.com/")).To(MatchError(monitor.ErrEgressDenied))
+			Expect(policy.CheckURL("https://evil.com/")).To(MatchError(monitor.ErrEgressDenied))
+			Expect(policy.CheckURL("http://10.0.0.1/")).To(MatchError(monitor.ErrEgressDenied))
+		})
+
+		It("should let CIDRs re-allow denied ranges", func() {
+			policy := monitor.EgressPolicy{AllowedCIDRs: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}
+
+			Expect(policy.CheckURL("http://127.0.0.1:8080/")).To(Succeed())
+			Expect(policy.CheckURL("http://169.254.169.254/")).To(MatchError(monitor.ErrEgressDenied))
+			Expect(policy.CheckURL("http://10.0.0.1/")).To(MatchError(monitor.ErrEgressDenied))
+		})
+
+		It("should let a CIDR re-allow part of a private range", func() {
+			policy := monitor.EgressPolicy{AllowedCIDRs: []netip.Prefix{netip.MustParsePrefix("10.96.12.0/24")}}
+
+			Expect(policy.CheckURL("http://10.96.12.7/healthz")).To(Succeed())
+			Expect(policy.CheckURL("http://10.96.0.1/")).To(MatchError(monitor.ErrEgressDenied))
+		})
+	})
+
+	Describe("Probing", func() {
+		var (
+			ctx        context.Context
+			cancel     context.CancelFunc
+			testServer *httptest.Server
+			m          *monitor.Manager
+		)
+
+		statusOf := func(id string) func() string {
+			return func() string {
+				status, err := m.GetStatus(id)
+				if err != nil {
+					return ""
+				}
+				return status.Status
+			}
+		}
+
+		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+		})
+
+		AfterEach(func() {
+			cancel()
+			if m != nil {
+				m.Close()
+				m = nil
+			}
+			if testServer != nil {
+				testServer.Close()
+				testServer = nil
+			}
+		})
+
+		It("should check the resolved address at dial time", func() {
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			// The hostname passes validation, but resolves to a loopback address
+			m = monitor.NewManagerWithOptions(monitor.Options{
+				Egress: monitor.EgressPolicy{AllowedHosts: []string{"localhost"}},
+			})
+			url := strings.Replace(testServer.URL, "127.0.0.1", "localhost", 1)
+
+			id, err := m.Start(ctx, url)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(statusOf(id), "6s", "100ms").Should(Equal("down"))
+		})
+
+		It("should not follow redirects into denied ranges", func() {
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+			}))
+			m = newLoopbackManager(monitor.Options{})
+
+			id, err := m.Start(ctx, testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(statusOf(id), "6s", "100ms").Should(Equal("down"))
+		})
+
+		It("should mark oversized responses as down", func() {
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(strings.Repeat("x", 2048)))
+			}))
+			opts := monitor.Options{}
+			opts.Egress.MaxResponseBytes = 1024
+			m = newLoopbackManager(opts)
+
+			id, err := m.Start(ctx, testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(statusOf(id), "6s", "100ms").Should(Equal("down"))
+		})
+
+		It("should accept responses within the size limit", func() {
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(strings.Repeat("x", 512)))
+			}))
+			opts := monitor.Options{}
+			opts.Egress.MaxResponseBytes = 1024
+			m = newLoopbackManager(opts)
+
+			id, err := m.Start(ctx, testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(statusOf(id), "6s", "100ms").Should(Equal("up"))
+		})
+	})
+})
@@ -0,0 +1,60 @@
+package monitor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/cldmnky/pod-invaders/internal/metrics"
+	"github.com/cldmnky/pod-invaders/internal/monitor"
+)
+
+// hasSeries reports whether the registry exposes a series of the named metric labelled with the monitor ID.
+func hasSeries(name, id string) bool {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "id" && label.GetValue() == id {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+var _ = Describe("Metrics", func() {
+	It("should export probe latency and up gauges until the monitor stops", func() {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer testServer.Close()
+
+		m := newLoopbackManager(monitor.DefaultOptions())
+		defer m.Close()
+
+		id, err := m.Start(context.Background(), testServer.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testutil.ToFloat64(metrics.Monitors)).To(Equal(1.0))
+
+		Eventually(func() bool {
+			return hasSeries("pod_invaders_monitor_up", id)
+		}, "6s", "50ms").Should(BeTrue())
+		Expect(testutil.ToFloat64(metrics.MonitorUp.WithLabelValues(id, testServer.URL))).To(Equal(1.0))
+		Expect(hasSeries("pod_invaders_monitor_probe_duration_seconds", id)).To(BeTrue())
+
+		Expect(m.Stop(id)).To(Succeed())
+		Expect(hasSeries("pod_invaders_monitor_up", id)).To(BeFalse())
+		Expect(hasSeries("pod_invaders_monitor_probe_duration_seconds", id)).To(BeFalse())
+		Expect(testutil.ToFloat64(metrics.Monitors)).To(Equal(0.0))
+	})
+})
@@ -3,28 +3,89 @@ package monitor
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cldmnky/pod-invaders/internal/logging"
+	"github.com/cldmnky/pod-invaders/internal/metrics"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
+)
+
+var (
+	// ErrNotFound is returned when a monitor ID is unknown to the manager.
+	ErrNotFound = errors.New("not found")
+	// ErrNotOwner is returned when a session tries to act on a monitor it does not own.
+	ErrNotOwner = errors.New("is owned by another session")
+	// ErrLimitReached is returned when starting a monitor would exceed the configured limits.
+	ErrLimitReached = errors.New("monitor limit reached")
 )
 
 // Monitor represents a URL to be monitored.
 type Monitor struct {
-	URL    string             `json:"url"`
-	ID     string             `json:"id"`
-	Ctx    context.Context    `json:"-"`
-	Cancel context.CancelFunc `json:"-"`
+	URL       string             `json:"url"`
+	ID        string             `json:"id"`
+	Owner     string             `json:"-"`
+	CreatedAt time.Time          `json:"createdAt"`
+	LastSeen  time.Time          `json:"lastSeen"`
+	Ctx       context.Context    `json:"-"`
+	Cancel    context.CancelFunc `json:"-"`
+
+	logger *slog.Logger // Tagged with the monitor ID and URL
+
+	// Debounced alerting state, guarded by Manager.mu
+	reported     string      // Last confirmed "up"/"down" state
+	reportedAt   time.Time   // When the reported state was confirmed
+	pending      string      // Candidate state awaiting confirmation
+	pendingCount int         // Consecutive checks that observed the pending state
+	firing       *Transition // Transition to "down" that has not been resolved yet
 }
 
 // Status represents the health status of a monitored URL.
 type Status struct {
-	URL    string `json:"url"`
-	Status string `json:"status"` // e.g., "up", "down", "unknown"
-	ID     string `json:"id"`
+	URL       string    `json:"url"`
+	Status    string    `json:"status"` // e.g., "up", "down", "unknown"
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeen  time.Time `json:"lastSeen"`
+	History   []Check   `json:"history,omitempty"` // Most recent checks, oldest first
+}
+
+// Options configures the limits, expiry, egress, persistence and alerting of a Manager.
+type Options struct {
+	Interval     time.Duration // Time between probes (0 = 5s)
+	MaxMonitors  int           // Maximum number of monitors across all sessions (0 = unlimited)
+	MaxPerOwner  int           // Maximum number of monitors per session (0 = unlimited)
+	IdleTimeout  time.Duration // Stop monitors whose owner has not sent a heartbeat for this long (0 = never)
+	ReapInterval time.Duration // How often idle monitors are checked for expiry
+	Egress       EgressPolicy  // Which hosts monitors may probe
+	Store        Store         // Where monitors are persisted (nil = in memory)
+
+	Notifiers     []Notifier // Receive up/down transitions
+	AlertDebounce int        // Consecutive checks a new state must hold before notifying (minimum 1)
+}
+
+// DefaultOptions returns the limits used by NewManager.
+func DefaultOptions() Options {
+	return Options{
+		Interval:     5 * time.Second,
+		MaxMonitors:  100,
+		MaxPerOwner:  10,
+		IdleTimeout:  2 * time.Minute,
+		ReapInterval: 30 * time.Second,
+
+		AlertDebounce: 2,
+	}
 }
 
 // Manager handles all active monitors.
@@ -32,118 +93,443 @@ type Manager struct {
 	mu       sync.Mutex
 	monitors map[string]*Monitor
 	statuses map[string]*Status
+	opts     Options
+	store    Store
+	wg       sync.WaitGroup
+	done     chan struct{}
+	closed   bool
+
+	notifyCtx    context.Context // Cancelled on Close to abandon pending webhook retries
+	notifyCancel context.CancelFunc
 }
 
-// NewManager creates a new monitor manager.
+// NewManager creates a new monitor manager with the default options.
 func NewManager() *Manager {
-	return &Manager{
+	return NewManagerWithOptions(DefaultOptions())
+}
+
+// NewManagerWithOptions creates a new monitor manager with the given limits.
+// If an idle timeout is configured, a background reaper stops monitors whose
+// owner has stopped sending heartbeats.
+func NewManagerWithOptions(opts Options) *Manager {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultOptions().Interval
+	}
+	if opts.ReapInterval <= 0 {
+		opts.ReapInterval = DefaultOptions().ReapInterval
+	}
+	if opts.AlertDebounce < 1 {
+		opts.AlertDebounce = 1
+	}
+	store := opts.Store
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
+	m := &Manager{
 		monitors: make(map[string]*Monitor),
 		statuses: make(map[string]*Status),
+		opts:     opts,
+		store:    store,
+		done:     make(chan struct{}),
 	}
+	m.notifyCtx, m.notifyCancel = context.WithCancel(context.Background())
+
+	if opts.IdleTimeout > 0 {
+		m.wg.Add(1)
+		go m.reapIdle()
+	}
+
+	return m
 }
 
-// Start begins monitoring a new URL.
+// Start begins monitoring a new URL without an owning session.
 func (m *Manager) Start(ctx context.Context, url string) (string, error) {
+	return m.StartForOwner(ctx, "", url)
+}
+
+// StartForOwner begins monitoring a new URL on behalf of the given session.
+func (m *Manager) StartForOwner(ctx context.Context, owner, url string) (string, error) {
+	if err := m.opts.Egress.CheckURL(url); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return "", errors.New("monitor manager is shut down")
+	}
+	if m.opts.MaxMonitors > 0 && len(m.monitors) >= m.opts.MaxMonitors {
+		return "", fmt.Errorf("%w: at most %d monitors may run at once", ErrLimitReached, m.opts.MaxMonitors)
+	}
+	if m.opts.MaxPerOwner > 0 && m.countOwnedLocked(owner) >= m.opts.MaxPerOwner {
+		return "", fmt.Errorf("%w: at most %d monitors per session", ErrLimitReached, m.opts.MaxPerOwner)
+	}
+
+	rec := Record{
+		ID:        uuid.New().String(),
+		URL:       url,
+		Owner:     owner,
+		CreatedAt: time.Now(),
+		Status:    "unknown",
+	}
+	if err := m.store.Save(rec); err != nil {
+		return "", fmt.Errorf("failed to persist monitor: %w", err)
+	}
+
+	m.launchLocked(ctx, rec)
+	return rec.ID, nil
+}
+
+// Restore resumes all monitors found in the store, keeping their IDs.
+// Monitors that are no longer permitted by the egress policy are discarded.
+// It returns the number of monitors resumed.
+func (m *Manager) Restore(ctx context.Context) (int, error) {
+	records, err := m.store.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load monitors: %w", err)
+	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	id := uuid.New().String()
-	monitorCtx, cancel := context.WithCancel(ctx)
+	if m.closed {
+		return 0, errors.New("monitor manager is shut down")
+	}
+
+	restored := 0
+	for _, rec := range records {
+		if _, ok := m.monitors[rec.ID]; ok {
+			continue
+		}
+		if err := m.opts.Egress.CheckURL(rec.URL); err != nil {
+			logger := logging.FromContext(ctx).With("monitor_id", rec.ID, "url", rec.URL)
+			logger.Warn("Discarding stored monitor", "error", err)
+			if err := m.store.Delete(rec.ID); err != nil {
+				logger.Error("Failed to delete stored monitor", "error", err)
+			}
+			continue
+		}
+		m.launchLocked(ctx, rec)
+		restored++
+	}
+
+	return restored, nil
+}
+
+// launchLocked registers a monitor and starts its goroutine. The caller must hold m.mu.
+// The idle clock starts now, giving restored monitors a full timeout to receive a heartbeat.
+func (m *Manager) launchLocked(ctx context.Context, rec Record) {
+	// Log lines of the monitor carry the request that started it, if any
+	logger := logging.FromContext(ctx).With("monitor_id", rec.ID, "url", rec.URL)
+	monitorCtx, cancel := context.WithCancel(logging.WithLogger(ctx, logger))
+	now := time.Now()
 
 	monitor := &Monitor{
-		URL:    url,
-		ID:     id,
-		Ctx:    monitorCtx,
-		Cancel: cancel,
+		URL:       rec.URL,
+		ID:        rec.ID,
+		Owner:     rec.Owner,
+		CreatedAt: rec.CreatedAt,
+		LastSeen:  now,
+		Ctx:       monitorCtx,
+		Cancel:    cancel,
+		logger:    logger,
+	}
+	// A restored monitor keeps its last known state as the alerting baseline
+	if rec.Status == "up" || rec.Status == "down" {
+		monitor.reported = rec.Status
+		monitor.reportedAt = now
 	}
 
 	status := &Status{
-		URL:    url,
-		ID:     id,
-		Status: "unknown",
+		URL:       rec.URL,
+		ID:        rec.ID,
+		Status:    rec.Status,
+		CreatedAt: rec.CreatedAt,
+		LastSeen:  now,
+		History:   append([]Check(nil), rec.History...),
 	}
 
-	m.monitors[id] = monitor
-	m.statuses[id] = status
+	m.monitors[rec.ID] = monitor
+	m.statuses[rec.ID] = status
+	metrics.Monitors.Set(float64(len(m.monitors)))
 
+	m.wg.Add(1)
 	go m.runMonitor(monitor)
+	logger.Info("Monitor started")
+}
 
-	return id, nil
+// countOwnedLocked returns the number of monitors owned by the given session.
+// The caller must hold m.mu.
+func (m *Manager) countOwnedLocked(owner string) int {
+	count := 0
+	for _, mon := range m.monitors {
+		if mon.Owner == owner {
+			count++
+		}
+	}
+	return count
 }
 
 // runMonitor is the background goroutine that checks the URL status.
 func (m *Manager) runMonitor(mon *Monitor) {
-	ticker := time.NewTicker(5 * time.Second)
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.opts.Interval)
 	defer ticker.Stop()
 
 	// Custom transport to ignore TLS verification
 	transport := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
-	client := http.Client{
-		Timeout:   5 * time.Second,
-		Transport: transport,
+	defer transport.CloseIdleConnections()
+	client := m.opts.Egress.newHTTPClient(transport)
+	maxBytes := m.opts.Egress.maxResponseBytes()
+
+	check := func() {
+		start := time.Now()
+		status := probe(mon.Ctx, client, mon.URL, maxBytes)
+		m.updateStatus(mon.ID, status, time.Since(start))
 	}
 
 	// Run the monitor once immediately
-	resp, err := client.Get(mon.URL)
-	newStatus := "down"
-	if err == nil {
-		defer resp.Body.Close()
-		if resp.StatusCode >= 200 && resp.StatusCode < 500 {
-			newStatus = "up"
-		}
-	} else {
-		log.Printf("Error monitoring URL %s: %v", mon.URL, err)
-	}
-	m.updateStatus(mon.ID, newStatus)
+	check()
 
 	for {
 		select {
 		case <-mon.Ctx.Done():
-			log.Printf("Monitoring service for URL %s (ID: %s) stopped.", mon.URL, mon.ID)
+			mon.logger.Info("Monitor stopped")
 			return
 		case <-ticker.C:
-			resp, err := client.Get(mon.URL)
-			newStatus := "down"
-			if err == nil {
-				defer resp.Body.Close()
-				if resp.StatusCode >= 200 && resp.StatusCode < 500 {
-					newStatus = "up"
-				}
-			} else {
-				log.Printf("Error monitoring URL %s: %v", mon.URL, err)
-			}
-			m.updateStatus(mon.ID, newStatus)
+			check()
 		}
 	}
 }
 
-// updateStatus safely updates the status of a monitor.
-func (m *Manager) updateStatus(id, status string) {
+// probe performs a single health check against url and returns "up" or "down".
+// Responses with bodies larger than maxBytes are treated as down.
+func probe(ctx context.Context, client *http.Client, url string, maxBytes int64) (status string) {
+	// Each probe is its own trace, linked to the request that started the monitor
+	ctx, span := tracing.Tracer().Start(ctx, "monitor.probe",
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("url.full", url)),
+	)
+	defer func() {
+		span.SetAttributes(attribute.String("monitor.status", status))
+		span.End()
+	}()
+
+	logger := logging.FromContext(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		logger.Warn("Monitor probe failed", "error", err)
+		return "down"
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Warn("Monitor probe failed", "error", err)
+		}
+		return "down"
+	}
+	defer resp.Body.Close()
+
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxBytes+1))
+	if n > maxBytes {
+		logger.Warn("Monitor probe failed: response too large", "max_bytes", maxBytes)
+		return "down"
+	}
+	if err != nil && ctx.Err() == nil {
+		logger.Warn("Monitor probe failed", "error", err)
+		return "down"
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 500 {
+		return "up"
+	}
+	return "down"
+}
+
+// updateStatus safely updates the status of a monitor and records the check in its history and metrics.
+func (m *Manager) updateStatus(id, status string, took time.Duration) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if s, ok := m.statuses[id]; ok {
-		s.Status = status
+
+	s, ok := m.statuses[id]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	s.Status = status
+	s.History = append(s.History, Check{Time: now, Status: status})
+	if len(s.History) > historySize {
+		s.History = append([]Check(nil), s.History[len(s.History)-historySize:]...)
+	}
+
+	mon, ok := m.monitors[id]
+	if !ok {
+		return
+	}
+	metrics.MonitorProbeDuration.WithLabelValues(id, status).Observe(took.Seconds())
+	up := 0.0
+	if status == "up" {
+		up = 1
+	}
+	metrics.MonitorUp.WithLabelValues(id, mon.URL).Set(up)
+
+	if err := m.store.Save(recordOf(mon, s)); err != nil {
+		mon.logger.Error("Failed to persist monitor status", "error", err)
+	}
+	if t := m.observeLocked(mon, status, now); t != nil {
+		mon.logger.Info("Monitor state changed", "from", t.From, "to", t.To)
+		mon.firing = nil
+		if t.To == "down" {
+			t.ExpiresAt = now.Add(m.alertLifetime())
+			mon.firing = t
+		}
+		m.dispatchLocked(mon, *t, m.opts.Notifiers)
+	} else if mon.firing != nil && status == "down" {
+		// Keep the alert from lapsing while the monitor is still down
+		t := *mon.firing
+		t.ExpiresAt = now.Add(m.alertLifetime())
+		m.dispatchLocked(mon, t, m.refreshers())
+	}
+}
+
+// alertLifetime is how long a firing alert lasts without being sent again. It spans the
+// checks an up state needs to be confirmed, so that an alert does not lapse before it is
+// resolved.
+func (m *Manager) alertLifetime() time.Duration {
+	return time.Duration(m.opts.AlertDebounce+3) * m.opts.Interval
+}
+
+// refreshers returns the notifiers that are sent firing alerts again.
+func (m *Manager) refreshers() []Notifier {
+	var refreshers []Notifier
+	for _, n := range m.opts.Notifiers {
+		if r, ok := n.(Refresher); ok && r.RefreshesAlerts() {
+			refreshers = append(refreshers, n)
+		}
+	}
+	return refreshers
+}
+
+// observeLocked feeds a check result into the monitor's debounce state and returns a
+// transition once a new state has held for AlertDebounce consecutive checks.
+// The first confirmed state only establishes a baseline. The caller must hold m.mu.
+func (m *Manager) observeLocked(mon *Monitor, status string, now time.Time) *Transition {
+	if status == mon.reported {
+		mon.pending, mon.pendingCount = "", 0
+		return nil
+	}
+
+	if status == mon.pending {
+		mon.pendingCount++
+	} else {
+		mon.pending, mon.pendingCount = status, 1
+	}
+	if mon.pendingCount < m.opts.AlertDebounce {
+		return nil
+	}
+
+	previous, since := mon.reported, mon.reportedAt
+	mon.reported, mon.reportedAt = status, now
+	mon.pending, mon.pendingCount = "", 0
+	if previous == "" {
+		return nil
+	}
+	metrics.MonitorTransitions.WithLabelValues(status).Inc()
+
+	return &Transition{
+		ID:    mon.ID,
+		URL:   mon.URL,
+		From:  previous,
+		To:    status,
+		Time:  now,
+		Since: since,
 	}
 }
 
-// Stop terminates monitoring for a given ID.
+// dispatchLocked delivers a transition to notifiers in the background. The caller must hold m.mu.
+func (m *Manager) dispatchLocked(mon *Monitor, t Transition, notifiers []Notifier) {
+	for _, n := range notifiers {
+		m.wg.Add(1)
+		go func(n Notifier) {
+			defer m.wg.Done()
+			if err := n.Notify(m.notifyCtx, t); err != nil {
+				mon.logger.Error("Failed to deliver alert", "error", err)
+			}
+		}(n)
+	}
+}
+
+// recordOf builds the persisted form of a monitor.
+func recordOf(mon *Monitor, s *Status) Record {
+	return Record{
+		ID:        mon.ID,
+		URL:       mon.URL,
+		Owner:     mon.Owner,
+		CreatedAt: mon.CreatedAt,
+		Status:    s.Status,
+		History:   append([]Check(nil), s.History...),
+	}
+}
+
+// Stop terminates monitoring for a given ID regardless of its owner.
 func (m *Manager) Stop(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if _, ok := m.monitors[id]; !ok {
+		return fmt.Errorf("monitor with ID %s %w", id, ErrNotFound)
+	}
+
+	m.removeLocked(id)
+	return nil
+}
+
+// StopForOwner terminates monitoring for a given ID if it belongs to the given session.
+// Monitors started without an owner may be stopped by any session.
+func (m *Manager) StopForOwner(id, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	monitor, ok := m.monitors[id]
 	if !ok {
-		return fmt.Errorf("monitor with ID %s not found", id)
+		return fmt.Errorf("monitor with ID %s %w", id, ErrNotFound)
+	}
+	if monitor.Owner != "" && monitor.Owner != owner {
+		return fmt.Errorf("monitor with ID %s %w", id, ErrNotOwner)
 	}
 
-	monitor.Cancel()
+	m.removeLocked(id)
+	return nil
+}
+
+// removeLocked cancels a monitor and deletes it, including from the store. The caller must hold m.mu.
+func (m *Manager) removeLocked(id string) {
+	logger := slog.Default().With("monitor_id", id)
+	if monitor, ok := m.monitors[id]; ok {
+		monitor.Cancel()
+		forgetMetrics(monitor)
+		logger = monitor.logger
+	}
 	delete(m.monitors, id)
 	delete(m.statuses, id)
+	metrics.Monitors.Set(float64(len(m.monitors)))
+	if err := m.store.Delete(id); err != nil {
+		logger.Error("Failed to delete stored monitor", "error", err)
+	}
+}
 
-	return nil
+// forgetMetrics drops the per-monitor metric series of a stopped monitor.
+func forgetMetrics(mon *Monitor) {
+	metrics.MonitorUp.DeleteLabelValues(mon.ID, mon.URL)
+	metrics.MonitorProbeDuration.DeletePartialMatch(prometheus.Labels{"id": mon.ID})
 }
 
 // GetStatus retrieves the current status of a monitor.
@@ -153,9 +539,138 @@ func (m *Manager) GetStatus(id string) (*Status, error) {
 
 	status, ok := m.statuses[id]
 	if !ok {
-		return nil, fmt.Errorf("monitor status for ID %s not found", id)
+		return nil, fmt.Errorf("monitor status for ID %s %w", id, ErrNotFound)
 	}
 	// Return a copy
-	statusCopy := *status
+	statusCopy := copyStatus(status)
+	return &statusCopy, nil
+}
+
+// GetStatusForOwner retrieves the status of a monitor owned by the given session. Monitors
+// of other sessions are reported as not found, so that their IDs and URLs do not leak.
+func (m *Manager) GetStatusForOwner(id, owner string) (*Status, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	monitor, ok := m.monitors[id]
+	status, hasStatus := m.statuses[id]
+	if !ok || !hasStatus || (monitor.Owner != "" && monitor.Owner != owner) {
+		return nil, fmt.Errorf("monitor status for ID %s %w", id, ErrNotFound)
+	}
+	statusCopy := copyStatus(status)
 	return &statusCopy, nil
 }
+
+// List returns the statuses of all monitors, oldest first.
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listLocked(func(*Monitor) bool { return true })
+}
+
+// ListByOwner returns the statuses of all monitors owned by the given session, oldest first.
+func (m *Manager) ListByOwner(owner string) []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listLocked(func(mon *Monitor) bool { return mon.Owner == owner })
+}
+
+// listLocked copies the statuses of the monitors matching keep. The caller must hold m.mu.
+func (m *Manager) listLocked(keep func(*Monitor) bool) []Status {
+	statuses := make([]Status, 0, len(m.statuses))
+	for id, mon := range m.monitors {
+		if !keep(mon) {
+			continue
+		}
+		if s, ok := m.statuses[id]; ok {
+			statuses = append(statuses, copyStatus(s))
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].CreatedAt.Before(statuses[j].CreatedAt)
+	})
+	return statuses
+}
+
+// copyStatus returns a copy of s that shares no memory with it.
+func copyStatus(s *Status) Status {
+	c := *s
+	c.History = append([]Check(nil), s.History...)
+	return c
+}
+
+// Touch records a heartbeat from the given session, keeping its monitors alive.
+// It returns the number of monitors that were refreshed.
+func (m *Manager) Touch(owner string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	touched := 0
+	for id, mon := range m.monitors {
+		if mon.Owner != owner {
+			continue
+		}
+		mon.LastSeen = now
+		if s, ok := m.statuses[id]; ok {
+			s.LastSeen = now
+		}
+		touched++
+	}
+	return touched
+}
+
+// reapIdle periodically stops monitors whose owner has gone quiet.
+func (m *Manager) reapIdle() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.opts.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.expireIdle(time.Now())
+		}
+	}
+}
+
+// expireIdle stops every monitor that has not been touched since the idle timeout.
+func (m *Manager) expireIdle(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, mon := range m.monitors {
+		if now.Sub(mon.LastSeen) > m.opts.IdleTimeout {
+			mon.logger.Info("Monitor expired without a heartbeat", "idle_timeout", m.opts.IdleTimeout)
+			m.removeLocked(id)
+		}
+	}
+}
+
+// Close stops all monitors and the idle reaper, and waits for their goroutines to exit.
+// Stored monitors are kept so they can be restored on the next start.
+// The manager rejects new monitors once closed.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	close(m.done)
+	m.notifyCancel()
+	for id, monitor := range m.monitors {
+		monitor.Cancel()
+		forgetMetrics(monitor)
+		delete(m.monitors, id)
+		delete(m.statuses, id)
+	}
+	metrics.Monitors.Set(0)
+	m.mu.Unlock()
+
+	m.wg.Wait()
+	return nil
+}
@@ -2,15 +2,29 @@ package monitor_test
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"github.com/cldmnky/pod-invaders/internal/monitor"
+	"github.com/cldmnky/pod-invaders/internal/tracing"
 )
 
+// newLoopbackManager creates a manager whose egress policy allows probing local httptest servers.
+func newLoopbackManager(opts monitor.Options) *monitor.Manager {
+	opts.Egress.AllowedCIDRs = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	return monitor.NewManagerWithOptions(opts)
+}
+
 var _ = Describe("Monitor", func() {
 	var (
 		manager    *monitor.Manager
@@ -20,12 +34,13 @@ var _ = Describe("Monitor", func() {
 	)
 
 	BeforeEach(func() {
-		manager = monitor.NewManager()
+		manager = newLoopbackManager(monitor.DefaultOptions())
 		ctx, cancel = context.WithCancel(context.Background())
 	})
 
 	AfterEach(func() {
 		cancel()
+		manager.Close()
 		if testServer != nil {
 			testServer.Close()
 		}
@@ -254,7 +269,7 @@ var _ = Describe("Monitor", func() {
 		Context("when URL is unreachable", func() {
 			It("should update status to 'down'", func() {
 				// Use a non-existent URL
-				unreachableURL := "http://localhost:99999"
+				unreachableURL := "http://127.0.0.1:99999"
 
 				id, err := manager.Start(ctx, unreachableURL)
 				Expect(err).NotTo(HaveOccurred())
@@ -361,4 +376,209 @@ var _ = Describe("Monitor", func() {
 			}
 		})
 	})
+
+	Describe("Ownership", func() {
+		BeforeEach(func() {
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+		})
+
+		It("should only let the owning session stop a monitor", func() {
+			id, err := manager.StartForOwner(ctx, "alice", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = manager.StopForOwner(id, "bob")
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, monitor.ErrNotOwner)).To(BeTrue())
+
+			_, err = manager.GetStatus(id)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(manager.StopForOwner(id, "alice")).To(Succeed())
+		})
+
+		It("should only show a monitor's status to the owning session", func() {
+			id, err := manager.StartForOwner(ctx, "alice", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, err := manager.GetStatusForOwner(id, "alice")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.URL).To(Equal(testServer.URL))
+
+			_, err = manager.GetStatusForOwner(id, "bob")
+			Expect(errors.Is(err, monitor.ErrNotFound)).To(BeTrue())
+		})
+
+		It("should report unknown monitors as not found", func() {
+			err := manager.StopForOwner("non-existent-id", "alice")
+			Expect(errors.Is(err, monitor.ErrNotFound)).To(BeTrue())
+		})
+
+		It("should list monitors by owner", func() {
+			id1, err := manager.StartForOwner(ctx, "alice", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+			id2, err := manager.StartForOwner(ctx, "alice", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = manager.StartForOwner(ctx, "bob", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			owned := manager.ListByOwner("alice")
+			Expect(owned).To(HaveLen(2))
+			Expect([]string{owned[0].ID, owned[1].ID}).To(ConsistOf(id1, id2))
+			Expect(manager.List()).To(HaveLen(3))
+			Expect(manager.ListByOwner("carol")).To(BeEmpty())
+		})
+	})
+
+	Describe("Limits", func() {
+		BeforeEach(func() {
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+		})
+
+		It("should enforce the per-owner limit", func() {
+			m := newLoopbackManager(monitor.Options{MaxPerOwner: 1})
+			defer m.Close()
+
+			_, err := m.StartForOwner(ctx, "alice", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = m.StartForOwner(ctx, "alice", testServer.URL)
+			Expect(errors.Is(err, monitor.ErrLimitReached)).To(BeTrue())
+
+			_, err = m.StartForOwner(ctx, "bob", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should enforce the global limit", func() {
+			m := newLoopbackManager(monitor.Options{MaxMonitors: 2})
+			defer m.Close()
+
+			_, err := m.StartForOwner(ctx, "alice", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = m.StartForOwner(ctx, "bob", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = m.StartForOwner(ctx, "carol", testServer.URL)
+			Expect(errors.Is(err, monitor.ErrLimitReached)).To(BeTrue())
+		})
+	})
+
+	Describe("Idle expiry", func() {
+		BeforeEach(func() {
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+		})
+
+		It("should stop monitors that stop receiving heartbeats", func() {
+			m := newLoopbackManager(monitor.Options{
+				IdleTimeout:  300 * time.Millisecond,
+				ReapInterval: 50 * time.Millisecond,
+			})
+			defer m.Close()
+
+			idle, err := m.StartForOwner(ctx, "idle", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+			alive, err := m.StartForOwner(ctx, "alive", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			stopHeartbeat := make(chan struct{})
+			defer close(stopHeartbeat)
+			go func() {
+				ticker := time.NewTicker(50 * time.Millisecond)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-stopHeartbeat:
+						return
+					case <-ticker.C:
+						m.Touch("alive")
+					}
+				}
+			}()
+
+			Eventually(func() error {
+				_, err := m.GetStatus(idle)
+				return err
+			}, "2s", "50ms").Should(HaveOccurred())
+
+			_, err = m.GetStatus(alive)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should report how many monitors a heartbeat refreshed", func() {
+			_, err := manager.StartForOwner(ctx, "alice", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(manager.Touch("alice")).To(Equal(1))
+			Expect(manager.Touch("bob")).To(Equal(0))
+		})
+	})
+
+	Describe("Close", func() {
+		It("should stop all monitors and reject new ones", func() {
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			m := newLoopbackManager(monitor.DefaultOptions())
+			_, err := m.StartForOwner(ctx, "alice", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = m.StartForOwner(ctx, "bob", testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(m.Close()).To(Succeed())
+			Expect(m.List()).To(BeEmpty())
+
+			_, err = m.StartForOwner(ctx, "alice", testServer.URL)
+			Expect(err).To(HaveOccurred())
+
+			// Closing twice is a no-op
+			Expect(m.Close()).To(Succeed())
+		})
+	})
+
+	Describe("Tracing", func() {
+		It("should record each probe as a new trace linked to the starting request", func() {
+			exporter := tracetest.NewInMemoryExporter()
+			provider := tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), 1)
+			previous := otel.GetTracerProvider()
+			otel.SetTracerProvider(provider)
+			DeferCleanup(func() {
+				otel.SetTracerProvider(previous)
+				provider.Shutdown(context.Background())
+			})
+
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			requestCtx, request := tracing.Tracer().Start(ctx, "POST /monitor")
+			_, err := manager.Start(requestCtx, testServer.URL)
+			Expect(err).NotTo(HaveOccurred())
+			request.End()
+
+			var probe tracetest.SpanStub
+			Eventually(func() bool {
+				for _, span := range exporter.GetSpans() {
+					if span.Name == "monitor.probe" {
+						probe = span
+						return true
+					}
+				}
+				return false
+			}, "6s", "100ms").Should(BeTrue())
+
+			Expect(probe.Parent.IsValid()).To(BeFalse())
+			Expect(probe.Links).To(HaveLen(1))
+			Expect(probe.Links[0].SpanContext.SpanID()).To(Equal(request.SpanContext().SpanID()))
+			Expect(probe.Attributes).To(ContainElements(
+				attribute.String("url.full", testServer.URL),
+				attribute.String("monitor.status", "up"),
+			))
+		})
+	})
 })
@@ -0,0 +1,198 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Transition describes a monitor changing between "up" and "down".
+type Transition struct {
+	ID    string    `json:"id"`
+	URL   string    `json:"url"`
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	Time  time.Time `json:"time"`  // When the new state was confirmed
+	Since time.Time `json:"since"` // When the previous state was confirmed
+
+	// ExpiresAt is, for a transition to "down", when its alert lapses unless it is sent again
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+}
+
+// Notifier delivers monitor transitions to an external system.
+type Notifier interface {
+	Notify(ctx context.Context, t Transition) error
+}
+
+// Refresher is implemented by notifiers whose alerts lapse unless they are sent again,
+// such as Alertmanager's. While a monitor stays down, they are notified of its transition
+// to "down" again on every probe, with a later ExpiresAt.
+type Refresher interface {
+	RefreshesAlerts() bool
+}
+
+// Formatter renders a transition as a webhook request body.
+type Formatter func(t Transition) ([]byte, error)
+
+// formatters maps webhook format names to their formatter.
+var formatters = map[string]Formatter{
+	"json":         FormatJSON,
+	"slack":        FormatSlack,
+	"alertmanager": FormatAlertmanager,
+}
+
+// FormatJSON renders a transition as a generic JSON object.
+func FormatJSON(t Transition) ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// FormatSlack renders a transition as a Slack-compatible incoming webhook message.
+func FormatSlack(t Transition) ([]byte, error) {
+	icon := ":red_circle:"
+	if t.To == "up" {
+		icon = ":large_green_circle:"
+	}
+	return json.Marshal(map[string]string{
+		"text": fmt.Sprintf("%s Pod Invaders monitor for %s is *%s* (was %s since %s)",
+			icon, t.URL, strings.ToUpper(t.To), t.From, t.Since.UTC().Format(time.RFC3339)),
+	})
+}
+
+// alertmanagerAlert is a single alert in the Alertmanager API v2 format.
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      *time.Time        `json:"endsAt,omitempty"`
+}
+
+// FormatAlertmanager renders a transition for POST /api/v2/alerts.
+// Going down fires an alert that ends at ExpiresAt unless it is sent again; coming back
+// up resolves it.
+func FormatAlertmanager(t Transition) ([]byte, error) {
+	alert := alertmanagerAlert{
+		Labels: map[string]string{
+			"alertname":  "PodInvadersMonitorDown",
+			"monitor_id": t.ID,
+			"url":        t.URL,
+			"severity":   "critical",
+		},
+		Annotations: map[string]string{
+			"summary": fmt.Sprintf("Monitored URL %s is down", t.URL),
+		},
+		StartsAt: t.Time,
+	}
+	if t.To == "down" && !t.ExpiresAt.IsZero() {
+		endsAt := t.ExpiresAt
+		alert.EndsAt = &endsAt
+	}
+	if t.To == "up" {
+		alert.StartsAt = t.Since
+		endsAt := t.Time
+		alert.EndsAt = &endsAt
+	}
+	return json.Marshal([]alertmanagerAlert{alert})
+}
+
+// WebhookNotifier posts transitions to an HTTP endpoint, retrying failed deliveries with exponential backoff.
+type WebhookNotifier struct {
+	URL        string
+	Format     Formatter
+	Client     *http.Client
+	MaxRetries int           // Additional attempts after the first failure
+	Backoff    time.Duration // Delay before the first retry, doubled on each attempt
+	Refresh    bool          // Send firing alerts again while the monitor stays down, see Refresher
+}
+
+// NewWebhookNotifier creates a notifier for the named format ("json", "slack" or "alertmanager").
+func NewWebhookNotifier(format, url string, maxRetries int) (*WebhookNotifier, error) {
+	f, ok := formatters[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown webhook format %q", format)
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("webhook URL %q must be http or https", url)
+	}
+	return &WebhookNotifier{
+		URL:        url,
+		Format:     f,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		MaxRetries: maxRetries,
+		Backoff:    time.Second,
+		Refresh:    format == "alertmanager",
+	}, nil
+}
+
+// RefreshesAlerts reports whether firing alerts are sent again while the monitor stays down.
+func (w *WebhookNotifier) RefreshesAlerts() bool {
+	return w.Refresh
+}
+
+// ParseWebhook parses a webhook specification of the form "format=url".
+// A bare URL uses the generic JSON format.
+func ParseWebhook(spec string, maxRetries int) (*WebhookNotifier, error) {
+	if format, url, ok := strings.Cut(spec, "="); ok {
+		if _, known := formatters[format]; known {
+			return NewWebhookNotifier(format, url, maxRetries)
+		}
+	}
+	return NewWebhookNotifier("json", spec, maxRetries)
+}
+
+// Notify delivers a transition, retrying on network errors, 429 and 5xx responses.
+func (w *WebhookNotifier) Notify(ctx context.Context, t Transition) error {
+	body, err := w.Format(t)
+	if err != nil {
+		return fmt.Errorf("failed to format webhook payload: %w", err)
+	}
+
+	backoff := w.Backoff
+	var lastErr error
+	for attempt := 0; attempt <= w.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("webhook %s cancelled after %d attempts: %w", w.URL, attempt, lastErr)
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return fmt.Errorf("webhook %s failed: %w", w.URL, lastErr)
+}
+
+// post sends a single webhook request and reports whether a failure is worth retrying.
+func (w *WebhookNotifier) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
@@ -0,0 +1,302 @@
+package monitor_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cldmnky/pod-invaders/internal/monitor"
+)
+
+// recordingNotifier collects the transitions it is notified about.
+type recordingNotifier struct {
+	mu          sync.Mutex
+	transitions []monitor.Transition
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, t monitor.Transition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transitions = append(r.transitions, t)
+	return nil
+}
+
+// refreshingNotifier is a recordingNotifier whose alerts lapse, like Alertmanager's.
+type refreshingNotifier struct {
+	recordingNotifier
+}
+
+func (r *refreshingNotifier) RefreshesAlerts() bool {
+	return true
+}
+
+// expiries returns when the alerts of the recorded transitions lapse.
+func (r *recordingNotifier) expiries() []time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expiries := make([]time.Time, 0, len(r.transitions))
+	for _, t := range r.transitions {
+		expiries = append(expiries, t.ExpiresAt)
+	}
+	return expiries
+}
+
+func (r *recordingNotifier) seen() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make([]string, 0, len(r.transitions))
+	for _, t := range r.transitions {
+		seen = append(seen, t.From+"->"+t.To)
+	}
+	return seen
+}
+
+var _ = Describe("Notifications", func() {
+	transition := monitor.Transition{
+		ID:    "1234",
+		URL:   "http://shop.example.com/health",
+		From:  "up",
+		To:    "down",
+		Time:  time.Date(2025, 1, 1, 12, 5, 0, 0, time.UTC),
+		Since: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	Describe("Formatters", func() {
+		It("should render generic JSON", func() {
+			body, err := monitor.FormatJSON(transition)
+			Expect(err).NotTo(HaveOccurred())
+
+			var decoded monitor.Transition
+			Expect(json.Unmarshal(body, &decoded)).To(Succeed())
+			Expect(decoded).To(Equal(transition))
+		})
+
+		It("should render a Slack message", func() {
+			body, err := monitor.FormatSlack(transition)
+			Expect(err).NotTo(HaveOccurred())
+
+			var msg map[string]string
+			Expect(json.Unmarshal(body, &msg)).To(Succeed())
+			Expect(msg["text"]).To(ContainSubstring("http://shop.example.com/health"))
+			Expect(msg["text"]).To(ContainSubstring("*DOWN*"))
+		})
+
+		It("should fire and resolve Alertmanager alerts", func() {
+			body, err := monitor.FormatAlertmanager(transition)
+			Expect(err).NotTo(HaveOccurred())
+
+			var alerts []map[string]interface{}
+			Expect(json.Unmarshal(body, &alerts)).To(Succeed())
+			Expect(alerts).To(HaveLen(1))
+			Expect(alerts[0]["labels"]).To(HaveKeyWithValue("alertname", "PodInvadersMonitorDown"))
+			Expect(alerts[0]["labels"]).To(HaveKeyWithValue("monitor_id", "1234"))
+			Expect(alerts[0]).NotTo(HaveKey("endsAt"))
+
+			resolved := transition
+			resolved.From, resolved.To = "down", "up"
+			body, err = monitor.FormatAlertmanager(resolved)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(body, &alerts)).To(Succeed())
+			Expect(alerts[0]["startsAt"]).To(Equal("2025-01-01T12:00:00Z"))
+			Expect(alerts[0]["endsAt"]).To(Equal("2025-01-01T12:05:00Z"))
+		})
+
+		It("should let firing Alertmanager alerts lapse at ExpiresAt", func() {
+			firing := transition
+			firing.ExpiresAt = time.Date(2025, 1, 1, 12, 6, 0, 0, time.UTC)
+			body, err := monitor.FormatAlertmanager(firing)
+			Expect(err).NotTo(HaveOccurred())
+
+			var alerts []map[string]interface{}
+			Expect(json.Unmarshal(body, &alerts)).To(Succeed())
+			Expect(alerts[0]["startsAt"]).To(Equal("2025-01-01T12:05:00Z"))
+			Expect(alerts[0]["endsAt"]).To(Equal("2025-01-01T12:06:00Z"))
+		})
+	})
+
+	Describe("ParseWebhook", func() {
+		It("should select the format from the prefix", func() {
+			w, err := monitor.ParseWebhook("alertmanager=http://alertmanager:9093/api/v2/alerts", 3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.URL).To(Equal("http://alertmanager:9093/api/v2/alerts"))
+			Expect(w.MaxRetries).To(Equal(3))
+			Expect(w.RefreshesAlerts()).To(BeTrue())
+		})
+
+		It("should default to JSON for bare URLs", func() {
+			w, err := monitor.ParseWebhook("https://hooks.example.com/notify?token=abc", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.URL).To(Equal("https://hooks.example.com/notify?token=abc"))
+			Expect(w.RefreshesAlerts()).To(BeFalse())
+		})
+
+		It("should reject non-http URLs and unknown formats", func() {
+			_, err := monitor.ParseWebhook("ftp://example.com", 0)
+			Expect(err).To(HaveOccurred())
+			_, err = monitor.NewWebhookNotifier("teams", "https://example.com", 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("WebhookNotifier", func() {
+		var (
+			receiver *httptest.Server
+			requests atomic.Int32
+			failures int32
+			code     int
+		)
+
+		BeforeEach(func() {
+			requests.Store(0)
+			receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+				body, _ := io.ReadAll(r.Body)
+				Expect(body).NotTo(BeEmpty())
+
+				if requests.Add(1) <= failures {
+					w.WriteHeader(code)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+		})
+
+		AfterEach(func() {
+			receiver.Close()
+		})
+
+		newNotifier := func(retries int) *monitor.WebhookNotifier {
+			w, err := monitor.NewWebhookNotifier("json", receiver.URL, retries)
+			Expect(err).NotTo(HaveOccurred())
+			w.Backoff = time.Millisecond
+			return w
+		}
+
+		It("should retry server errors with backoff", func() {
+			failures, code = 2, http.StatusServiceUnavailable
+			Expect(newNotifier(3).Notify(context.Background(), transition)).To(Succeed())
+			Expect(requests.Load()).To(Equal(int32(3)))
+		})
+
+		It("should give up after the configured retries", func() {
+			failures, code = 10, http.StatusInternalServerError
+			Expect(newNotifier(2).Notify(context.Background(), transition)).NotTo(Succeed())
+			Expect(requests.Load()).To(Equal(int32(3)))
+		})
+
+		It("should not retry client errors", func() {
+			failures, code = 10, http.StatusBadRequest
+			Expect(newNotifier(3).Notify(context.Background(), transition)).NotTo(Succeed())
+			Expect(requests.Load()).To(Equal(int32(1)))
+		})
+	})
+
+	Describe("Manager transitions", func() {
+		var (
+			ctx      context.Context
+			cancel   context.CancelFunc
+			target   *httptest.Server
+			notifier *recordingNotifier
+			refresh  *refreshingNotifier
+			m        *monitor.Manager
+		)
+
+		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+			notifier = &recordingNotifier{}
+			refresh = &refreshingNotifier{}
+		})
+
+		AfterEach(func() {
+			cancel()
+			m.Close()
+			target.Close()
+		})
+
+		startManager := func(handler http.HandlerFunc) string {
+			target = httptest.NewServer(handler)
+			opts := monitor.DefaultOptions()
+			opts.Interval = 20 * time.Millisecond
+			opts.AlertDebounce = 2
+			opts.Notifiers = []monitor.Notifier{notifier, refresh}
+			m = newLoopbackManager(opts)
+
+			id, err := m.Start(ctx, target.URL)
+			Expect(err).NotTo(HaveOccurred())
+			return id
+		}
+
+		It("should notify on up->down and down->up", func() {
+			var healthy atomic.Bool
+			healthy.Store(true)
+			startManager(func(w http.ResponseWriter, r *http.Request) {
+				if healthy.Load() {
+					w.WriteHeader(http.StatusOK)
+				} else {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			})
+
+			// Let the baseline settle; it must not alert
+			Consistently(notifier.seen, "150ms", "20ms").Should(BeEmpty())
+
+			healthy.Store(false)
+			Eventually(notifier.seen, "2s", "20ms").Should(Equal([]string{"up->down"}))
+
+			healthy.Store(true)
+			Eventually(notifier.seen, "2s", "20ms").Should(Equal([]string{"up->down", "down->up"}))
+		})
+
+		It("should send firing alerts again to refreshers while down", func() {
+			var healthy atomic.Bool
+			healthy.Store(true)
+			startManager(func(w http.ResponseWriter, r *http.Request) {
+				if healthy.Load() {
+					w.WriteHeader(http.StatusOK)
+				} else {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			})
+			Consistently(refresh.seen, "100ms", "20ms").Should(BeEmpty())
+
+			healthy.Store(false)
+			Eventually(func() int { return len(refresh.seen()) }, "2s", "20ms").Should(BeNumerically(">=", 3))
+			Expect(refresh.seen()).To(HaveEach("up->down"))
+			expiries := refresh.expiries()
+			Expect(expiries[0]).NotTo(BeZero())
+			Expect(expiries[len(expiries)-1]).To(BeTemporally(">", expiries[0]))
+			// Other notifiers hear of the transition once
+			Expect(notifier.seen()).To(Equal([]string{"up->down"}))
+
+			healthy.Store(true)
+			Eventually(refresh.seen, "2s", "20ms").Should(ContainElement("down->up"))
+			count := len(refresh.seen())
+			Consistently(func() int { return len(refresh.seen()) }, "100ms", "20ms").Should(Equal(count))
+		})
+
+		It("should debounce flapping targets", func() {
+			var calls atomic.Int32
+			startManager(func(w http.ResponseWriter, r *http.Request) {
+				// Baseline up for a few checks, then alternate on every check
+				n := calls.Add(1)
+				if n <= 3 || n%2 == 0 {
+					w.WriteHeader(http.StatusOK)
+				} else {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			})
+
+			Eventually(calls.Load, "2s", "20ms").Should(BeNumerically(">", 10))
+			Expect(notifier.seen()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// historySize is the number of recent checks kept for each monitor.
+const historySize = 20
+
+// Check is the outcome of a single probe.
+type Check struct {
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+}
+
+// Record is the persisted form of a monitor.
+type Record struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Owner     string    `json:"owner"`
+	CreatedAt time.Time `json:"createdAt"`
+	Status    string    `json:"status"`
+	History   []Check   `json:"history,omitempty"`
+}
+
+// Store persists monitor definitions and their recent history.
+type Store interface {
+	Save(rec Record) error
+	Delete(id string) error
+	List() ([]Record, error)
+}
+
+// MemoryStore keeps monitor records in memory. It is the default store and does not survive restarts.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+// NewMemoryStore creates a new in-memory monitor store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]Record),
+	}
+}
+
+// Save stores or replaces a monitor record.
+func (s *MemoryStore) Save(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec.History = append([]Check(nil), rec.History...)
+	s.records[rec.ID] = rec
+	return nil
+}
+
+// Delete removes a monitor record.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, id)
+	return nil
+}
+
+// List returns all stored monitor records, oldest first.
+func (s *MemoryStore) List() ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := make([]Record, 0, len(s.records))
+	for _, rec := range s.records {
+		rec.History = append([]Check(nil), rec.History...)
+		records = append(records, rec)
+	}
+	sortRecords(records)
+	return records, nil
+}
+
+// badgerKeyPrefix namespaces monitor records within a shared BadgerDB.
+const badgerKeyPrefix = "monitor_"
+
+// BadgerStore persists monitor records in BadgerDB.
+type BadgerStore struct {
+	db *badger.DB
+}
+
+// NewBadgerStore creates a monitor store backed by an already open BadgerDB.
+// The caller remains responsible for closing db.
+func NewBadgerStore(db *badger.DB) *BadgerStore {
+	return &BadgerStore{db: db}
+}
+
+// Save stores or replaces a monitor record.
+func (s *BadgerStore) Save(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal monitor %s: %w", rec.ID, err)
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(badgerKeyPrefix+rec.ID), data)
+	})
+}
+
+// Delete removes a monitor record.
+func (s *BadgerStore) Delete(id string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(badgerKeyPrefix + id))
+	})
+}
+
+// List returns all stored monitor records, oldest first.
+func (s *BadgerStore) List() ([]Record, error) {
+	var records []Record
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(badgerKeyPrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var rec Record
+				if err := json.Unmarshal(val, &rec); err != nil {
+					return fmt.Errorf("failed to unmarshal monitor %s: %w", it.Item().Key(), err)
+				}
+				records = append(records, rec)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortRecords(records)
+	return records, nil
+}
+
+// sortRecords orders records by creation time.
+func sortRecords(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+}
@@ -0,0 +1,183 @@
+package monitor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cldmnky/pod-invaders/internal/monitor"
+)
+
+var _ = Describe("Store", func() {
+	record := func(id string, created time.Time) monitor.Record {
+		return monitor.Record{
+			ID:        id,
+			URL:       "http://example.com/" + id,
+			Owner:     "alice",
+			CreatedAt: created,
+			Status:    "up",
+			History:   []monitor.Check{{Time: created, Status: "up"}},
+		}
+	}
+
+	// storeBehaviour exercises the contract shared by all Store implementations.
+	storeBehaviour := func(newStore func() monitor.Store) {
+		It("should save, list and delete records", func() {
+			store := newStore()
+			now := time.Now().UTC().Truncate(time.Second)
+
+			Expect(store.Save(record("b", now.Add(time.Second)))).To(Succeed())
+			Expect(store.Save(record("a", now))).To(Succeed())
+
+			records, err := store.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records).To(HaveLen(2))
+			Expect(records[0].ID).To(Equal("a"))
+			Expect(records[1].ID).To(Equal("b"))
+			Expect(records[0].CreatedAt.Equal(now)).To(BeTrue())
+			Expect(records[0].History).To(HaveLen(1))
+
+			Expect(store.Delete("a")).To(Succeed())
+			records, err = store.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].ID).To(Equal("b"))
+		})
+
+		It("should replace records with the same ID", func() {
+			store := newStore()
+			rec := record("a", time.Now())
+			Expect(store.Save(rec)).To(Succeed())
+
+			rec.Status = "down"
+			Expect(store.Save(rec)).To(Succeed())
+
+			records, err := store.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Status).To(Equal("down"))
+		})
+	}
+
+	Describe("MemoryStore", func() {
+		storeBehaviour(func() monitor.Store { return monitor.NewMemoryStore() })
+	})
+
+	Describe("BadgerStore", func() {
+		storeBehaviour(func() monitor.Store {
+			opts := badger.DefaultOptions(filepath.Join(GinkgoT().TempDir(), "monitors"))
+			opts.Logger = nil
+			db, err := badger.Open(opts)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(db.Close)
+			return monitor.NewBadgerStore(db)
+		})
+	})
+})
+
+var _ = Describe("Persistence", func() {
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		testServer *httptest.Server
+		store      *monitor.MemoryStore
+	)
+
+	newManager := func() *monitor.Manager {
+		opts := monitor.DefaultOptions()
+		opts.Store = store
+		m := newLoopbackManager(opts)
+		DeferCleanup(m.Close)
+		return m
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		store = monitor.NewMemoryStore()
+		testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	AfterEach(func() {
+		cancel()
+		testServer.Close()
+	})
+
+	It("should resume monitors with the same IDs after a restart", func() {
+		first := newManager()
+		id, err := first.StartForOwner(ctx, "alice", testServer.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() string {
+			status, _ := first.GetStatus(id)
+			return status.Status
+		}, "6s", "100ms").Should(Equal("up"))
+		Expect(first.Close()).To(Succeed())
+
+		second := newManager()
+		restored, err := second.Restore(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored).To(Equal(1))
+
+		status, err := second.GetStatus(id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.URL).To(Equal(testServer.URL))
+		Expect(status.Status).To(Equal("up"))
+		Expect(status.History).NotTo(BeEmpty())
+
+		// Ownership survives the restart
+		Expect(second.ListByOwner("alice")).To(HaveLen(1))
+		Expect(second.StopForOwner(id, "bob")).To(MatchError(monitor.ErrNotOwner))
+	})
+
+	It("should forget stopped monitors", func() {
+		m := newManager()
+		id, err := m.Start(ctx, testServer.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Stop(id)).To(Succeed())
+
+		records, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(BeEmpty())
+	})
+
+	It("should discard stored monitors the egress policy no longer allows", func() {
+		Expect(store.Save(monitor.Record{
+			ID:        "metadata",
+			URL:       "http://169.254.169.254/",
+			CreatedAt: time.Now(),
+			Status:    "unknown",
+		})).To(Succeed())
+
+		m := newManager()
+		restored, err := m.Restore(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored).To(Equal(0))
+
+		records, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(BeEmpty())
+	})
+
+	It("should keep a bounded history of checks", func() {
+		m := newManager()
+		id, err := m.Start(ctx, testServer.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() int {
+			status, _ := m.GetStatus(id)
+			return len(status.History)
+		}, "6s", "100ms").Should(BeNumerically(">=", 1))
+
+		status, err := m.GetStatus(id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(status.History)).To(BeNumerically("<=", 20))
+		Expect(status.History[len(status.History)-1].Status).To(Equal("up"))
+	})
+})
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Off disables a limit.
+const Off = "off"
+
+// sweepInterval is how often buckets of clients that went quiet are dropped.
+const sweepInterval = time.Minute
+
+// Limit is a token bucket budget: Rate tokens per second, up to Burst at once.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit parses a limit written as "rate:burst", e.g. "2:10" for two requests per
+// second with bursts of ten. An empty string or "off" returns a zero Limit, which disables limiting.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == Off {
+		return Limit{}, nil
+	}
+	rateStr, burstStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: must be rate:burst or %s", s, Off)
+	}
+	r, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || r <= 0 || math.IsInf(r, 0) {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: rate must be a positive number", s)
+	}
+	burst, err := strconv.Atoi(burstStr)
+	if err != nil || burst < 1 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+	}
+	return Limit{Rate: r, Burst: burst}, nil
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0
+}
+
+// String formats the limit the way ParseLimit reads it.
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return Off
+	}
+	return strconv.FormatFloat(l.Rate, 'f', -1, 64) + ":" + strconv.Itoa(l.Burst)
+}
+
+// bucket is the token bucket of one client.
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter keeps a token bucket per client key.
+type Limiter struct {
+	limit Limit
+	idle  time.Duration // Buckets unused for this long are full again and can be dropped
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New creates a limiter that gives every key its own bucket with the given limit.
+func New(limit Limit) *Limiter {
+	refill := time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))
+	return &Limiter{
+		limit:   limit,
+		idle:    max(refill, sweepInterval),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of key. If none is left, it returns false and
+// how long the client should wait before the next request can succeed.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(l.limit.Rate), l.limit.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// Len returns the number of clients with a bucket.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// sweep drops the buckets of clients that have been idle long enough to be full again.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.idle {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "2:10", want: Limit{Rate: 2, Burst: 10}},
+		{in: "0.5:1", want: Limit{Rate: 0.5, Burst: 1}},
+		{in: "off", want: Limit{}},
+		{in: "", want: Limit{}},
+		{in: "10", wantErr: true},
+		{in: "0:5", wantErr: true},
+		{in: "-1:5", wantErr: true},
+		{in: "1:0", wantErr: true},
+		{in: "fast:5", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+	if s := (Limit{Rate: 0.5, Burst: 3}).String(); s != "0.5:3" {
+		t.Errorf("Expected 0.5:3, got %s", s)
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := New(Limit{Rate: 1, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("alice"); !ok {
+			t.Fatalf("Request %d within the burst was rejected", i+1)
+		}
+	}
+	ok, retryAfter := l.Allow("alice")
+	if ok {
+		t.Fatal("Expected the request after the burst to be rejected")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("Expected a retry delay of up to 1s, got %v", retryAfter)
+	}
+
+	// Other clients have their own bucket
+	if ok, _ := l.Allow("bob"); !ok {
+		t.Error("Expected another client to be allowed")
+	}
+
+	// Rejected requests do not consume tokens, so one second refills one token
+	now = now.Add(time.Second)
+	if ok, _ := l.Allow("alice"); !ok {
+		t.Error("Expected a token to be available after waiting")
+	}
+	if ok, _ := l.Allow("alice"); ok {
+		t.Error("Expected only one token to be refilled")
+	}
+}
+
+func TestLimiterSweepsIdleClients(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := New(Limit{Rate: 10, Burst: 10})
+	l.now = func() time.Time { return now }
+
+	l.Allow("alice")
+	l.Allow("bob")
+	if l.Len() != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", l.Len())
+	}
+
+	now = now.Add(2 * sweepInterval)
+	l.Allow("carol")
+	if l.Len() != 1 {
+		t.Errorf("Expected idle buckets to be dropped, got %d buckets", l.Len())
+	}
+}
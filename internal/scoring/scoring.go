@@ -0,0 +1,150 @@
+// Package scoring decides how many hits a real pod takes to kill, from signals of how
+// important it is to its cluster.
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+)
+
+// DefaultRules are the rules and weights used unless configured otherwise.
+var DefaultRules = []string{"priority=4", "pdb=4", "replicas=8", "qos=2"}
+
+// Rule rates how important a pod is on one signal, from 0 (expendable) to 1 (critical).
+type Rule func(p game.Pod) float64
+
+var (
+	mu    sync.RWMutex
+	rules = map[string]Rule{
+		"priority": Priority,
+		"pdb":      PDB,
+		"replicas": Replicas,
+		"qos":      QOS,
+	}
+)
+
+// Register makes a rule available to configurations under name, replacing any rule
+// registered under the same name.
+func Register(name string, rule Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+	rules[name] = rule
+}
+
+// Names returns the names of the registered rules in alphabetical order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Priority rates pods by their scheduling priority on a logarithmic scale, so that a
+// priority of one billion, the highest user-defined one, rates 1.
+func Priority(p game.Pod) float64 {
+	if p.Priority <= 0 {
+		return 0
+	}
+	return min(math.Log10(float64(p.Priority)+1)/9, 1)
+}
+
+// PDB rates pods covered by a PodDisruptionBudget as critical.
+func PDB(p game.Pod) float64 {
+	if p.CoveredByPDB {
+		return 1
+	}
+	return 0
+}
+
+// Replicas rates singletons as critical, with importance shrinking as replicas are added:
+// one of two rates 0.5, one of fifty 0.02.
+func Replicas(p game.Pod) float64 {
+	if p.OwnerReplicas <= 1 {
+		return 1
+	}
+	return 1 / float64(p.OwnerReplicas)
+}
+
+// QOS rates Guaranteed pods above Burstable ones, and BestEffort pods as expendable.
+func QOS(p game.Pod) float64 {
+	switch p.QOSClass {
+	case "Guaranteed":
+		return 1
+	case "Burstable":
+		return 0.5
+	}
+	return 0
+}
+
+// weightedRule is a configured rule.
+type weightedRule struct {
+	name   string
+	weight float64
+	rule   Rule
+}
+
+// Scorer turns the importance of real pods into hit points.
+type Scorer struct {
+	base, max int
+	rules     []weightedRule
+}
+
+// New creates a scorer from rules written as "name=weight". A pod takes base hits plus the
+// weighted sum of its rule ratings, rounded, and at most max.
+func New(specs []string, base, max int) (*Scorer, error) {
+	if base < 1 {
+		return nil, fmt.Errorf("base hit points must be at least 1")
+	}
+	if max < base {
+		return nil, fmt.Errorf("maximum hit points must be at least the base of %d", base)
+	}
+
+	s := &Scorer{base: base, max: max}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, spec := range specs {
+		name, weightStr, ok := strings.Cut(strings.TrimSpace(spec), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule %q: must be name=weight", spec)
+		}
+		rule, ok := rules[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		weight, err := strconv.ParseFloat(weightStr, 64)
+		if err != nil || weight < 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+			return nil, fmt.Errorf("invalid rule %q: weight must be a non-negative number", spec)
+		}
+		s.rules = append(s.rules, weightedRule{name: name, weight: weight, rule: rule})
+	}
+	return s, nil
+}
+
+// HitPoints returns the number of hits it takes to kill a pod. Fake pods take one.
+func (s *Scorer) HitPoints(p game.Pod) int {
+	if !p.IsRealPod {
+		return 1
+	}
+	total := float64(s.base)
+	for _, r := range s.rules {
+		total += r.weight * r.rule(p)
+	}
+	return min(int(math.Round(total)), s.max)
+}
+
+// Apply sets the hit points of pods.
+func (s *Scorer) Apply(pods []game.Pod) {
+	for i := range pods {
+		pods[i].HitPoints = s.HitPoints(pods[i])
+	}
+}
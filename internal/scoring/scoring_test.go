@@ -0,0 +1,110 @@
+package scoring
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+)
+
+func TestHitPoints(t *testing.T) {
+	scorer, err := New(DefaultRules, 1, 20)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		pod  game.Pod
+		want int
+	}{
+		{
+			name: "fake pod",
+			pod:  game.Pod{Priority: 1_000_000_000, CoveredByPDB: true, QOSClass: "Guaranteed"},
+			want: 1,
+		},
+		{
+			name: "one of fifty best effort replicas",
+			pod:  game.Pod{IsRealPod: true, OwnerReplicas: 50, QOSClass: "BestEffort"},
+			want: 1,
+		},
+		{
+			name: "one of two burstable replicas",
+			pod:  game.Pod{IsRealPod: true, OwnerReplicas: 2, QOSClass: "Burstable"},
+			want: 6, // 1 + 8*0.5 + 2*0.5
+		},
+		{
+			name: "critical singleton",
+			pod:  game.Pod{IsRealPod: true, Priority: 1_000_000_000, CoveredByPDB: true, OwnerReplicas: 1, QOSClass: "Guaranteed"},
+			want: 19,
+		},
+		{
+			name: "system priority is capped",
+			pod:  game.Pod{IsRealPod: true, Priority: 2_000_001_000, OwnerReplicas: 1000},
+			want: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scorer.HitPoints(tt.pod); got != tt.want {
+				t.Errorf("HitPoints() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHitPointsMax(t *testing.T) {
+	scorer, err := New([]string{"pdb=100"}, 2, 8)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	pods := []game.Pod{
+		{IsRealPod: true, CoveredByPDB: true},
+		{IsRealPod: true},
+	}
+	scorer.Apply(pods)
+	if pods[0].HitPoints != 8 || pods[1].HitPoints != 2 {
+		t.Errorf("Expected hit points 8 and 2, got %d and %d", pods[0].HitPoints, pods[1].HitPoints)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     []string
+		base, max int
+	}{
+		{name: "missing weight", rules: []string{"pdb"}, base: 1, max: 20},
+		{name: "unknown rule", rules: []string{"age=3"}, base: 1, max: 20},
+		{name: "negative weight", rules: []string{"pdb=-1"}, base: 1, max: 20},
+		{name: "non-numeric weight", rules: []string{"pdb=lots"}, base: 1, max: 20},
+		// NaN compares false with everything, so it would pass the range check
+		{name: "NaN weight", rules: []string{"pdb=NaN"}, base: 1, max: 20},
+		{name: "zero base", rules: DefaultRules, base: 0, max: 20},
+		{name: "max below base", rules: DefaultRules, base: 5, max: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.rules, tt.base, tt.max); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("restarts", func(p game.Pod) float64 {
+		return min(float64(p.RestartCount)/10, 1)
+	})
+	if !slices.Contains(Names(), "restarts") {
+		t.Fatalf("Expected restarts in %v", Names())
+	}
+
+	scorer, err := New([]string{"restarts=10"}, 1, 20)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got := scorer.HitPoints(game.Pod{IsRealPod: true, RestartCount: 5}); got != 6 {
+		t.Errorf("HitPoints() = %d, want 6", got)
+	}
+}
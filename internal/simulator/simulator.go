@@ -0,0 +1,526 @@
+// Package simulator runs an in-process Kubernetes cluster for standalone mode. It backs a
+// client-go fake clientset with Deployments and ReplicaSets whose controllers replace
+// killed pods after a delay, so that the game exercises the same code as against a real
+// cluster without one.
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// resyncInterval is how often the controllers compare the cluster with what it should be.
+const resyncInterval = 100 * time.Millisecond
+
+// Options configure a simulated cluster.
+type Options struct {
+	RecreateDelay time.Duration // How long a controller takes to replace a pod, jittered by ±50%
+	CrashInterval time.Duration // Mean time between simulated container crashes, 0 for none
+	APIErrorRate  float64       // Fraction of pod deletions that fail with a server error
+}
+
+// Cluster is a simulated cluster. Its client can be used like the client of a real one.
+type Cluster struct {
+	opts   Options
+	client *fake.Clientset
+
+	mu         sync.Mutex
+	namespaces map[string]bool
+
+	// Only used by the controller loop
+	due        map[types.UID][]time.Time // Replacement pods a ReplicaSet is waiting for
+	recovering map[types.UID]time.Time   // Crashed pods and when they are ready again
+	nextCrash  time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New starts a simulated cluster with the workloads of the given namespaces.
+func New(opts Options, namespaces ...string) (*Cluster, error) {
+	c := &Cluster{
+		opts:       opts,
+		client:     fake.NewSimpleClientset(),
+		namespaces: make(map[string]bool),
+		due:        make(map[types.UID][]time.Time),
+		recovering: make(map[types.UID]time.Time),
+		done:       make(chan struct{}),
+	}
+	if opts.APIErrorRate > 0 {
+		c.client.PrependReactor("delete", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+			if rand.Float64() < opts.APIErrorRate {
+				return true, nil, apierrors.NewInternalError(errors.New("simulated API server failure"))
+			}
+			return false, nil, nil
+		})
+	}
+	if err := c.AddNamespaces(namespaces...); err != nil {
+		return nil, err
+	}
+	c.scheduleCrash(time.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.run(ctx)
+	return c, nil
+}
+
+// Client returns the client of the cluster.
+func (c *Cluster) Client() kubernetes.Interface {
+	return c.client
+}
+
+// Close stops the controllers.
+func (c *Cluster) Close() {
+	c.cancel()
+	<-c.done
+}
+
+// AddNamespaces creates the namespaces that do not exist yet, each running the workloads
+// of a small web shop. Namespaces that fail are left out and retried on the next call.
+func (c *Cluster) AddNamespaces(namespaces ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for _, ns := range namespaces {
+		if c.namespaces[ns] {
+			continue
+		}
+		if err := c.seed(context.Background(), ns); err != nil {
+			errs = append(errs, fmt.Errorf("failed to simulate namespace %s: %w", ns, err))
+			continue
+		}
+		c.namespaces[ns] = true
+	}
+	return errors.Join(errs...)
+}
+
+// run reconciles the cluster until ctx is done.
+func (c *Cluster) run(ctx context.Context) {
+	defer close(c.done)
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := c.reconcile(ctx, now); err != nil {
+				slog.Warn("Simulated controllers failed", "error", err)
+			}
+			// The fake clientset records every request; nothing reads them here
+			c.client.ClearActions()
+		}
+	}
+}
+
+// reconcile runs one pass of the deployment and ReplicaSet controllers and of the
+// simulated failures.
+func (c *Cluster) reconcile(ctx context.Context, now time.Time) error {
+	deployments, err := c.client.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range deployments.Items {
+		if err := c.syncDeployment(ctx, &deployments.Items[i]); err != nil {
+			return err
+		}
+	}
+
+	replicaSets, err := c.client.AppsV1().ReplicaSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	pods, err := c.client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	owned := make(map[types.UID][]corev1.Pod)
+	for _, pod := range pods.Items {
+		if owner := metav1.GetControllerOf(&pod); owner != nil && pod.DeletionTimestamp == nil {
+			owned[owner.UID] = append(owned[owner.UID], pod)
+		}
+	}
+	exists := make(map[types.UID]bool)
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		exists[rs.UID] = true
+		if err := c.syncReplicaSet(ctx, rs, owned[rs.UID], now); err != nil {
+			return err
+		}
+	}
+	for uid := range c.due {
+		if !exists[uid] {
+			delete(c.due, uid)
+		}
+	}
+	return c.simulateFailures(ctx, pods.Items, now)
+}
+
+// syncDeployment makes the ReplicaSet of the deployment's current template run all
+// replicas and scales the ReplicaSets of earlier templates to zero.
+func (c *Cluster) syncDeployment(ctx context.Context, d *appsv1.Deployment) error {
+	hash, err := templateHash(&d.Spec.Template)
+	if err != nil {
+		return err
+	}
+	replicaSets, err := c.client.AppsV1().ReplicaSets(d.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	current := false
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if owner := metav1.GetControllerOf(rs); owner == nil || owner.UID != d.UID {
+			continue
+		}
+		want := int32(0)
+		if rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey] == hash {
+			want, current = replicas(d.Spec.Replicas), true
+		}
+		if replicas(rs.Spec.Replicas) != want {
+			rs.Spec.Replicas = &want
+			if _, err := c.client.AppsV1().ReplicaSets(rs.Namespace).Update(ctx, rs, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+		}
+	}
+	if current {
+		return nil
+	}
+	_, err = c.client.AppsV1().ReplicaSets(d.Namespace).Create(ctx, newReplicaSet(d, hash), metav1.CreateOptions{})
+	return err
+}
+
+// syncReplicaSet creates or deletes pods until the ReplicaSet runs its replicas. New pods
+// appear after the recreate delay.
+func (c *Cluster) syncReplicaSet(ctx context.Context, rs *appsv1.ReplicaSet, pods []corev1.Pod, now time.Time) error {
+	due := c.due[rs.UID]
+	defer func() { c.due[rs.UID] = due }()
+	live := len(pods)
+	for len(due) > 0 && !due[0].After(now) {
+		if _, err := c.client.CoreV1().Pods(rs.Namespace).Create(ctx, newPod(rs, now), metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		due = due[1:]
+		live++
+	}
+
+	want := int(replicas(rs.Spec.Replicas))
+	for live+len(due) < want {
+		due = append(due, now.Add(c.jitter(c.opts.RecreateDelay)))
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Before(due[j]) })
+	for live+len(due) > want && len(due) > 0 {
+		due = due[:len(due)-1]
+	}
+
+	// Scale down the youngest pods first, bypassing the simulated API failures
+	sort.Slice(pods, func(i, j int) bool { return pods[j].CreationTimestamp.Before(&pods[i].CreationTimestamp) })
+	for _, pod := range pods[:min(max(live-want, 0), len(pods))] {
+		err := c.client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), pod.Namespace, pod.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// simulateFailures crashes a container now and then, making its pod unready for the
+// recreate delay.
+func (c *Cluster) simulateFailures(ctx context.Context, pods []corev1.Pod, now time.Time) error {
+	for i := range pods {
+		pod := &pods[i]
+		at, ok := c.recovering[pod.UID]
+		if !ok || at.After(now) {
+			continue
+		}
+		delete(c.recovering, pod.UID)
+		setReady(pod, true)
+		if _, err := c.client.CoreV1().Pods(pod.Namespace).UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if c.opts.CrashInterval <= 0 || now.Before(c.nextCrash) {
+		return nil
+	}
+	c.scheduleCrash(now)
+	var candidates []*corev1.Pod
+	for i := range pods {
+		if _, crashed := c.recovering[pods[i].UID]; !crashed && pods[i].DeletionTimestamp == nil && len(pods[i].Status.ContainerStatuses) > 0 {
+			candidates = append(candidates, &pods[i])
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	pod := candidates[rand.IntN(len(candidates))]
+	pod.Status.ContainerStatuses[0].RestartCount++
+	setReady(pod, false)
+	c.recovering[pod.UID] = now.Add(c.jitter(c.opts.RecreateDelay))
+	slog.Debug("Simulated container crash", "namespace", pod.Namespace, "pod", pod.Name)
+	if _, err := c.client.CoreV1().Pods(pod.Namespace).UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// scheduleCrash picks the time of the next crash, with exponentially distributed gaps.
+func (c *Cluster) scheduleCrash(now time.Time) {
+	if c.opts.CrashInterval > 0 {
+		c.nextCrash = now.Add(time.Duration(rand.ExpFloat64() * float64(c.opts.CrashInterval)))
+	}
+}
+
+// jitter returns d varied by up to 50% either way.
+func (c *Cluster) jitter(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * (0.5 + rand.Float64()))
+}
+
+// workload is a Deployment every simulated namespace runs.
+type workload struct {
+	name     string
+	image    string
+	replicas int32
+	qos      corev1.PodQOSClass
+	critical bool // High priority and protected by a PodDisruptionBudget
+}
+
+// shop are the workloads of a small web shop, with the mix of replicas and importance
+// found in real namespaces.
+var shop = []workload{
+	{name: "frontend", image: "nginx:1.27", replicas: 3, qos: corev1.PodQOSBurstable},
+	{name: "cart", image: "gcr.io/google-samples/microservices-demo/cartservice:v0.10.1", replicas: 2, qos: corev1.PodQOSBurstable},
+	{name: "checkout", image: "gcr.io/google-samples/microservices-demo/checkoutservice:v0.10.1", replicas: 2, qos: corev1.PodQOSBurstable},
+	{name: "payments", image: "gcr.io/google-samples/microservices-demo/paymentservice:v0.10.1", replicas: 1, qos: corev1.PodQOSGuaranteed, critical: true},
+	{name: "redis", image: "redis:7.4", replicas: 1, qos: corev1.PodQOSGuaranteed, critical: true},
+	{name: "worker", image: "busybox:1.36", replicas: 4, qos: corev1.PodQOSBestEffort},
+}
+
+// nodes are the nodes simulated pods are scheduled to.
+var nodes = []string{"sim-worker-0", "sim-worker-1", "sim-worker-2"}
+
+// criticalPriority is the priority of critical workloads, as in a high-priority class.
+const criticalPriority = 1_000_000
+
+// seed creates a namespace running the shop, with pods that have been running for a while.
+// c.mu must be held.
+func (c *Cluster) seed(ctx context.Context, ns string) error {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns, UID: newUID()}}
+	if _, err := c.client.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	for _, w := range shop {
+		d := newDeployment(ns, w)
+		if _, err := c.client.AppsV1().Deployments(ns).Create(ctx, d, metav1.CreateOptions{}); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				continue
+			}
+			return err
+		}
+		hash, err := templateHash(&d.Spec.Template)
+		if err != nil {
+			return err
+		}
+		rs := newReplicaSet(d, hash)
+		if _, err := c.client.AppsV1().ReplicaSets(ns).Create(ctx, rs, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		for range w.replicas {
+			started := time.Now().Add(-time.Duration(rand.Int64N(int64(7 * 24 * time.Hour))))
+			if _, err := c.client.CoreV1().Pods(ns).Create(ctx, newPod(rs, started), metav1.CreateOptions{}); err != nil {
+				return err
+			}
+		}
+		if w.critical {
+			minAvailable := intstr.FromInt32(1)
+			pdb := &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: w.name, Namespace: ns, UID: newUID()},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					MinAvailable: &minAvailable,
+					Selector:     d.Spec.Selector,
+				},
+			}
+			if _, err := c.client.PolicyV1().PodDisruptionBudgets(ns).Create(ctx, pdb, metav1.CreateOptions{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newDeployment creates the Deployment of a workload.
+func newDeployment(ns string, w workload) *appsv1.Deployment {
+	labels := map[string]string{"app": w.name}
+	container := corev1.Container{Name: w.name, Image: w.image, Resources: resources(w.qos)}
+	spec := corev1.PodSpec{Containers: []corev1.Container{container}}
+	if w.critical {
+		priority := int32(criticalPriority)
+		spec.PriorityClassName = "high-priority"
+		spec.Priority = &priority
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: w.name, Namespace: ns, UID: newUID(), Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &w.replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       spec,
+			},
+		},
+	}
+}
+
+// resources returns container resources that give a pod the QoS class.
+func resources(qos corev1.PodQOSClass) corev1.ResourceRequirements {
+	requests := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+		corev1.ResourceMemory: resource.MustParse("128Mi"),
+	}
+	switch qos {
+	case corev1.PodQOSGuaranteed:
+		return corev1.ResourceRequirements{Requests: requests, Limits: requests}
+	case corev1.PodQOSBurstable:
+		return corev1.ResourceRequirements{Requests: requests}
+	}
+	return corev1.ResourceRequirements{}
+}
+
+// newReplicaSet creates the ReplicaSet of a deployment's template, named after its hash.
+func newReplicaSet(d *appsv1.Deployment, hash string) *appsv1.ReplicaSet {
+	template := d.Spec.Template.DeepCopy()
+	template.Labels = withLabel(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey, hash)
+	selector := d.Spec.Selector.DeepCopy()
+	selector.MatchLabels = withLabel(selector.MatchLabels, appsv1.DefaultDeploymentUniqueLabelKey, hash)
+	controller := true
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      d.Name + "-" + hash,
+			Namespace: d.Namespace,
+			UID:       newUID(),
+			Labels:    template.Labels,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: d.Name, UID: d.UID, Controller: &controller},
+			},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Replicas: d.Spec.Replicas,
+			Selector: selector,
+			Template: *template,
+		},
+	}
+}
+
+// newPod creates a running pod of a ReplicaSet, named with a random suffix.
+func newPod(rs *appsv1.ReplicaSet, started time.Time) *corev1.Pod {
+	controller := true
+	spec := *rs.Spec.Template.Spec.DeepCopy()
+	spec.NodeName = nodes[rand.IntN(len(nodes))]
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              rs.Name + "-" + utilrand.String(5),
+			Namespace:         rs.Namespace,
+			UID:               newUID(),
+			Labels:            rs.Spec.Template.Labels,
+			CreationTimestamp: metav1.NewTime(started.Truncate(time.Second)),
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rs.Name, UID: rs.UID, Controller: &controller},
+			},
+		},
+		Spec: spec,
+		Status: corev1.PodStatus{
+			Phase:     corev1.PodRunning,
+			QOSClass:  qosClass(spec.Containers),
+			StartTime: &metav1.Time{Time: started.Truncate(time.Second)},
+		},
+	}
+	for _, container := range spec.Containers {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name: container.Name, Image: container.Image, Started: &controller,
+		})
+	}
+	setReady(pod, true)
+	return pod
+}
+
+// qosClass derives the QoS class of containers made by resources.
+func qosClass(containers []corev1.Container) corev1.PodQOSClass {
+	r := containers[0].Resources
+	switch {
+	case len(r.Requests) == 0:
+		return corev1.PodQOSBestEffort
+	case len(r.Limits) == len(r.Requests):
+		return corev1.PodQOSGuaranteed
+	}
+	return corev1.PodQOSBurstable
+}
+
+// setReady sets the readiness of a pod and its containers.
+func setReady(pod *corev1.Pod, ready bool) {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}
+	for i := range pod.Status.ContainerStatuses {
+		pod.Status.ContainerStatuses[i].Ready = ready
+	}
+}
+
+// templateHash hashes a pod template into a suffix like those of real ReplicaSets.
+func templateHash(template *corev1.PodTemplateSpec) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode pod template: %w", err)
+	}
+	h := fnv.New32a()
+	h.Write(data)
+	return utilrand.SafeEncodeString(fmt.Sprint(h.Sum32())), nil
+}
+
+// replicas returns the desired replicas, which default to one.
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
+
+// withLabel returns a copy of labels with one more label.
+func withLabel(labels map[string]string, key, value string) map[string]string {
+	out := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+func newUID() types.UID {
+	return types.UID(uuid.NewString())
+}
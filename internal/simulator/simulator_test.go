@@ -0,0 +1,236 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/cldmnky/pod-invaders/internal/game"
+	"github.com/cldmnky/pod-invaders/internal/k8s"
+)
+
+// shopPods is the number of pods every simulated namespace runs.
+func shopPods() int {
+	n := 0
+	for _, w := range shop {
+		n += int(w.replicas)
+	}
+	return n
+}
+
+// eventually fails the test unless cond holds within five seconds.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// podsOf returns the names of the pods of a workload.
+func podsOf(t *testing.T, c *Cluster, ns, app string) []string {
+	t.Helper()
+	pods, err := c.Client().CoreV1().Pods(ns).List(context.Background(), metav1.ListOptions{LabelSelector: "app=" + app})
+	if err != nil {
+		t.Fatalf("Failed to list pods: %v", err)
+	}
+	var names []string
+	for _, p := range pods.Items {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// newCluster starts a simulated cluster, failing the test if it cannot.
+func newCluster(t *testing.T, opts Options, namespaces ...string) *Cluster {
+	t.Helper()
+	c, err := New(opts, namespaces...)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return c
+}
+
+func TestSeededPods(t *testing.T) {
+	c := newCluster(t, Options{RecreateDelay: time.Second}, "default", "shop")
+	defer c.Close()
+
+	pods, err := k8s.GetPods(context.Background(), c.Client(), 100, "default", "shop")
+	if err != nil {
+		t.Fatalf("GetPods failed: %v", err)
+	}
+	realPods := 0
+	names := make(map[string]bool)
+	for _, p := range pods {
+		if !p.IsRealPod {
+			continue
+		}
+		realPods++
+		names[p.Namespace+"/"+p.Name] = true
+		// Pods are named like those of a Deployment: <deployment>-<template hash>-<suffix>
+		parts := strings.Split(p.Name, "-")
+		if len(parts) != 3 || p.OwnerKind != "ReplicaSet" || p.OwnerName != parts[0]+"-"+parts[1] || len(parts[2]) != 5 {
+			t.Errorf("Expected a pod of a ReplicaSet with a hash suffix, got %+v", p)
+		}
+		if p.Labels["app"] != parts[0] || p.Phase != "Running" || !p.Ready || p.Node == "" {
+			t.Errorf("Expected a running, ready and scheduled pod, got %+v", p)
+		}
+	}
+	if realPods != 2*shopPods() || len(names) != realPods {
+		t.Errorf("Expected %d distinct real pods, got %d of %d", 2*shopPods(), len(names), realPods)
+	}
+
+	byName := make(map[string]game.Pod)
+	for _, p := range pods {
+		if p.Namespace == "default" {
+			byName[strings.SplitN(p.Name, "-", 2)[0]] = p
+		}
+	}
+	if redis := byName["redis"]; !redis.CoveredByPDB || redis.Priority != criticalPriority || redis.QOSClass != "Guaranteed" || redis.OwnerReplicas != 1 {
+		t.Errorf("Expected redis to be a critical singleton, got %+v", redis)
+	}
+	if worker := byName["worker"]; worker.CoveredByPDB || worker.QOSClass != "BestEffort" || worker.OwnerReplicas != 4 {
+		t.Errorf("Expected workers to be unprotected replicas, got %+v", worker)
+	}
+}
+
+func TestKilledPodsAreReplaced(t *testing.T) {
+	c := newCluster(t, Options{RecreateDelay: 200 * time.Millisecond}, "default")
+	defer c.Close()
+	ctx := context.Background()
+
+	before := podsOf(t, c, "default", "frontend")
+	victim := game.Pod{Name: before[0], Namespace: "default", IsRealPod: true}
+	start := time.Now()
+	if err := k8s.KillPod(ctx, c.Client(), victim); err != nil {
+		t.Fatalf("KillPod failed: %v", err)
+	}
+	if got := podsOf(t, c, "default", "frontend"); len(got) != len(before)-1 {
+		t.Fatalf("Expected the pod to be gone until it is replaced, got %v", got)
+	}
+
+	eventually(t, "the pod to be replaced", func() bool { return len(podsOf(t, c, "default", "frontend")) == len(before) })
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the replacement to take about the recreate delay, took %v", elapsed)
+	}
+	for _, name := range podsOf(t, c, "default", "frontend") {
+		if name == victim.Name {
+			t.Error("Expected the replacement to get a new name")
+		}
+	}
+}
+
+func TestDeploymentScaling(t *testing.T) {
+	c := newCluster(t, Options{RecreateDelay: 50 * time.Millisecond}, "default")
+	defer c.Close()
+	ctx := context.Background()
+	scale := func(replicas string) {
+		t.Helper()
+		patch := []byte(`{"spec":{"replicas":` + replicas + `}}`)
+		if _, err := c.Client().AppsV1().Deployments("default").Patch(ctx, "cart", types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			t.Fatalf("Failed to scale: %v", err)
+		}
+	}
+
+	scale("0")
+	eventually(t, "cart to scale to zero", func() bool { return len(podsOf(t, c, "default", "cart")) == 0 })
+	scale("3")
+	eventually(t, "cart to scale up", func() bool { return len(podsOf(t, c, "default", "cart")) == 3 })
+
+	// A rolling restart changes the template, which gets a ReplicaSet of its own
+	before := podsOf(t, c, "default", "cart")
+	if err := k8s.RestartWorkload(ctx, c.Client(), game.KindDeployment, "default", "cart"); err != nil {
+		t.Fatalf("RestartWorkload failed: %v", err)
+	}
+	eventually(t, "cart to roll out", func() bool {
+		after := podsOf(t, c, "default", "cart")
+		return len(after) == 3 && strings.Split(after[0], "-")[1] != strings.Split(before[0], "-")[1]
+	})
+	replicaSets, err := c.Client().AppsV1().ReplicaSets("default").List(ctx, metav1.ListOptions{LabelSelector: "app=cart"})
+	if err != nil || len(replicaSets.Items) != 2 {
+		t.Fatalf("Expected an old and a new ReplicaSet, got %v (%v)", replicaSets, err)
+	}
+	for _, rs := range replicaSets.Items {
+		if old := strings.Contains(before[0], rs.Name); old != (*rs.Spec.Replicas == 0) {
+			t.Errorf("Expected only the old ReplicaSet to be scaled to zero, got %s with %d", rs.Name, *rs.Spec.Replicas)
+		}
+	}
+}
+
+func TestSimulatedFailures(t *testing.T) {
+	t.Run("API errors", func(t *testing.T) {
+		c := newCluster(t, Options{RecreateDelay: time.Second, APIErrorRate: 1}, "default")
+		defer c.Close()
+		victim := game.Pod{Name: podsOf(t, c, "default", "redis")[0], Namespace: "default", IsRealPod: true}
+		if err := k8s.KillPod(context.Background(), c.Client(), victim); err == nil {
+			t.Error("Expected the deletion to fail")
+		}
+	})
+
+	t.Run("container crashes", func(t *testing.T) {
+		c := newCluster(t, Options{RecreateDelay: 50 * time.Millisecond, CrashInterval: 50 * time.Millisecond}, "default")
+		defer c.Close()
+		eventually(t, "a container to crash", func() bool {
+			pods, err := c.Client().CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("Failed to list pods: %v", err)
+			}
+			for _, p := range pods.Items {
+				if p.Status.ContainerStatuses[0].RestartCount > 0 {
+					return true
+				}
+			}
+			return false
+		})
+	})
+}
+
+func TestAddNamespaces(t *testing.T) {
+	c := newCluster(t, Options{RecreateDelay: time.Second})
+	defer c.Close()
+	if err := c.AddNamespaces("dev", "dev"); err != nil {
+		t.Fatalf("AddNamespaces failed: %v", err)
+	}
+
+	deployments, err := c.Client().AppsV1().Deployments("dev").List(context.Background(), metav1.ListOptions{})
+	if err != nil || len(deployments.Items) != len(shop) {
+		t.Fatalf("Expected the shop's deployments once, got %d (%v)", len(deployments.Items), err)
+	}
+	if n := len(podsOf(t, c, "dev", "worker")); n != 4 {
+		t.Errorf("Expected 4 workers, got %d", n)
+	}
+}
+
+func TestAddNamespacesError(t *testing.T) {
+	c := newCluster(t, Options{RecreateDelay: time.Second})
+	defer c.Close()
+	fail := true
+	c.client.PrependReactor("create", "namespaces", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if fail {
+			return true, nil, apierrors.NewInternalError(errors.New("unavailable"))
+		}
+		return false, nil, nil
+	})
+
+	if err := c.AddNamespaces("dev"); err == nil || !strings.Contains(err.Error(), "namespace dev") {
+		t.Fatalf("Expected an error naming the namespace, got %v", err)
+	}
+	fail = false
+	if err := c.AddNamespaces("dev"); err != nil {
+		t.Fatalf("Expected a failed namespace to be retried, got %v", err)
+	}
+	if n := len(podsOf(t, c, "dev", "worker")); n != 4 {
+		t.Errorf("Expected 4 workers after the retry, got %d", n)
+	}
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName identifies pod-invaders in exported traces.
+const ServiceName = "pod-invaders"
+
+// Options configures trace export.
+type Options struct {
+	Endpoint    string  // OTLP/HTTP collector, as host:port or URL; empty disables export
+	Insecure    bool    // Use plain HTTP for a host:port endpoint
+	SampleRatio float64 // Fraction of new traces to sample
+}
+
+// Setup installs the global tracer provider and propagator. Without an endpoint
+// the no-op provider stays in place. The returned function flushes and stops export.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var clientOpts []otlptracehttp.Option
+	if strings.Contains(opts.Endpoint, "://") {
+		clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+	} else {
+		clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+	}
+	exporter, err := otlptracehttp.New(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := NewProvider(sdktrace.NewBatchSpanProcessor(exporter), opts.SampleRatio)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider creates a tracer provider for this service that sends spans to processor.
+// Spans continue the sampling decision of their parent.
+func NewProvider(processor sdktrace.SpanProcessor, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	)
+}
+
+// Tracer returns the tracer for instrumentation in this module, from the current global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer("github.com/cldmnky/pod-invaders")
+}
+
+// WrapTransport traces outgoing requests made through rt and propagates the trace context.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rt)
+}